@@ -1,46 +1,59 @@
 package api
 
 import (
-	"database/sql"
 	"net/http"
+	"strconv"
 
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
 	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
 	"github.com/ashokmouli/simplebank/token"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 )
 
 type createAccountRequest struct {
-	Currency string `json:"currency" binding:"required,oneof=USD EUR"`
+	Currency string `json:"currency" binding:"required,currency"`
+	// Optional; checking when empty.
+	Type     string `json:"type" binding:"omitempty,account_type"`
+	Nickname string `json:"nickname" binding:"omitempty,nickname"`
 }
 
 func (server *Server) createAccount(ctx *gin.Context) {
 	var json createAccountRequest
 	if err := ctx.ShouldBindJSON(&json); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, bindError(err))
 		return
 	}
 	// Retrieve the user name from the authorized payload and make it the
 	// owner of this account.
 	payload := (ctx.MustGet(authorizationPayloadKey)).(*token.Payload)
 
+	accountType := json.Type
+	if accountType == "" {
+		accountType = util.Checking
+	}
 	account, err := server.store.CreateAccount(ctx, db.CreateAccountParams{
 		Owner:    payload.Username,
 		Balance:  0,
 		Currency: json.Currency,
+		Type:     accountType,
+		Nickname: json.Nickname,
 	})
 
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, apperr.FromDB(err, "account"))
 		return
 	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionAccountCreate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceAccount,
+		ResourceID:   strconv.FormatInt(account.ID, 10),
+		Client:       auditClient(ctx),
+		After:        account,
+	})
 	ctx.JSON(http.StatusOK, account)
 }
 
@@ -51,26 +64,107 @@ type getAccountRequest struct {
 func (server *Server) getAccount(ctx *gin.Context) {
 	var req getAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, bindError(err))
 		return
 	}
 	account, err := server.store.GetAccount(ctx, req.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, apperr.FromDB(err, "account"))
 		return
 	}
 	// Validate that the account being requested is owned by the authorized user
 	payload := (ctx.MustGet(authorizationPayloadKey)).(*token.Payload)
 	if payload.Username != account.Owner {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		respondError(ctx, apperr.New(apperr.CodePermissionDenied, "account does not belong to the authenticated user"))
+		return
 	}
 	ctx.JSON(http.StatusOK, account)
 }
 
+// ownedAccount returns the account with the ID in the URI, provided the caller owns it.
+func (server *Server) ownedAccount(ctx *gin.Context) (db.Account, bool) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return db.Account{}, false
+	}
+	account, err := server.store.GetAccount(ctx, req.ID)
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "account"))
+		return db.Account{}, false
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username != account.Owner {
+		respondError(ctx, apperr.New(apperr.CodePermissionDenied, "account does not belong to the authenticated user"))
+		return db.Account{}, false
+	}
+	return account, true
+}
+
+type updateAccountRequest struct {
+	// An empty nickname removes it.
+	Nickname string `json:"nickname" binding:"omitempty,nickname"`
+}
+
+// updateAccount renames one of the caller's accounts.
+func (server *Server) updateAccount(ctx *gin.Context) {
+	account, ok := server.ownedAccount(ctx)
+	if !ok {
+		return
+	}
+	var req updateAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+
+	updated, err := server.store.UpdateAccountNickname(ctx, db.UpdateAccountNicknameParams{
+		ID:       account.ID,
+		Nickname: req.Nickname,
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "account nickname"))
+		return
+	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        account.Owner,
+		Action:       audit.ActionAccountUpdate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceAccount,
+		ResourceID:   strconv.FormatInt(account.ID, 10),
+		Client:       auditClient(ctx),
+		Before:       gin.H{"nickname": account.Nickname},
+		After:        gin.H{"nickname": updated.Nickname},
+	})
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// setDefaultAccount makes one of the caller's accounts the one that receives transfers sent to
+// them by username in its currency.
+func (server *Server) setDefaultAccount(ctx *gin.Context) {
+	account, ok := server.ownedAccount(ctx)
+	if !ok {
+		return
+	}
+
+	updated, err := server.store.SetDefaultAccountTx(ctx, account.ID)
+	if err != nil {
+		respondError(ctx, accountError(err))
+		return
+	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        account.Owner,
+		Action:       audit.ActionAccountUpdate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceAccount,
+		ResourceID:   strconv.FormatInt(account.ID, 10),
+		Client:       auditClient(ctx),
+		Before:       gin.H{"is_default": account.IsDefault},
+		After:        gin.H{"is_default": updated.IsDefault},
+	})
+	ctx.JSON(http.StatusOK, updated)
+}
+
 type listAccountRequest struct {
 	PageID   int32 `form:"page_id" binding:"required,min=1"`
 	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
@@ -79,7 +173,7 @@ type listAccountRequest struct {
 func (server *Server) listAccount(ctx *gin.Context) {
 	var req listAccountRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, bindError(err))
 		return
 	}
 
@@ -92,7 +186,7 @@ func (server *Server) listAccount(ctx *gin.Context) {
 		Offset: (req.PageID - 1) * req.PageSize,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, accounts)
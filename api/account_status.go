@@ -0,0 +1,117 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/ashokmouli/simplebank/txlimit"
+	"github.com/gin-gonic/gin"
+)
+
+type setAccountStatusURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type setAccountStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=active frozen closed"`
+	// Optional, when closing: the account that receives the remaining balance.
+	SweepToAccountID int64 `json:"sweep_to_account_id" binding:"min=0"`
+}
+
+// setAccountStatus lets bankers freeze, unfreeze, close and reopen accounts. Every change is
+// recorded in the audit log with the account's status and balance before and after.
+func (server *Server) setAccountStatus(ctx *gin.Context) {
+	var uri setAccountStatusURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	var req setAccountStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "account"))
+		return
+	}
+	if req.SweepToAccountID != 0 {
+		if err := server.validateSweep(ctx, account, req); err != nil {
+			respondError(ctx, err)
+			return
+		}
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	event := audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionAccountStatus,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceAccount,
+		ResourceID:   strconv.FormatInt(account.ID, 10),
+		Client:       auditClient(ctx),
+	}
+	auditEntry, err := event.Params()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	result, err := server.store.SetAccountStatusTx(ctx, db.SetAccountStatusTxParams{
+		AccountID:        account.ID,
+		Status:           req.Status,
+		SweepToAccountID: req.SweepToAccountID,
+		Audit:            &auditEntry,
+	})
+	if err != nil {
+		appErr := accountError(err)
+		if appErr.Code == apperr.CodeFailedPrecondition {
+			event.Outcome = audit.OutcomeFailure
+			event.After = gin.H{"status": req.Status}
+			audit.Record(ctx, server.store, event)
+		}
+		respondError(ctx, appErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// validateSweep checks the account that receives the balance of an account being closed.
+func (server *Server) validateSweep(ctx *gin.Context, account db.Account, req setAccountStatusRequest) error {
+	if req.Status != db.AccountClosed {
+		return apperr.InvalidArgument(apperr.Violation("sweep_to_account_id", "is only allowed when closing"))
+	}
+	if req.SweepToAccountID == account.ID {
+		return apperr.InvalidArgument(apperr.Violation("sweep_to_account_id", "must be another account"))
+	}
+	sweepTo, err := server.store.GetAccount(ctx, req.SweepToAccountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperr.InvalidArgument(apperr.Violation("sweep_to_account_id", "account does not exist"))
+		}
+		return err
+	}
+	if sweepTo.Currency != account.Currency {
+		return apperr.InvalidArgument(apperr.Violation("sweep_to_account_id", "must hold "+account.Currency))
+	}
+	return nil
+}
+
+// accountError maps the errors the store returns for accounts whose status, balance or limits
+// don't allow an operation.
+func accountError(err error) *apperr.Error {
+	if errors.Is(err, db.ErrAccountNotActive) || errors.Is(err, db.ErrStatusTransition) || errors.Is(err, db.ErrBalanceNotZero) ||
+		errors.Is(err, txlimit.ErrExceeded) || errors.Is(err, db.ErrFundsHeld) || errors.Is(err, db.ErrReviewClosed) ||
+		errors.Is(err, db.ErrSystemAccount) {
+		return apperr.Wrap(err, apperr.CodeFailedPrecondition, err.Error())
+	}
+	return apperr.FromDB(err, "account")
+}
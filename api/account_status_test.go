@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAccountStatusAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	sweepTo := randomAccount(account.Owner)
+	sweepTo.ID = account.ID + 1
+	sweepTo.Currency = account.Currency
+
+	testCases := []struct {
+		name        string
+		body        gin.H
+		scopes      []string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Freeze",
+			body:   gin.H{"status": db.AccountFrozen},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.SetAccountStatusTxParams) (db.SetAccountStatusTxResult, error) {
+						require.Equal(t, account.ID, arg.AccountID)
+						require.Equal(t, db.AccountFrozen, arg.Status)
+						require.Equal(t, audit.ActionAccountStatus, arg.Audit.Action)
+						frozen := account
+						frozen.Status = db.AccountFrozen
+						return db.SetAccountStatusTxResult{Account: frozen}, nil
+					}).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got db.SetAccountStatusTxResult
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, db.AccountFrozen, got.Account.Status)
+			},
+		},
+		{
+			name:   "CloseWithSweep",
+			body:   gin.H{"status": db.AccountClosed, "sweep_to_account_id": sweepTo.ID},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(sweepTo.ID)).Return(sweepTo, nil).Times(1)
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.SetAccountStatusTxParams) (db.SetAccountStatusTxResult, error) {
+						require.Equal(t, sweepTo.ID, arg.SweepToAccountID)
+						return db.SetAccountStatusTxResult{}, nil
+					}).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name:   "SweepWhenFreezing",
+			body:   gin.H{"status": db.AccountFrozen, "sweep_to_account_id": sweepTo.ID},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "sweep_to_account_id")
+			},
+		},
+		{
+			name:   "BalanceNotZero",
+			body:   gin.H{"status": db.AccountClosed},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Any()).
+					Return(db.SetAccountStatusTxResult{}, fmt.Errorf("account %d holds 10: %w", account.ID, db.ErrBalanceNotZero)).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionAccountStatus, audit.OutcomeFailure)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "failed_precondition")
+			},
+		},
+		{
+			name:   "NotFound",
+			body:   gin.H{"status": db.AccountFrozen},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(db.Account{}, sql.ErrNoRows).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, resp.Code)
+			},
+		},
+		{
+			name:   "InvalidStatus",
+			body:   gin.H{"status": "deleted"},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:   "Depositor",
+			body:   gin.H{"status": db.AccountFrozen},
+			scopes: token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(util.RandomOwner(), tc.scopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/accounts/%d/status", account.ID), bytes.NewReader(data))
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
@@ -11,12 +11,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ashokmouli/simplebank/audit"
 	mockdb "github.com/ashokmouli/simplebank/db/mock"
 	db "github.com/ashokmouli/simplebank/db/sqlc"
 	"github.com/ashokmouli/simplebank/db/util"
 	"github.com/ashokmouli/simplebank/token"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,14 +47,60 @@ func TestCreateAccountAPI(t *testing.T) {
 					Owner:    username,
 					Balance:  0,
 					Currency: account.Currency,
+					Type:     util.Checking,
 				}
 				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).Return(account, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionAccountCreate, audit.OutcomeSuccess)).Times(1)
 			},
 			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, resp.Code)
 				matchReturnedAccount(t, resp.Body, &account)
 			},
 		},
+		{
+			name: "SavingsWithNickname",
+			body: gin.H{
+				"currency": account.Currency,
+				"type":     util.Savings,
+				"nickname": "Rainy day",
+			},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker, username string, duration time.Duration) {
+				addAuthHeader(t, req, tokenMaker, username, duration)
+			},
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{
+					Owner:    username,
+					Balance:  0,
+					Currency: account.Currency,
+					Type:     util.Savings,
+					Nickname: "Rainy day",
+				}
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).Return(account, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name: "InvalidType",
+			body: gin.H{
+				"currency": account.Currency,
+				"type":     "brokerage",
+				"nickname": " ",
+			},
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker, username string, duration time.Duration) {
+				addAuthHeader(t, req, tokenMaker, username, duration)
+			},
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "must be one of checking, savings")
+				require.Contains(t, resp.Body.String(), "nickname")
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -248,6 +296,7 @@ func randomAccount(user string) db.Account {
 		Owner:    user,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Status:   db.AccountActive,
 	}
 }
 
@@ -280,3 +329,119 @@ func matchReturnedAccounts(t *testing.T, buffer *bytes.Buffer, accounts []db.Acc
 		require.Equal(t, gotAccounts[i].Currency, accounts[i].Currency)
 	}
 }
+
+func TestUpdateAccountAPI(t *testing.T) {
+	username := util.RandomOwner()
+	account := randomAccount(username)
+	other := randomAccount(util.RandomOwner())
+	other.ID = account.ID + 1
+
+	testCases := []struct {
+		name        string
+		method      string
+		path        string
+		body        gin.H
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Rename",
+			method: http.MethodPatch,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			body:   gin.H{"nickname": "Bills"},
+			buildStore: func(store *mockdb.MockStore) {
+				renamed := account
+				renamed.Nickname = "Bills"
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().UpdateAccountNickname(gomock.Any(), gomock.Eq(db.UpdateAccountNicknameParams{
+					ID:       account.ID,
+					Nickname: "Bills",
+				})).Return(renamed, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionAccountUpdate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				require.Contains(t, resp.Body.String(), `"nickname":"Bills"`)
+			},
+		},
+		{
+			name:   "DuplicateNickname",
+			method: http.MethodPatch,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			body:   gin.H{"nickname": "Bills"},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().UpdateAccountNickname(gomock.Any(), gomock.Any()).Return(db.Account{}, &pq.Error{Code: "23505"}).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, resp.Code)
+			},
+		},
+		{
+			name:   "RenameOtherUsersAccount",
+			method: http.MethodPatch,
+			path:   fmt.Sprintf("/accounts/%d", other.ID),
+			body:   gin.H{"nickname": "Mine now"},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(other.ID)).Return(other, nil).Times(1)
+				store.EXPECT().UpdateAccountNickname(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			name:   "SetDefault",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/accounts/%d/default", account.ID),
+			buildStore: func(store *mockdb.MockStore) {
+				updated := account
+				updated.IsDefault = true
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().SetDefaultAccountTx(gomock.Any(), gomock.Eq(account.ID)).Return(updated, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionAccountUpdate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				require.Contains(t, resp.Body.String(), `"is_default":true`)
+			},
+		},
+		{
+			name:   "SetDefaultClosed",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/accounts/%d/default", account.ID),
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().SetDefaultAccountTx(gomock.Any(), gomock.Any()).
+					Return(db.Account{}, fmt.Errorf("account %d is closed: %w", account.ID, db.ErrAccountNotActive)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "is closed")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			var body io.Reader
+			if tc.body != nil {
+				data, err := json.Marshal(tc.body)
+				require.NoError(t, err)
+				body = bytes.NewReader(data)
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			addAuthHeader(t, req, server.maker, username, time.Minute)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
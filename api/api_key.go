@@ -0,0 +1,169 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ashokmouli/simplebank/apikey"
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type createAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int32    `json:"expires_in_days" binding:"omitempty,min=1"`
+}
+
+type apiKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type createAPIKeyResponse struct {
+	// The key is only ever returned here; only its hash is stored.
+	Key    string         `json:"key"`
+	APIKey apiKeyResponse `json:"api_key"`
+}
+
+func newAPIKeyResponse(apiKey db.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		ExpiresAt:  nullTime(apiKey.ExpiresAt),
+		LastUsedAt: nullTime(apiKey.LastUsedAt),
+		RevokedAt:  nullTime(apiKey.RevokedAt),
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	payload := (ctx.MustGet(authorizationPayloadKey)).(*token.Payload)
+	if err := token.CheckAPIKeyScopes(req.Scopes, payload.Scopes); err != nil {
+		respondError(ctx, apperr.InvalidArgument(apperr.Violation("scopes", err.Error())))
+		return
+	}
+
+	key, prefix, hashedSecret, err := apikey.Generate()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, int(req.ExpiresInDays)), Valid: true}
+	}
+
+	apiKey, err := server.store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		ID:           id,
+		Username:     payload.Username,
+		Name:         req.Name,
+		Prefix:       prefix,
+		HashedSecret: hashedSecret,
+		Scopes:       req.Scopes,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "api key"))
+		return
+	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionAPIKeyCreate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceAPIKey,
+		ResourceID:   apiKey.ID.String(),
+		Client:       auditClient(ctx),
+		After:        newAPIKeyResponse(apiKey),
+	})
+
+	ctx.JSON(http.StatusOK, createAPIKeyResponse{
+		Key:    key,
+		APIKey: newAPIKeyResponse(apiKey),
+	})
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	payload := (ctx.MustGet(authorizationPayloadKey)).(*token.Payload)
+	apiKeys, err := server.store.ListAPIKeys(ctx, payload.Username)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	resp := make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		resp = append(resp, newAPIKeyResponse(apiKey))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type revokeAPIKeyRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var req revokeAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+
+	// Users can only revoke their own keys, so a key owned by someone else looks the same as a missing one.
+	payload := (ctx.MustGet(authorizationPayloadKey)).(*token.Payload)
+	apiKey, err := server.store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		ID:       uuid.MustParse(req.ID),
+		Username: payload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(ctx, apperr.Wrap(err, apperr.CodeNotFound, "api key not found or already revoked"))
+			return
+		}
+		respondError(ctx, apperr.FromDB(err, "api key"))
+		return
+	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionAPIKeyRevoke,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceAPIKey,
+		ResourceID:   apiKey.ID.String(),
+		Client:       auditClient(ctx),
+		Before:       gin.H{"revoked_at": nil},
+		After:        gin.H{"revoked_at": apiKey.RevokedAt.Time},
+	})
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(apiKey))
+}
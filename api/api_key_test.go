@@ -0,0 +1,391 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/apikey"
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// randomAPIKey returns a stored API key for username along with the key handed to the user.
+func randomAPIKey(t *testing.T, username string, scopes ...string) (db.APIKey, string) {
+	key, prefix, hashedSecret, err := apikey.Generate()
+	require.NoError(t, err)
+
+	apiKey := db.APIKey{
+		ID:           uuid.New(),
+		Username:     username,
+		Name:         util.RandomString(6),
+		Prefix:       prefix,
+		HashedSecret: hashedSecret,
+		Scopes:       scopes,
+		CreatedAt:    time.Now(),
+	}
+	return apiKey, key
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name string
+		body gin.H
+		// scopes narrow the caller's token, which holds the user scopes otherwise.
+		scopes      []string
+		buildStore  func(t *testing.T, store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ok",
+			body: gin.H{
+				"name":   "batch",
+				"scopes": []string{token.ScopeAccountsRead},
+			},
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.CreateAPIKeyParams) (db.APIKey, error) {
+						require.Equal(t, username, arg.Username)
+						require.Equal(t, "batch", arg.Name)
+						require.Equal(t, []string{token.ScopeAccountsRead}, arg.Scopes)
+						require.False(t, arg.ExpiresAt.Valid)
+						return db.APIKey{
+							ID:           arg.ID,
+							Username:     arg.Username,
+							Name:         arg.Name,
+							Prefix:       arg.Prefix,
+							HashedSecret: arg.HashedSecret,
+							Scopes:       arg.Scopes,
+							CreatedAt:    time.Now(),
+						}, nil
+					}).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionAPIKeyCreate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+
+				data, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				var got createAPIKeyResponse
+				err = json.Unmarshal(data, &got)
+				require.NoError(t, err)
+
+				prefix, _, err := apikey.Parse(got.Key)
+				require.NoError(t, err)
+				require.Equal(t, prefix, got.APIKey.Prefix)
+				require.Equal(t, "batch", got.APIKey.Name)
+				require.NotContains(t, string(data), "hashed_secret")
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{
+				"name":   "batch",
+				"scopes": []string{"everything"},
+			},
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name: "ScopeNotGrantable",
+			body: gin.H{
+				"name":   "batch",
+				"scopes": []string{token.ScopeAPIKeysManage},
+			},
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name: "ScopeNotHeld",
+			body: gin.H{
+				"name":   "batch",
+				"scopes": []string{token.ScopeTransfersWrite},
+			},
+			scopes: []string{token.ScopeAPIKeysManage},
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				requireErrorBody(t, resp, apperr.CodeInvalidArgument, "scopes")
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{
+				"name": "batch",
+			},
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				requireErrorBody(t, resp, apperr.CodeInvalidArgument, "scopes")
+			},
+		},
+		{
+			name: "DuplicateName",
+			body: gin.H{
+				"name":   "batch",
+				"scopes": []string{token.ScopeTransfersWrite},
+			},
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Return(db.APIKey{}, &pq.Error{Code: "23505"}).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, resp.Code)
+				requireErrorBody(t, resp, apperr.CodeAlreadyExists)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"name":   "batch",
+				"scopes": []string{token.ScopeTransfersWrite},
+			},
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Return(db.APIKey{}, sql.ErrConnDone).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, resp.Code)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := mockdb.NewMockStore(ctrl)
+			tc.buildStore(t, mockStore)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			server := newTestServer(t, mockStore)
+			req := httptest.NewRequest(http.MethodPost, "/api_keys", bytes.NewReader(data))
+			if tc.scopes != nil {
+				accessToken, _, err := server.maker.CreateToken(username, tc.scopes, token.AccessToken, time.Hour)
+				require.NoError(t, err)
+				req.Header.Set("authorization", "bearer "+accessToken)
+			} else {
+				addAuthHeader(t, req, server.maker, username, time.Hour)
+			}
+
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	username := util.RandomOwner()
+	apiKey, _ := randomAPIKey(t, username, token.ScopeAccountsRead)
+
+	testCases := []struct {
+		name        string
+		id          string
+		buildStore  func(t *testing.T, store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ok",
+			id:   apiKey.ID.String(),
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				arg := db.RevokeAPIKeyParams{
+					ID:       apiKey.ID,
+					Username: username,
+				}
+				revoked := apiKey
+				revoked.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Eq(arg)).Return(revoked, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionAPIKeyRevoke, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			id:   apiKey.ID.String(),
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Return(db.APIKey{}, sql.ErrNoRows).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, resp.Code)
+			},
+		},
+		{
+			name: "BadRequest",
+			id:   "not-a-uuid",
+			buildStore: func(t *testing.T, store *mockdb.MockStore) {
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := mockdb.NewMockStore(ctrl)
+			tc.buildStore(t, mockStore)
+
+			server := newTestServer(t, mockStore)
+			req := httptest.NewRequest(http.MethodDelete, "/api_keys/"+tc.id, nil)
+			addAuthHeader(t, req, server.maker, username, time.Hour)
+
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	username := util.RandomOwner()
+	account := randomAccount(username)
+
+	testCases := []struct {
+		name        string
+		buildKey    func(t *testing.T) (db.APIKey, string)
+		path        string
+		buildStore  func(t *testing.T, store *mockdb.MockStore, apiKey db.APIKey)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ok",
+			buildKey: func(t *testing.T) (db.APIKey, string) {
+				return randomAPIKey(t, username, token.ScopeAccountsRead)
+			},
+			path: fmt.Sprintf("/accounts/%d", account.ID),
+			buildStore: func(t *testing.T, store *mockdb.MockStore, apiKey db.APIKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Return(nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				matchReturnedAccount(t, resp.Body, &account)
+			},
+		},
+		{
+			name: "MissingScope",
+			buildKey: func(t *testing.T) (db.APIKey, string) {
+				return randomAPIKey(t, username, token.ScopeTransfersWrite)
+			},
+			path: fmt.Sprintf("/accounts/%d", account.ID),
+			buildStore: func(t *testing.T, store *mockdb.MockStore, apiKey db.APIKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			name: "RouteNotAllowed",
+			buildKey: func(t *testing.T) (db.APIKey, string) {
+				return randomAPIKey(t, username, token.ScopeAccountsRead, token.ScopeTransfersWrite)
+			},
+			path: "/api_keys",
+			buildStore: func(t *testing.T, store *mockdb.MockStore, apiKey db.APIKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			name: "Revoked",
+			buildKey: func(t *testing.T) (db.APIKey, string) {
+				apiKey, key := randomAPIKey(t, username, token.ScopeAccountsRead)
+				apiKey.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				return apiKey, key
+			},
+			path: fmt.Sprintf("/accounts/%d", account.ID),
+			buildStore: func(t *testing.T, store *mockdb.MockStore, apiKey db.APIKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, resp.Code)
+			},
+		},
+		{
+			name: "WrongSecret",
+			buildKey: func(t *testing.T) (db.APIKey, string) {
+				apiKey, _ := randomAPIKey(t, username, token.ScopeAccountsRead)
+				_, _, otherHash, err := apikey.Generate()
+				require.NoError(t, err)
+				apiKey.HashedSecret = otherHash
+				_, key := randomAPIKey(t, username)
+				prefix, _, err := apikey.Parse(key)
+				require.NoError(t, err)
+				apiKey.Prefix = prefix
+				return apiKey, key
+			},
+			path: fmt.Sprintf("/accounts/%d", account.ID),
+			buildStore: func(t *testing.T, store *mockdb.MockStore, apiKey db.APIKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, resp.Code)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := mockdb.NewMockStore(ctrl)
+			apiKey, key := tc.buildKey(t)
+			tc.buildStore(t, mockStore, apiKey)
+
+			server := newTestServer(t, mockStore)
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("authorization", "apikey "+key)
+
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
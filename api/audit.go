@@ -0,0 +1,58 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const requestIDHeader = "x-request-id"
+
+// auditClient describes the caller for the audit log.
+func auditClient(ctx *gin.Context) audit.Client {
+	return audit.Client{
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		RequestID: ctx.GetHeader(requestIDHeader),
+	}
+}
+
+type listAuditLogRequest struct {
+	Actor        string    `form:"actor"`
+	Action       string    `form:"action"`
+	ResourceType string    `form:"resource_type"`
+	ResourceID   string    `form:"resource_id"`
+	Since        time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until        time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	PageID       int32     `form:"page_id" binding:"required,min=1"`
+	PageSize     int32     `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listAuditLog lets bankers search the audit log, newest entries first. Every filter is optional.
+func (server *Server) listAuditLog(ctx *gin.Context) {
+	var req listAuditLogRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+
+	entries, err := server.store.ListAuditLog(ctx, db.ListAuditLogParams{
+		Actor:        audit.NullString(req.Actor),
+		Action:       audit.NullString(req.Action),
+		ResourceType: audit.NullString(req.ResourceType),
+		ResourceID:   audit.NullString(req.ResourceID),
+		Since:        sql.NullTime{Time: req.Since, Valid: !req.Since.IsZero()},
+		Until:        sql.NullTime{Time: req.Until, Valid: !req.Until.IsZero()},
+		PageLimit:    req.PageSize,
+		PageOffset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, entries)
+}
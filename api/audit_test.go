@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// eqAuditEventMatcher matches the audit log entry of an action with the given outcome.
+type eqAuditEventMatcher struct {
+	action  string
+	outcome string
+}
+
+func (e eqAuditEventMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateAuditLogParams)
+	return ok && arg.Action == e.action && arg.Outcome == e.outcome
+}
+
+func (e eqAuditEventMatcher) String() string {
+	return fmt.Sprintf("audit log entry for %s with outcome %s", e.action, e.outcome)
+}
+
+func EqAuditEvent(action, outcome string) gomock.Matcher {
+	return eqAuditEventMatcher{action, outcome}
+}
+
+func TestListAuditLogAPI(t *testing.T) {
+	username := util.RandomOwner()
+	entries := []db.AuditLog{
+		{
+			ID:           2,
+			Actor:        username,
+			Action:       audit.ActionLogin,
+			Outcome:      audit.OutcomeFailure,
+			ResourceType: audit.ResourceUser,
+			ResourceID:   username,
+			Before:       json.RawMessage("{}"),
+			After:        json.RawMessage("{}"),
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		query       string
+		scopes      []string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ok",
+			query:  "?page_id=2&page_size=5&actor=" + username + "&since=2026-01-02T15:04:05Z",
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLog(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.ListAuditLogParams) ([]db.AuditLog, error) {
+						require.Equal(t, username, arg.Actor.String)
+						require.False(t, arg.Action.Valid)
+						require.True(t, arg.Since.Valid)
+						require.False(t, arg.Until.Valid)
+						require.Equal(t, int32(5), arg.PageLimit)
+						require.Equal(t, int32(5), arg.PageOffset)
+						return entries, nil
+					}).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got []db.AuditLog
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Len(t, got, 1)
+				require.Equal(t, entries[0].Action, got[0].Action)
+			},
+		},
+		{
+			name:   "Depositor",
+			query:  "?page_id=1&page_size=5",
+			scopes: token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLog(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			name:   "InvalidSince",
+			query:  "?page_id=1&page_size=5&since=yesterday",
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLog(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(username, tc.scopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/audit_log"+tc.query, nil)
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
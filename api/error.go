@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// respondError aborts the request with the error envelope shared with the gateway. Internal
+// errors are logged with their cause, which is never sent to the client.
+func respondError(ctx *gin.Context, err error) {
+	appErr := apperr.From(err)
+	if appErr.Code == apperr.CodeInternal {
+		log.Error().Err(err).Str("path", ctx.FullPath()).Msg("internal error")
+	}
+	ctx.Error(err)
+	ctx.AbortWithStatusJSON(appErr.HTTPStatus(), appErr.Envelope())
+}
+
+// bindError turns an error from binding a request into field violations, named as the client
+// sent them.
+func bindError(err error) *apperr.Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		violations := make([]apperr.FieldViolation, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			violations = append(violations, apperr.Violation(fe.Field(), describeFieldError(fe)))
+		}
+		return apperr.InvalidArgument(violations...)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return apperr.InvalidArgument(apperr.Violation(typeErr.Field, "must be a "+typeErr.Type.String()))
+	}
+	return apperr.Wrap(err, apperr.CodeInvalidArgument, "malformed request body")
+}
+
+func describeFieldError(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	} else if fe.Kind() == reflect.Slice {
+		unit = " items"
+	}
+
+	if rule, ok := fieldRules[fe.Tag()]; ok {
+		if err := rule(reflect.ValueOf(fe.Value())); err != nil {
+			return err.Error()
+		}
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "email":
+		return "must be a valid email address"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "uuid":
+		return "must be a UUID"
+	}
+	return "is invalid"
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/require"
+)
+
+// requireErrorBody checks that resp carries the error envelope with code, and violations for
+// exactly fields.
+func requireErrorBody(t *testing.T, resp *httptest.ResponseRecorder, code apperr.Code, fields ...string) {
+	var body apperr.Envelope
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	require.Equal(t, code, body.Error.Code)
+	require.NotEmpty(t, body.Error.Message)
+
+	var got []string
+	for _, v := range body.Error.Violations {
+		require.NotEmpty(t, v.Description)
+		got = append(got, v.Field)
+	}
+	require.ElementsMatch(t, fields, got)
+}
+
+func TestBindError(t *testing.T) {
+	registerValidators()
+
+	req := transferRequest{ToAccountID: 1, Amount: -5, Currency: "GBP"}
+	err := bindError(binding.Validator.ValidateStruct(&req))
+
+	require.Equal(t, apperr.CodeInvalidArgument, err.Code)
+	require.ElementsMatch(t, []apperr.FieldViolation{
+		apperr.Violation("from_account_id", "is required"),
+		apperr.Violation("amount", "must be greater than 0"),
+		apperr.Violation("currency", "must be one of USD, EUR, CAD"),
+	}, err.Violations)
+
+	var syntaxErr json.SyntaxError
+	err = bindError(&syntaxErr)
+	require.Equal(t, apperr.CodeInvalidArgument, err.Code)
+	require.Empty(t, err.Violations)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type feeScheduleResponse struct {
+	Currency     string `json:"currency"`
+	TransferType string `json:"transfer_type"`
+	Kind         string `json:"kind"`
+	// Value is in minor units for flat fees, and in basis points of the amount for percentages.
+	Value     int64     `json:"value"`
+	SetBy     string    `json:"set_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newFeeScheduleResponse(schedule db.FeeSchedule) *feeScheduleResponse {
+	return &feeScheduleResponse{
+		Currency:     schedule.Currency,
+		TransferType: schedule.TransferType,
+		Kind:         schedule.Kind,
+		Value:        schedule.Value,
+		SetBy:        schedule.SetBy,
+		UpdatedAt:    schedule.UpdatedAt,
+	}
+}
+
+// listFees lists what transfers cost. Transfers not listed are free.
+func (server *Server) listFees(ctx *gin.Context) {
+	schedules, err := server.store.ListFeeSchedules(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	rsp := make([]*feeScheduleResponse, 0, len(schedules))
+	for _, schedule := range schedules {
+		rsp = append(rsp, newFeeScheduleResponse(schedule))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type feeScheduleURI struct {
+	Currency     string `uri:"currency" binding:"required,currency"`
+	TransferType string `uri:"transfer_type" binding:"required,oneof=internal external"`
+}
+
+type setFeeRequest struct {
+	Kind  string `json:"kind" binding:"required,oneof=flat percentage"`
+	Value *int64 `json:"value" binding:"required,min=0"`
+}
+
+// setFee lets bankers set the fee of a type of transfer in a currency. It applies to the transfers
+// that post from then on, including held ones approved later.
+func (server *Server) setFee(ctx *gin.Context) {
+	var uri feeScheduleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	var req setFeeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	if req.Kind == db.FeePercentage && *req.Value > 10000 {
+		respondError(ctx, apperr.InvalidArgument(apperr.Violation("value", "a percentage can't be over 10000 basis points")))
+		return
+	}
+
+	// Before stays empty in the audit log when the transfers were free.
+	var before interface{}
+	previous, err := server.store.GetFeeSchedule(ctx, db.GetFeeScheduleParams{
+		Currency:     uri.Currency,
+		TransferType: uri.TransferType,
+	})
+	if err == nil {
+		before = newFeeScheduleResponse(previous)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		respondError(ctx, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	schedule, err := server.store.SetFeeSchedule(ctx, db.SetFeeScheduleParams{
+		Currency:     uri.Currency,
+		TransferType: uri.TransferType,
+		Kind:         req.Kind,
+		Value:        *req.Value,
+		SetBy:        payload.Username,
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "fee"))
+		return
+	}
+	rsp := newFeeScheduleResponse(schedule)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionFeeUpdate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceFeeSchedule,
+		ResourceID:   uri.Currency + "/" + uri.TransferType,
+		Client:       auditClient(ctx),
+		Before:       before,
+		After:        rsp,
+	})
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// deleteFee lets bankers make a type of transfer in a currency free again, and returns the fee it
+// charged.
+func (server *Server) deleteFee(ctx *gin.Context) {
+	var uri feeScheduleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	params := db.GetFeeScheduleParams{
+		Currency:     uri.Currency,
+		TransferType: uri.TransferType,
+	}
+	previous, err := server.store.GetFeeSchedule(ctx, params)
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "fee"))
+		return
+	}
+	_, err = server.store.DeleteFeeSchedule(ctx, db.DeleteFeeScheduleParams(params))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	rsp := newFeeScheduleResponse(previous)
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionFeeDelete,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceFeeSchedule,
+		ResourceID:   uri.Currency + "/" + uri.TransferType,
+		Client:       auditClient(ctx),
+		Before:       rsp,
+	})
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFeesAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListFeeSchedules(gomock.Any()).Return([]db.FeeSchedule{
+		{Currency: util.USD, TransferType: db.TransferExternal, Kind: db.FeeFlat, Value: 25, SetBy: "banker"},
+	}, nil).Times(1)
+	server := newTestServer(t, store)
+
+	req := httptest.NewRequest(http.MethodGet, "/fees", nil)
+	addAuthHeader(t, req, server.maker, util.RandomOwner(), time.Minute)
+	resp := httptest.NewRecorder()
+	server.router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	var got []feeScheduleResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, db.FeeFlat, got[0].Kind)
+	require.Equal(t, int64(25), got[0].Value)
+}
+
+func TestSetFeeAPI(t *testing.T) {
+	banker := util.RandomOwner()
+	params := db.GetFeeScheduleParams{Currency: util.USD, TransferType: db.TransferExternal}
+
+	testCases := []struct {
+		name        string
+		path        string
+		body        gin.H
+		scopes      []string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			path:   "/fees/USD/external",
+			body:   gin.H{"kind": db.FeePercentage, "value": 150},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFeeSchedule(gomock.Any(), gomock.Eq(params)).Return(db.FeeSchedule{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().SetFeeSchedule(gomock.Any(), gomock.Eq(db.SetFeeScheduleParams{
+					Currency:     util.USD,
+					TransferType: db.TransferExternal,
+					Kind:         db.FeePercentage,
+					Value:        150,
+					SetBy:        banker,
+				})).Return(db.FeeSchedule{
+					Currency:     util.USD,
+					TransferType: db.TransferExternal,
+					Kind:         db.FeePercentage,
+					Value:        150,
+					SetBy:        banker,
+				}, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionFeeUpdate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got feeScheduleResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, int64(150), got.Value)
+				require.Equal(t, banker, got.SetBy)
+			},
+		},
+		{
+			name:   "PercentageTooHigh",
+			path:   "/fees/USD/external",
+			body:   gin.H{"kind": db.FeePercentage, "value": 10001},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().SetFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:   "UnknownKind",
+			path:   "/fees/USD/external",
+			body:   gin.H{"kind": "tiered", "value": 10},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().SetFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:   "UnknownTransferType",
+			path:   "/fees/USD/fee",
+			body:   gin.H{"kind": db.FeeFlat, "value": 10},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:   "Depositor",
+			path:   "/fees/USD/external",
+			body:   gin.H{"kind": db.FeeFlat, "value": 0},
+			scopes: token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().SetFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(banker, tc.scopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPut, tc.path, bytes.NewReader(data))
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestDeleteFeeAPI(t *testing.T) {
+	params := db.GetFeeScheduleParams{Currency: util.EUR, TransferType: db.TransferInternal}
+	schedule := db.FeeSchedule{Currency: util.EUR, TransferType: db.TransferInternal, Kind: db.FeeFlat, Value: 10}
+
+	testCases := []struct {
+		name        string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFeeSchedule(gomock.Any(), gomock.Eq(params)).Return(schedule, nil).Times(1)
+				store.EXPECT().DeleteFeeSchedule(gomock.Any(), gomock.Eq(db.DeleteFeeScheduleParams(params))).Return(int64(1), nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionFeeDelete, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got feeScheduleResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, int64(10), got.Value)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetFeeSchedule(gomock.Any(), gomock.Eq(params)).Return(db.FeeSchedule{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().DeleteFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(util.RandomOwner(), token.BankerScopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodDelete, "/fees/EUR/internal", nil)
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type interestRateResponse struct {
+	AccountType string `json:"account_type"`
+	// AnnualRateBps is the yearly rate in basis points: 250 is 2.5%.
+	AnnualRateBps int32     `json:"annual_rate_bps"`
+	SetBy         string    `json:"set_by,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func newInterestRateResponse(rate db.InterestRate) interestRateResponse {
+	return interestRateResponse{
+		AccountType:   rate.AccountType,
+		AnnualRateBps: rate.AnnualRateBps,
+		SetBy:         rate.SetBy.String,
+		UpdatedAt:     rate.UpdatedAt,
+	}
+}
+
+// listInterestRates lists the interest each type of account earns.
+func (server *Server) listInterestRates(ctx *gin.Context) {
+	rates, err := server.store.ListInterestRates(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	rsp := make([]interestRateResponse, 0, len(rates))
+	for _, rate := range rates {
+		rsp = append(rsp, newInterestRateResponse(rate))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type interestRateURI struct {
+	AccountType string `uri:"account_type" binding:"required,account_type"`
+}
+
+type setInterestRateRequest struct {
+	AnnualRateBps *int32 `json:"annual_rate_bps" binding:"required,min=0,max=10000"`
+}
+
+// setInterestRate lets bankers change the rate of a type of account. Interest already accrued
+// keeps the rate it accrued at.
+func (server *Server) setInterestRate(ctx *gin.Context) {
+	var uri interestRateURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	var req setInterestRateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	previous, err := server.store.GetInterestRate(ctx, uri.AccountType)
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "interest rate"))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	rate, err := server.store.SetInterestRate(ctx, db.SetInterestRateParams{
+		AccountType:   uri.AccountType,
+		AnnualRateBps: *req.AnnualRateBps,
+		SetBy:         sql.NullString{String: payload.Username, Valid: true},
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "interest rate"))
+		return
+	}
+	rsp := newInterestRateResponse(rate)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionInterestRate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceInterestRate,
+		ResourceID:   uri.AccountType,
+		Client:       auditClient(ctx),
+		Before:       newInterestRateResponse(previous),
+		After:        rsp,
+	})
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type interestReportRequest struct {
+	Month time.Time `form:"month" binding:"required" time_format:"2006-01" time_utc:"1"`
+}
+
+type interestReportResponse struct {
+	Month      string                  `json:"month"`
+	Currencies []db.InterestReportLine `json:"currencies"`
+}
+
+// getInterestReport compares the interest accrued over a month with what was posted for it, per
+// currency, for bankers.
+func (server *Server) getInterestReport(ctx *gin.Context) {
+	var req interestReportRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	report, err := server.store.InterestReport(ctx, req.Month)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, interestReportResponse{
+		Month:      req.Month.Format("2006-01"),
+		Currencies: report,
+	})
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListInterestRatesAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListInterestRates(gomock.Any()).Return([]db.InterestRate{
+		{AccountType: util.Checking, AnnualRateBps: 0},
+		{AccountType: util.Savings, AnnualRateBps: 200, SetBy: sql.NullString{String: "banker", Valid: true}},
+	}, nil).Times(1)
+	server := newTestServer(t, store)
+
+	req := httptest.NewRequest(http.MethodGet, "/interest_rates", nil)
+	addAuthHeader(t, req, server.maker, util.RandomOwner(), time.Minute)
+	resp := httptest.NewRecorder()
+	server.router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	var got []interestRateResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+	require.Len(t, got, 2)
+	require.Equal(t, int32(200), got[1].AnnualRateBps)
+	require.Equal(t, "banker", got[1].SetBy)
+}
+
+func TestSetInterestRateAPI(t *testing.T) {
+	banker := util.RandomOwner()
+	previous := db.InterestRate{AccountType: util.Savings, AnnualRateBps: 200}
+
+	testCases := []struct {
+		name        string
+		accountType string
+		body        gin.H
+		scopes      []string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:        "OK",
+			accountType: util.Savings,
+			body:        gin.H{"annual_rate_bps": 250},
+			scopes:      token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetInterestRate(gomock.Any(), gomock.Eq(util.Savings)).Return(previous, nil).Times(1)
+				store.EXPECT().SetInterestRate(gomock.Any(), gomock.Eq(db.SetInterestRateParams{
+					AccountType:   util.Savings,
+					AnnualRateBps: 250,
+					SetBy:         sql.NullString{String: banker, Valid: true},
+				})).Return(db.InterestRate{
+					AccountType:   util.Savings,
+					AnnualRateBps: 250,
+					SetBy:         sql.NullString{String: banker, Valid: true},
+				}, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionInterestRate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got interestRateResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, int32(250), got.AnnualRateBps)
+				require.Equal(t, banker, got.SetBy)
+			},
+		},
+		{
+			name:        "Zero",
+			accountType: util.Checking,
+			body:        gin.H{"annual_rate_bps": 0},
+			scopes:      token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetInterestRate(gomock.Any(), gomock.Eq(util.Checking)).Return(db.InterestRate{AccountType: util.Checking}, nil).Times(1)
+				store.EXPECT().SetInterestRate(gomock.Any(), gomock.Any()).Return(db.InterestRate{AccountType: util.Checking}, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name:        "TooHigh",
+			accountType: util.Savings,
+			body:        gin.H{"annual_rate_bps": 10001},
+			scopes:      token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().SetInterestRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:        "Missing",
+			accountType: util.Savings,
+			body:        gin.H{},
+			scopes:      token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().SetInterestRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:        "UnknownType",
+			accountType: "brokerage",
+			body:        gin.H{"annual_rate_bps": 100},
+			scopes:      token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetInterestRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:        "Depositor",
+			accountType: util.Savings,
+			body:        gin.H{"annual_rate_bps": 9000},
+			scopes:      token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().SetInterestRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(banker, tc.scopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPut, "/interest_rates/"+tc.accountType, bytes.NewReader(data))
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestInterestReportAPI(t *testing.T) {
+	month := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		query       string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "month=2024-02",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().InterestReport(gomock.Any(), gomock.Eq(month)).Return([]db.InterestReportLine{
+					{Currency: util.USD, Accounts: 3, Accrued: 120, Unposted: 0, Posted: 121},
+				}, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				require.JSONEq(t, `{"month": "2024-02", "currencies": [
+					{"currency": "USD", "accounts": 3, "accrued": 120, "unposted": 0, "posted": 121}
+				]}`, resp.Body.String())
+			},
+		},
+		{
+			name:  "BadMonth",
+			query: "month=2024-02-01",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().InterestReport(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:  "NoMonth",
+			query: "",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().InterestReport(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(util.RandomOwner(), token.BankerScopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/interest_report?"+tc.query, nil)
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/ledger"
+	"github.com/gin-gonic/gin"
+)
+
+type verifyLedgerRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// verifyLedger walks the hash chain of an account's entries for bankers and auditors. A broken
+// chain is reported in the response, not as an error.
+func (server *Server) verifyLedger(ctx *gin.Context) {
+	var req verifyLedgerRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+
+	report, err := ledger.Verify(ctx, server.store, []byte(server.config.LedgerKey), req.ID)
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "account"))
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/ledger"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyLedgerAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+
+	// readTx runs the verification against the mock itself.
+	readTx := func(store *mockdb.MockStore) {
+		store.EXPECT().ReadTx(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, fn func(context.Context, db.Querier) error) error {
+				return fn(ctx, store)
+			}).Times(1)
+	}
+
+	testCases := []struct {
+		name        string
+		accountID   int64
+		scopes      []string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "BrokenChain",
+			accountID: account.ID,
+			scopes:    token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				readTx(store)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().ListLedgerEntries(gomock.Any(), gomock.Any()).Return([]db.ListLedgerEntriesRow{
+					{ID: 1, AccountID: account.ID, Amount: 10, Hash: []byte("forged")},
+				}, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var report ledger.Report
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+				require.False(t, report.OK())
+				require.Equal(t, int64(1), report.BrokenLink.EntryID)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			scopes:    token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				readTx(store)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(db.Account{}, sql.ErrNoRows).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, resp.Code)
+			},
+		},
+		{
+			name:      "Depositor",
+			accountID: account.ID,
+			scopes:    token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().ReadTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			accountID: 0,
+			scopes:    token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().ReadTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(util.RandomOwner(), tc.scopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d/ledger/verify", tc.accountID), nil)
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// getLimits shows what can still leave an account under its limits and its owner's. Bankers can
+// look at any account.
+func (server *Server) getLimits(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "account"))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username != account.Owner && !payload.HasScope(token.ScopeAccountsAdmin) {
+		respondError(ctx, apperr.New(apperr.CodePermissionDenied, "account does not belong to the authenticated user"))
+		return
+	}
+
+	allowance, err := server.store.TransferAllowance(ctx, account, server.transferLimits[account.Currency])
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, allowance)
+}
+
+type limitsRequest struct {
+	// Limits left out keep the default; 0 lifts a limit.
+	Single  *int64 `json:"single" binding:"omitempty,min=0"`
+	Daily   *int64 `json:"daily" binding:"omitempty,min=0"`
+	Monthly *int64 `json:"monthly" binding:"omitempty,min=0"`
+	Reason  string `json:"reason" binding:"required,max=200"`
+}
+
+type setUserLimitsRequest struct {
+	limitsRequest
+	Currency string `json:"currency" binding:"required,currency"`
+}
+
+type setUserLimitsURI struct {
+	Username string `uri:"username" binding:"required,username"`
+}
+
+type limitsResponse struct {
+	Username  string `json:"username,omitempty"`
+	AccountID int64  `json:"account_id,omitempty"`
+	Currency  string `json:"currency"`
+	// Null limits keep the default.
+	Single    *int64    `json:"single"`
+	Daily     *int64    `json:"daily"`
+	Monthly   *int64    `json:"monthly"`
+	SetBy     string    `json:"set_by"`
+	Reason    string    `json:"reason"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newLimitsResponse(limit db.TransferLimit) *limitsResponse {
+	return &limitsResponse{
+		Username:  limit.Username.String,
+		AccountID: limit.AccountID.Int64,
+		Currency:  limit.Currency,
+		Single:    nullInt64(limit.SingleMax),
+		Daily:     nullInt64(limit.DailyMax),
+		Monthly:   nullInt64(limit.MonthlyMax),
+		SetBy:     limit.SetBy,
+		Reason:    limit.Reason,
+		UpdatedAt: limit.UpdatedAt,
+	}
+}
+
+func nullInt64(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
+
+func toNullInt64(n *int64) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *n, Valid: true}
+}
+
+// setUserLimits lets bankers replace the default limits of a user in a currency, over all of the
+// user's accounts in it.
+func (server *Server) setUserLimits(ctx *gin.Context) {
+	var uri setUserLimitsURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	var req setUserLimitsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	if _, err := server.store.GetUser(ctx, uri.Username); err != nil {
+		respondError(ctx, apperr.FromDB(err, "user"))
+		return
+	}
+
+	// Before stays empty in the audit log when the limits were the defaults.
+	var before interface{}
+	previous, err := server.store.GetUserTransferLimit(ctx, db.GetUserTransferLimitParams{
+		Username: sql.NullString{String: uri.Username, Valid: true},
+		Currency: req.Currency,
+	})
+	if err == nil {
+		before = newLimitsResponse(previous)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		respondError(ctx, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	limit, err := server.store.SetUserTransferLimit(ctx, db.SetUserTransferLimitParams{
+		Username:   sql.NullString{String: uri.Username, Valid: true},
+		Currency:   req.Currency,
+		SingleMax:  toNullInt64(req.Single),
+		DailyMax:   toNullInt64(req.Daily),
+		MonthlyMax: toNullInt64(req.Monthly),
+		SetBy:      payload.Username,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "transfer limit"))
+		return
+	}
+	rsp := newLimitsResponse(limit)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionLimitsUpdate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   uri.Username,
+		Client:       auditClient(ctx),
+		Before:       before,
+		After:        rsp,
+	})
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// setAccountLimits lets bankers limit one account, on top of its owner's limits.
+func (server *Server) setAccountLimits(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	var req limitsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "account"))
+		return
+	}
+
+	// Before stays empty in the audit log when the limits were the defaults.
+	var before interface{}
+	previous, err := server.store.GetAccountTransferLimit(ctx, sql.NullInt64{Int64: account.ID, Valid: true})
+	if err == nil {
+		before = newLimitsResponse(previous)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		respondError(ctx, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	limit, err := server.store.SetAccountTransferLimit(ctx, db.SetAccountTransferLimitParams{
+		AccountID:  sql.NullInt64{Int64: account.ID, Valid: true},
+		Currency:   account.Currency,
+		SingleMax:  toNullInt64(req.Single),
+		DailyMax:   toNullInt64(req.Daily),
+		MonthlyMax: toNullInt64(req.Monthly),
+		SetBy:      payload.Username,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "transfer limit"))
+		return
+	}
+	rsp := newLimitsResponse(limit)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionLimitsUpdate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceAccount,
+		ResourceID:   strconv.FormatInt(account.ID, 10),
+		Client:       auditClient(ctx),
+		Before:       before,
+		After:        rsp,
+	})
+	ctx.JSON(http.StatusOK, rsp)
+}
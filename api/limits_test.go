@@ -0,0 +1,233 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/ashokmouli/simplebank/txlimit"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLimitsAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	allowance := txlimit.Allowance{
+		AccountID: account.ID,
+		Currency:  account.Currency,
+		Daily:     txlimit.Window{Limit: 100, Used: 40, Remaining: 60, Scope: txlimit.ScopeUser},
+	}
+
+	testCases := []struct {
+		name        string
+		username    string
+		scopes      []string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Owner",
+			username: account.Owner,
+			scopes:   token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().TransferAllowance(gomock.Any(), gomock.Eq(account), gomock.Any()).Return(allowance, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got txlimit.Allowance
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, allowance, got)
+			},
+		},
+		{
+			name:     "Banker",
+			username: util.RandomOwner(),
+			scopes:   token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().TransferAllowance(gomock.Any(), gomock.Any(), gomock.Any()).Return(allowance, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name:     "SomeoneElse",
+			username: util.RandomOwner(),
+			scopes:   token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().TransferAllowance(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(tc.username, tc.scopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d/limits", account.ID), nil)
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestSetLimitsAPI(t *testing.T) {
+	banker := util.RandomOwner()
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	previous := db.TransferLimit{
+		AccountID: sql.NullInt64{Int64: account.ID, Valid: true},
+		Currency:  account.Currency,
+		SingleMax: sql.NullInt64{Int64: 10, Valid: true},
+		SetBy:     banker,
+		Reason:    "new account",
+	}
+
+	testCases := []struct {
+		name        string
+		path        string
+		body        gin.H
+		scopes      []string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "User",
+			path:   "/users/" + user.Username + "/limits",
+			body:   gin.H{"currency": util.USD, "daily": 0, "reason": "house purchase"},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(user, nil).Times(1)
+				store.EXPECT().GetUserTransferLimit(gomock.Any(), gomock.Any()).Return(db.TransferLimit{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().SetUserTransferLimit(gomock.Any(), gomock.Eq(db.SetUserTransferLimitParams{
+					Username: sql.NullString{String: user.Username, Valid: true},
+					Currency: util.USD,
+					DailyMax: sql.NullInt64{Int64: 0, Valid: true},
+					SetBy:    banker,
+					Reason:   "house purchase",
+				})).Return(db.TransferLimit{
+					Username: sql.NullString{String: user.Username, Valid: true},
+					Currency: util.USD,
+					DailyMax: sql.NullInt64{Int64: 0, Valid: true},
+					SetBy:    banker,
+					Reason:   "house purchase",
+				}, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionLimitsUpdate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got limitsResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Nil(t, got.Single)
+				require.Equal(t, int64(0), *got.Daily)
+			},
+		},
+		{
+			name:   "Account",
+			path:   fmt.Sprintf("/accounts/%d/limits", account.ID),
+			body:   gin.H{"single": 1000, "reason": "verified"},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().GetAccountTransferLimit(gomock.Any(), gomock.Any()).Return(previous, nil).Times(1)
+				store.EXPECT().SetAccountTransferLimit(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.SetAccountTransferLimitParams) (db.TransferLimit, error) {
+						require.Equal(t, account.Currency, arg.Currency)
+						require.Equal(t, int64(1000), arg.SingleMax.Int64)
+						require.False(t, arg.DailyMax.Valid)
+						return db.TransferLimit{AccountID: arg.AccountID, Currency: arg.Currency, SingleMax: arg.SingleMax}, nil
+					}).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionLimitsUpdate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name:   "NegativeLimit",
+			path:   fmt.Sprintf("/accounts/%d/limits", account.ID),
+			body:   gin.H{"single": -1, "reason": "oops"},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:   "NoReason",
+			path:   "/users/" + user.Username + "/limits",
+			body:   gin.H{"currency": util.USD, "daily": 0},
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().SetUserTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "reason")
+			},
+		},
+		{
+			name:   "Depositor",
+			path:   "/users/" + user.Username + "/limits",
+			body:   gin.H{"currency": util.USD, "daily": 0, "reason": "because"},
+			scopes: token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().SetUserTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(banker, tc.scopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPut, tc.path, bytes.NewReader(data))
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
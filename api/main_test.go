@@ -7,6 +7,8 @@ import (
 
 	db "github.com/ashokmouli/simplebank/db/sqlc"
 	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/password"
+	"github.com/ashokmouli/simplebank/token"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 )
@@ -15,8 +17,11 @@ func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config {
 		DBDriver: "Notused",
 		DBSource: "Notused",
+		TokenMaker: token.MakerPaseto,
 		TokenSymmetricKey: util.RandomString(32),
 		AccessTokenDuration: time.Minute,
+		PasswordHasher: password.AlgorithmArgon2id,
+		PasswordMinLength: 6,
 	}
 
 	server, err :=  NewServer(store, config)
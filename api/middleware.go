@@ -2,47 +2,70 @@ package api
 
 import (
 	"errors"
-	"fmt"
-	"net/http"
 	"strings"
 
+	"github.com/ashokmouli/simplebank/apikey"
+	"github.com/ashokmouli/simplebank/apperr"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/ratelimit"
 	"github.com/ashokmouli/simplebank/token"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
 const (
 	authorizationHeaderKey = "authorization"
 	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
 	authorizationPayloadKey = "auth_payload"
 )
 
-func createAuthMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func createAuthMiddleware(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
 		// The header should be of the form 
 		// authorization: bearer <token>
+		// or, for machine clients,
+		// authorization: apikey <key>
 		authHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authHeader) == 0 {
-			err := errors.New("authorization header not found")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			respondError(ctx, apperr.New(apperr.CodeUnauthenticated, "authorization header not found"))
 			return
 		}
 
 		tokenString := strings.Fields(authHeader)
-
-		// Validate that the first field is the literal "bearer"
-		authType := strings.ToLower(tokenString[0])
-		if authType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type: %s", authType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		if len(tokenString) != 2 {
+			respondError(ctx, apperr.New(apperr.CodeUnauthenticated, "invalid authorization header format"))
 			return
 		}
-		//  Pluck out the auth token and verify it.
-		authToken := tokenString[1]
 
-		payload, err := tokenMaker.VerifyToken(authToken)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		// Validate that the first field is the literal "bearer" or "apikey"
+		authType := strings.ToLower(tokenString[0])
+		var payload *token.Payload
+		switch authType {
+		case authorizationTypeBearer:
+			//  Pluck out the auth token and verify it.
+			authToken := tokenString[1]
+
+			var err error
+			payload, err = tokenMaker.VerifyToken(authToken, token.AccessToken)
+			if err != nil {
+				respondError(ctx, apperr.Wrap(err, apperr.CodeUnauthenticated, err.Error()))
+				return
+			}
+		case authorizationTypeAPIKey:
+			apiKey, err := apikey.Authenticate(ctx, store, tokenString[1])
+			if err != nil {
+				if errors.Is(err, apikey.ErrInvalidKey) || errors.Is(err, apikey.ErrRevokedKey) || errors.Is(err, apikey.ErrExpiredKey) {
+					respondError(ctx, apperr.Wrap(err, apperr.CodeUnauthenticated, err.Error()))
+					return
+				}
+				respondError(ctx, err)
+				return
+			}
+			payload = apikey.NewPayload(apiKey)
+		default:
+			respondError(ctx, apperr.Newf(apperr.CodeUnauthenticated, "unsupported authorization type: %s", authType))
 			return
 		}
 
@@ -51,3 +74,41 @@ func createAuthMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// requireScope declares the scope a route needs. It must run after the auth middleware.
+func requireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := (ctx.MustGet(authorizationPayloadKey)).(*token.Payload)
+		if !payload.HasScope(scope) {
+			respondError(ctx, apperr.Newf(apperr.CodePermissionDenied, "token is missing the %s scope", scope))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// rateLimit limits how often a caller can use a route. Rules without a configured limit share the
+// default one. Authenticated callers are counted by username, so it must run after the auth
+// middleware on authenticated routes.
+func (server *Server) rateLimit(rule string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		caller := ratelimit.IPCaller(ctx.ClientIP())
+		if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+			caller = ratelimit.UserCaller(payload.(*token.Payload).Username)
+		}
+
+		allowed, retryAfter, err := server.limiter.Allow(ctx, rule, caller)
+		if err != nil {
+			// Better to serve unthrottled than to fail every request while the backend is down.
+			log.Warn().Err(err).Str("rule", rule).Msg("cannot check rate limit")
+			ctx.Next()
+			return
+		}
+		if !allowed {
+			ctx.Header("Retry-After", ratelimit.RetryAfterSeconds(retryAfter))
+			respondError(ctx, apperr.Wrap(ratelimit.ErrLimitExceeded, apperr.CodeRateLimited, ratelimit.ErrLimitExceeded.Error()))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/ratelimit"
 	"github.com/ashokmouli/simplebank/token"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -19,7 +20,7 @@ func addAuthHeader(
 	username string,
 	duration time.Duration) {
 
-	token, payload, err := tokenMaker.CreateToken(username, duration)
+	token, payload, err := tokenMaker.CreateToken(username, token.UserScopes, token.AccessToken, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 	require.NotEmpty(t, payload)
@@ -46,7 +47,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "no authorization",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				/* token, err := tokenMaker.CreateToken(username, duration)
+				/* token, err := tokenMaker.CreateToken(username, token.UserScopes, token.AccessToken, duration)
 				require.NoError(t, err)
 				require.NotEmpty(t, token)
 				req.Header.Set("authorization", "bearer " + token) */
@@ -58,7 +59,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "invalid auth type",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				token, payload, err := tokenMaker.CreateToken(username, duration)
+				token, payload, err := tokenMaker.CreateToken(username, token.UserScopes, token.AccessToken, duration)
 				require.NoError(t, err)
 				require.NotEmpty(t, token)
 				require.NotEmpty(t, payload)
@@ -72,7 +73,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "Expired token",
 			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
-				token, payload, err := tokenMaker.CreateToken(username, -time.Minute)
+				token, payload, err := tokenMaker.CreateToken(username, token.UserScopes, token.AccessToken, -time.Minute)
 				require.NoError(t, err)
 				require.NotEmpty(t, token)
 				require.NotEmpty(t, payload)
@@ -82,6 +83,20 @@ func TestAuthMiddleware(t *testing.T) {
 				require.Equal(t, http.StatusUnauthorized, resp.Code)
 			},
 		},
+		{
+			name: "Refresh token",
+			setupAuth: func(t *testing.T, req *http.Request, tokenMaker token.Maker) {
+				token, payload, err := tokenMaker.CreateToken(username, token.UserScopes, token.RefreshToken, duration)
+				require.NoError(t, err)
+				require.NotEmpty(t, token)
+				require.NotEmpty(t, payload)
+				// A refresh token must not be accepted in place of an access token.
+				req.Header.Set("authorization", "bearer "+token)
+			},
+			checkResponse: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, resp.Code)
+			},
+		},
 	}
 
 	for i := range testCases {
@@ -91,7 +106,7 @@ func TestAuthMiddleware(t *testing.T) {
 
 			server.router.GET(
 				"/auth",
-				createAuthMiddleware(server.maker),
+				createAuthMiddleware(server.maker, server.store),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -106,3 +121,137 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireScope(t *testing.T) {
+
+	username := util.RandomOwner()
+	testCases := []struct {
+		name          string
+		scopes        []string
+		checkResponse func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ok",
+			scopes: []string{token.ScopeAccountsRead},
+			checkResponse: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name:   "missing scope",
+			scopes: []string{token.ScopeTransfersWrite},
+			checkResponse: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			name:   "no scopes",
+			scopes: nil,
+			checkResponse: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			server.router.GET(
+				"/auth",
+				createAuthMiddleware(server.maker, server.store),
+				requireScope(token.ScopeAccountsRead),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			req, err := http.NewRequest(http.MethodGet, "/auth", nil)
+			require.NoError(t, err)
+			token, _, err := server.maker.CreateToken(username, tc.scopes, token.AccessToken, time.Hour)
+			require.NoError(t, err)
+			req.Header.Set("authorization", "bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.limiter = ratelimit.New(ratelimit.NewMemoryBackend(), map[string]ratelimit.Limit{
+		ratelimit.DefaultRule: {Rate: 1.0 / 60, Burst: 2},
+	})
+
+	server.router.GET("/public", server.rateLimit("public"), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+	server.router.GET(
+		"/private",
+		createAuthMiddleware(server.maker, server.store),
+		server.rateLimit("private"),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		},
+	)
+
+	send := func(path string, remoteAddr string, username string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		req.RemoteAddr = remoteAddr
+		if username != "" {
+			addAuthHeader(t, req, server.maker, username, time.Minute)
+		}
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Anonymous callers are limited per IP address.
+	require.Equal(t, http.StatusOK, send("/public", "10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusOK, send("/public", "10.0.0.1:1001", "").Code)
+	recorder := send("/public", "10.0.0.1:1002", "")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "60", recorder.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusOK, send("/public", "10.0.0.2:1000", "").Code)
+
+	// Authenticated callers are limited per user, wherever they call from.
+	username := util.RandomOwner()
+	require.Equal(t, http.StatusOK, send("/private", "10.0.0.3:1000", username).Code)
+	require.Equal(t, http.StatusOK, send("/private", "10.0.0.4:1000", username).Code)
+	require.Equal(t, http.StatusTooManyRequests, send("/private", "10.0.0.5:1000", username).Code)
+	require.Equal(t, http.StatusOK, send("/private", "10.0.0.3:1000", util.RandomOwner()).Code)
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.limiter = ratelimit.New(ratelimit.NewMemoryBackend(), map[string]ratelimit.Limit{
+		ratelimit.DefaultRule: {Rate: 1.0 / 60, Burst: 1},
+	})
+	server.router.GET("/public", server.rateLimit("public"), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+
+	send := func(remoteAddr string, forwardedFor string) int {
+		req, err := http.NewRequest(http.MethodGet, "/public", nil)
+		require.NoError(t, err)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Without trusted proxies, a new X-Forwarded-For on every request doesn't get a new bucket.
+	require.Equal(t, http.StatusOK, send("203.0.113.1:1000", "198.51.100.1"))
+	require.Equal(t, http.StatusTooManyRequests, send("203.0.113.1:1000", "198.51.100.2"))
+
+	// Behind a trusted proxy, clients are told apart by the address it forwards.
+	require.NoError(t, server.router.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	require.Equal(t, http.StatusOK, send("10.0.0.1:1000", "198.51.100.3"))
+	require.Equal(t, http.StatusOK, send("10.0.0.1:1000", "198.51.100.4"))
+	require.Equal(t, http.StatusTooManyRequests, send("10.0.0.1:1000", "198.51.100.3"))
+}
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/recipient"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// payeeQuery addresses a user by username or email, to send them money in currency.
+type payeeQuery struct {
+	Username string `form:"username" binding:"omitempty,username"`
+	Email    string `form:"email" binding:"omitempty,email_address"`
+	Currency string `form:"currency" binding:"required,currency"`
+}
+
+type payeeLookupResponse struct {
+	// MaskedName lets the sender recognise the payee without learning their full name.
+	MaskedName string `json:"masked_name"`
+	Currency   string `json:"currency"`
+}
+
+// lookupPayee shows who a transfer addressed by username or email would go to, so the sender can
+// confirm it before sending. Neither the account nor the username is disclosed.
+func (server *Server) lookupPayee(ctx *gin.Context) {
+	var req payeeQuery
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	if (req.Username == "") == (req.Email == "") {
+		respondError(ctx, apperr.InvalidArgument(apperr.Violation("username", "exactly one of username and email is required")))
+		return
+	}
+
+	field := "username"
+	if req.Email != "" {
+		field = "email"
+	}
+	payee, valid := payeeAccount(ctx, server.store, field, req)
+	if !valid {
+		return
+	}
+	ctx.JSON(http.StatusOK, payeeLookupResponse{
+		MaskedName: maskName(payee.FullName),
+		Currency:   req.Currency,
+	})
+}
+
+// payeeAccount returns the account that receives the transfers sent to the user in the query:
+// their default account in the currency. field names the request field that addressed the user,
+// for violations.
+func payeeAccount(ctx *gin.Context, store db.Store, field string, query payeeQuery) (db.GetPayeeAccountRow, bool) {
+	payee, err := recipient.PayeeAccount(ctx, store, field, query.Username, query.Email, query.Currency)
+	if err != nil {
+		respondError(ctx, err)
+		return payee, false
+	}
+	return payee, true
+}
+
+// maskName keeps the first letter of each word of a name and hides the rest, without giving away
+// the length of the words: "Jane Smith" becomes "J*** S***".
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		first, _ := utf8.DecodeRuneInString(word)
+		words[i] = string(first) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+type payeeResponse struct {
+	ID    int64  `json:"id"`
+	Label string `json:"label"`
+	// Exactly one of account_id and username is set.
+	AccountID *int64    `json:"account_id"`
+	Username  *string   `json:"username"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newPayeeResponse(payee db.Payee) payeeResponse {
+	rsp := payeeResponse{
+		ID:        payee.ID,
+		Label:     payee.Label,
+		Currency:  payee.Currency,
+		CreatedAt: payee.CreatedAt,
+	}
+	if payee.AccountID.Valid {
+		rsp.AccountID = &payee.AccountID.Int64
+	}
+	if payee.Username.Valid {
+		rsp.Username = &payee.Username.String
+	}
+	return rsp
+}
+
+type createPayeeRequest struct {
+	Label string `json:"label" binding:"required,payee_label"`
+	// Exactly one of account_id and username. A username pays the user's default account in the
+	// currency of the transfer.
+	AccountID int64  `json:"account_id" binding:"omitempty,min=1"`
+	Username  string `json:"username" binding:"omitempty,username"`
+	// The currency of transfers that don't name one. Required with a username; an account's payee
+	// uses the account's currency.
+	Currency string `json:"currency" binding:"omitempty,currency"`
+}
+
+// createPayee saves a payee in the caller's address book, so that transfers can name it by ID.
+func (server *Server) createPayee(ctx *gin.Context) {
+	var req createPayeeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	if (req.AccountID == 0) == (req.Username == "") {
+		respondError(ctx, apperr.InvalidArgument(apperr.Violation("account_id", "exactly one of account_id and username is required")))
+		return
+	}
+	currency, err := recipient.PayeeCurrency(ctx, server.store, req.AccountID, req.Username, req.Currency)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	payee, err := server.store.CreatePayee(ctx, db.CreatePayeeParams{
+		Owner:     payload.Username,
+		Label:     req.Label,
+		AccountID: sql.NullInt64{Int64: req.AccountID, Valid: req.AccountID != 0},
+		Username:  sql.NullString{String: req.Username, Valid: req.Username != ""},
+		Currency:  currency,
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "payee"))
+		return
+	}
+	rsp := newPayeeResponse(payee)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionPayeeCreate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourcePayee,
+		ResourceID:   strconv.FormatInt(payee.ID, 10),
+		Client:       auditClient(ctx),
+		After:        rsp,
+	})
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type payeeURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// ownedPayee returns the payee with the ID in the URI from the caller's address book. Other
+// users' payees are not found.
+func (server *Server) ownedPayee(ctx *gin.Context) (db.Payee, bool) {
+	var uri payeeURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, bindError(err))
+		return db.Payee{}, false
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	payee, err := server.store.GetPayee(ctx, db.GetPayeeParams{
+		ID:    uri.ID,
+		Owner: payload.Username,
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "payee"))
+		return db.Payee{}, false
+	}
+	return payee, true
+}
+
+type listPayeesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// listPayees lists the caller's address book by label.
+func (server *Server) listPayees(ctx *gin.Context) {
+	var req listPayeesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	payees, err := server.store.ListPayees(ctx, db.ListPayeesParams{
+		Owner:  payload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	rsp := make([]payeeResponse, 0, len(payees))
+	for _, payee := range payees {
+		rsp = append(rsp, newPayeeResponse(payee))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type updatePayeeRequest struct {
+	// Fields left out are unchanged. A payee's target can't change; save another payee instead.
+	Label    string `json:"label" binding:"omitempty,payee_label"`
+	Currency string `json:"currency" binding:"omitempty,currency"`
+}
+
+// updatePayee renames a payee or changes the currency transfers to it default to.
+func (server *Server) updatePayee(ctx *gin.Context) {
+	payee, ok := server.ownedPayee(ctx)
+	if !ok {
+		return
+	}
+	var req updatePayeeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	if req.Currency != "" && req.Currency != payee.Currency {
+		if _, err := recipient.PayeeCurrency(ctx, server.store, payee.AccountID.Int64, payee.Username.String, req.Currency); err != nil {
+			respondError(ctx, err)
+			return
+		}
+	}
+
+	updated, err := server.store.UpdatePayee(ctx, db.UpdatePayeeParams{
+		ID:       payee.ID,
+		Owner:    payee.Owner,
+		Label:    sql.NullString{String: req.Label, Valid: req.Label != ""},
+		Currency: sql.NullString{String: req.Currency, Valid: req.Currency != ""},
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "payee"))
+		return
+	}
+	rsp := newPayeeResponse(updated)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payee.Owner,
+		Action:       audit.ActionPayeeUpdate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourcePayee,
+		ResourceID:   strconv.FormatInt(payee.ID, 10),
+		Client:       auditClient(ctx),
+		Before:       newPayeeResponse(payee),
+		After:        rsp,
+	})
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// deletePayee removes a payee from the caller's address book. Transfers already made to it are
+// not affected.
+func (server *Server) deletePayee(ctx *gin.Context) {
+	payee, ok := server.ownedPayee(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := server.store.DeletePayee(ctx, db.DeletePayeeParams{
+		ID:    payee.ID,
+		Owner: payee.Owner,
+	})
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "payee"))
+		return
+	}
+	if rows == 0 {
+		respondError(ctx, apperr.New(apperr.CodeNotFound, "payee not found"))
+		return
+	}
+	rsp := newPayeeResponse(payee)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payee.Owner,
+		Action:       audit.ActionPayeeDelete,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourcePayee,
+		ResourceID:   strconv.FormatInt(payee.ID, 10),
+		Client:       auditClient(ctx),
+		Before:       rsp,
+	})
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,398 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupPayeeAPI(t *testing.T) {
+	payee := db.GetPayeeAccountRow{ID: 42, Owner: util.RandomOwner(), Status: db.AccountActive, FullName: "Jane van Smith"}
+	email := util.RandomEmail()
+
+	testCases := []struct {
+		name        string
+		query       url.Values
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "ByUsername",
+			query: url.Values{"username": {payee.Owner}, "currency": {util.USD}},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Eq(db.GetPayeeAccountParams{
+					Username: sql.NullString{String: payee.Owner, Valid: true},
+					Currency: util.USD,
+				})).Return(payee, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got payeeLookupResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, payeeLookupResponse{MaskedName: "J*** v*** S***", Currency: util.USD}, got)
+				// Neither the account nor the username is given away.
+				require.NotContains(t, resp.Body.String(), payee.Owner)
+				require.NotContains(t, resp.Body.String(), "42")
+			},
+		},
+		{
+			name:  "ByEmail",
+			query: url.Values{"email": {email}, "currency": {util.USD}},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Eq(db.GetPayeeAccountParams{
+					Email:    sql.NullString{String: email, Valid: true},
+					Currency: util.USD,
+				})).Return(payee, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name:  "NotFound",
+			query: url.Values{"email": {email}, "currency": {util.USD}},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Any()).Return(db.GetPayeeAccountRow{}, sql.ErrNoRows).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "has no USD account")
+			},
+		},
+		{
+			name:  "ClosedAccount",
+			query: url.Values{"username": {payee.Owner}, "currency": {util.USD}},
+			buildStore: func(store *mockdb.MockStore) {
+				closed := payee
+				closed.Status = db.AccountClosed
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Any()).Return(closed, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "has no USD account")
+			},
+		},
+		{
+			name:  "UsernameAndEmail",
+			query: url.Values{"username": {payee.Owner}, "email": {email}, "currency": {util.USD}},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:  "NoCurrency",
+			query: url.Values{"username": {payee.Owner}},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "currency")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			req := httptest.NewRequest(http.MethodGet, "/payees/lookup?"+tc.query.Encode(), nil)
+			addAuthHeader(t, req, server.maker, util.RandomOwner(), time.Minute)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestMaskName(t *testing.T) {
+	require.Equal(t, "J*** S***", maskName("Jane Smith"))
+	require.Equal(t, "J*** S***", maskName("  Jo   Smithsonian "))
+	require.Equal(t, "É***", maskName("Élodie"))
+	require.Equal(t, "", maskName(""))
+}
+
+func randomPayee(owner string, account db.Account) db.Payee {
+	return db.Payee{
+		ID:        util.RandomInt(1, 1000),
+		Owner:     owner,
+		Label:     util.RandomString(8),
+		AccountID: sql.NullInt64{Int64: account.ID, Valid: true},
+		Currency:  account.Currency,
+	}
+}
+
+func TestCreatePayeeAPI(t *testing.T) {
+	username := util.RandomOwner()
+	account := randomAccount(util.RandomOwner())
+	recipient := db.GetPayeeAccountRow{ID: account.ID + 1, Owner: util.RandomOwner(), Status: db.AccountActive}
+
+	testCases := []struct {
+		name        string
+		body        gin.H
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ByAccount",
+			body: gin.H{"label": "Landlord", "account_id": account.ID},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Eq(db.CreatePayeeParams{
+					Owner:     username,
+					Label:     "Landlord",
+					AccountID: sql.NullInt64{Int64: account.ID, Valid: true},
+					Currency:  account.Currency,
+				})).Return(db.Payee{ID: 1, Owner: username, Label: "Landlord", AccountID: sql.NullInt64{Int64: account.ID, Valid: true}, Currency: account.Currency}, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionPayeeCreate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got payeeResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, account.ID, *got.AccountID)
+				require.Nil(t, got.Username)
+				require.Equal(t, account.Currency, got.Currency)
+			},
+		},
+		{
+			name: "ByUsername",
+			body: gin.H{"label": "Mom", "username": recipient.Owner, "currency": util.EUR},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Eq(db.GetPayeeAccountParams{
+					Username: sql.NullString{String: recipient.Owner, Valid: true},
+					Currency: util.EUR,
+				})).Return(recipient, nil).Times(1)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.CreatePayeeParams) (db.Payee, error) {
+						require.False(t, arg.AccountID.Valid)
+						require.Equal(t, recipient.Owner, arg.Username.String)
+						return db.Payee{ID: 2, Owner: username, Label: arg.Label, Username: arg.Username, Currency: arg.Currency}, nil
+					}).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionPayeeCreate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				require.Contains(t, resp.Body.String(), `"account_id":null`)
+			},
+		},
+		{
+			name: "UsernameWithoutCurrency",
+			body: gin.H{"label": "Mom", "username": recipient.Owner},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "currency")
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{"label": "Landlord", "account_id": account.ID, "currency": otherCurrency(account.Currency)},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name: "NoTarget",
+			body: gin.H{"label": "Nobody"},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "account_id")
+			},
+		},
+		{
+			name: "DuplicateLabel",
+			body: gin.H{"label": "Landlord", "account_id": account.ID},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().CreatePayee(gomock.Any(), gomock.Any()).Return(db.Payee{}, &pq.Error{Code: "23505"}).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/payees", bytes.NewReader(data))
+			addAuthHeader(t, req, server.maker, username, time.Minute)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestUpdatePayeeAPI(t *testing.T) {
+	username := util.RandomOwner()
+	payee := randomPayee(username, randomAccount(util.RandomOwner()))
+
+	testCases := []struct {
+		name        string
+		body        gin.H
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Rename",
+			body: gin.H{"label": "Old landlord"},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(db.GetPayeeParams{ID: payee.ID, Owner: username})).Return(payee, nil).Times(1)
+				renamed := payee
+				renamed.Label = "Old landlord"
+				store.EXPECT().UpdatePayee(gomock.Any(), gomock.Eq(db.UpdatePayeeParams{
+					ID:    payee.ID,
+					Owner: username,
+					Label: sql.NullString{String: "Old landlord", Valid: true},
+				})).Return(renamed, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionPayeeUpdate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				require.Contains(t, resp.Body.String(), "Old landlord")
+			},
+		},
+		{
+			name: "CurrencyOfAccount",
+			body: gin.H{"currency": otherCurrency(payee.Currency)},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Any()).Return(payee, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payee.AccountID.Int64)).
+					Return(db.Account{ID: payee.AccountID.Int64, Currency: payee.Currency, Status: db.AccountActive}, nil).Times(1)
+				store.EXPECT().UpdatePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "can receive "+otherCurrency(payee.Currency)+" transfers")
+			},
+		},
+		{
+			name: "SomeoneElses",
+			body: gin.H{"label": "Mine now"},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Any()).Return(db.Payee{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().UpdatePayee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/payees/%d", payee.ID), bytes.NewReader(data))
+			addAuthHeader(t, req, server.maker, username, time.Minute)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestDeletePayeeAPI(t *testing.T) {
+	username := util.RandomOwner()
+	payee := randomPayee(username, randomAccount(util.RandomOwner()))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(db.GetPayeeParams{ID: payee.ID, Owner: username})).Return(payee, nil).Times(1)
+	store.EXPECT().DeletePayee(gomock.Any(), gomock.Eq(db.DeletePayeeParams{ID: payee.ID, Owner: username})).Return(int64(1), nil).Times(1)
+	store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionPayeeDelete, audit.OutcomeSuccess)).Times(1)
+	server := newTestServer(t, store)
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/payees/%d", payee.ID), nil)
+	addAuthHeader(t, req, server.maker, username, time.Minute)
+	resp := httptest.NewRecorder()
+	server.router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestListPayeesAPI(t *testing.T) {
+	username := util.RandomOwner()
+	payees := []db.Payee{
+		randomPayee(username, randomAccount(util.RandomOwner())),
+		randomPayee(username, randomAccount(util.RandomOwner())),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListPayees(gomock.Any(), gomock.Eq(db.ListPayeesParams{Owner: username, Limit: 5, Offset: 5})).Return(payees, nil).Times(1)
+	server := newTestServer(t, store)
+
+	req := httptest.NewRequest(http.MethodGet, "/payees?page_id=2&page_size=5", nil)
+	addAuthHeader(t, req, server.maker, username, time.Minute)
+	resp := httptest.NewRecorder()
+	server.router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var got []payeeResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+	require.Len(t, got, 2)
+	require.Equal(t, payees[0].Label, got[0].Label)
+}
+
+// otherCurrency returns a supported currency other than currency.
+func otherCurrency(currency string) string {
+	for _, c := range util.SupportedCurrencies {
+		if c != currency {
+			return c
+		}
+	}
+	return ""
+}
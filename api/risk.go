@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/risk"
+	"github.com/gin-gonic/gin"
+)
+
+// reviewTransfer parks a transfer the risk rules flagged until a banker looks at it, holding its
+// amount on the sender's account, and answers 202 Accepted. The sender is not told which rules
+// matched.
+func (server *Server) reviewTransfer(ctx *gin.Context, event audit.Event, input db.TransferTxParams, currency string, assessment risk.Assessment) {
+	findings, err := json.Marshal(assessment.Findings)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	input.Review = &db.CreateTransferReviewParams{
+		RequestedBy: event.Actor,
+		ClientIp:    event.Client.IP,
+		Findings:    findings,
+		ExpiresAt:   time.Now().Add(server.config.TransferReviewTTL),
+	}
+	// The hold is recorded in the audit log in the same transaction, with the findings.
+	event.Action = audit.ActionTransferHold
+	event.Outcome = audit.OutcomeSuccess
+	event.After = assessment
+	auditEntry, err := event.Params()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	input.Audit = &auditEntry
+
+	results, err := server.store.TransferTx(ctx, &input)
+	if err != nil {
+		respondError(ctx, accountError(err))
+		return
+	}
+	ctx.JSON(http.StatusAccepted, newTransferReviewResponse(*results.Review, currency))
+}
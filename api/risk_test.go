@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/risk"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferScreeningAPI(t *testing.T) {
+	amount := int64(10)
+	username := util.RandomOwner()
+	account1 := randomAccount(username)
+	account2 := randomAccount(util.RandomOwner())
+	account2.ID = account1.ID + 1
+	account2.Currency = account1.Currency
+
+	body := gin.H{
+		"from_account_id": account1.ID,
+		"to_account_id":   account2.ID,
+		"amount":          amount,
+		"currency":        account1.Currency,
+	}
+	rules := []risk.Rule{
+		{Check: risk.NewPayee{Amount: amount}, Verdict: risk.Review},
+		{Check: risk.Velocity{Count: 3, Window: time.Minute}, Verdict: risk.Deny},
+	}
+
+	testCases := []struct {
+		name        string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Allow",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().CountUserTransfersTo(gomock.Any(), gomock.Eq(db.CountUserTransfersToParams{
+					Owner:     username,
+					ToAccount: account2.ID,
+				})).Return(int64(1), nil).Times(1)
+				store.EXPECT().CountUserTransfersSince(gomock.Any(), gomock.Any()).Return(int64(0), nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg *db.TransferTxParams) (db.TransferTxResults, error) {
+						require.Nil(t, arg.Review)
+						return db.TransferTxResults{ToAccount: account2}, nil
+					}).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name: "Review",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().CountUserTransfersTo(gomock.Any(), gomock.Any()).Return(int64(0), nil).Times(1)
+				store.EXPECT().CountUserTransfersSince(gomock.Any(), gomock.Any()).Return(int64(0), nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg *db.TransferTxParams) (db.TransferTxResults, error) {
+						require.Equal(t, account1.ID, arg.FromAccountID)
+						require.Equal(t, account2.ID, arg.ToAccountID)
+						require.Equal(t, amount, arg.Amount)
+						require.NotNil(t, arg.Review)
+						require.Equal(t, username, arg.Review.RequestedBy)
+						require.Equal(t, audit.ActionTransferHold, arg.Audit.Action)
+						var findings []risk.Finding
+						require.NoError(t, json.Unmarshal(arg.Review.Findings, &findings))
+						require.Len(t, findings, 1)
+						require.Equal(t, "new_payee", findings[0].Rule)
+						return db.TransferTxResults{
+							Transfer: db.Transfer{ID: 7, FromAccount: arg.FromAccountID, ToAccount: arg.ToAccountID, Amount: arg.Amount, Status: db.TransferPending},
+							Review: &db.TransferReview{
+								ID:          1,
+								TransferID:  7,
+								FromAccount: arg.FromAccountID,
+								ToAccount:   arg.ToAccountID,
+								Amount:      arg.Amount,
+								RequestedBy: arg.Review.RequestedBy,
+								Findings:    arg.Review.Findings,
+								Status:      db.ReviewPending,
+							},
+						}, nil
+					}).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, resp.Code)
+				var got transferReviewResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, db.ReviewPending, got.Status)
+				require.Equal(t, int64(7), got.TransferID)
+				require.Equal(t, account1.Currency, got.Currency)
+				require.NotContains(t, resp.Body.String(), "new_payee")
+			},
+		},
+		{
+			name: "Deny",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().CountUserTransfersTo(gomock.Any(), gomock.Any()).Return(int64(0), nil).Times(1)
+				store.EXPECT().CountUserTransfersSince(gomock.Any(), gomock.Any()).Return(int64(3), nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionTransferCreate, audit.OutcomeFailure)).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+				require.NotContains(t, resp.Body.String(), "velocity")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Return(account2, nil).Times(1)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+			server.screener = risk.New(rules...)
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			addAuthHeader(t, req, server.maker, username, time.Minute)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
@@ -5,56 +5,132 @@ import (
 
 	db "github.com/ashokmouli/simplebank/db/sqlc"
 	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/metrics"
+	"github.com/ashokmouli/simplebank/password"
+	"github.com/ashokmouli/simplebank/ratelimit"
+	"github.com/ashokmouli/simplebank/risk"
 	"github.com/ashokmouli/simplebank/token"
+	"github.com/ashokmouli/simplebank/txlimit"
 	"github.com/gin-gonic/gin"
 )
 
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
-}
-
 type Server struct {
-	store  db.Store
-	router *gin.Engine
-	maker  token.Maker
-	config util.Config
+	store          db.Store
+	router         *gin.Engine
+	maker          token.Maker
+	config         util.Config
+	limiter        *ratelimit.Limiter
+	passwords      *password.Hashing
+	passwordPolicy *password.Policy
+	// transferLimits are the default limits of every user, by currency.
+	transferLimits map[string]txlimit.Limits
+	// screener runs the risk rules over transfers before they post.
+	screener *risk.Screener
 }
 
 func NewServer(store db.Store, config util.Config) (*Server, error) {
 
 	// Create a token maker
-	maker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	maker, err := token.NewMaker(config.TokenMaker, config.TokenSymmetricKey, config.TokenIssuer, config.TokenAudience)
 	if err != nil {
 		err := fmt.Errorf("could not create a new token interface, %w", err)
 		return nil, err
 	}
 
+	limits, err := ratelimit.ParseLimits(config.RateLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	passwords, err := password.NewHashing(config.PasswordHasher)
+	if err != nil {
+		return nil, err
+	}
+	passwordPolicy, err := password.NewPolicy(config.PasswordMinLength, config.PasswordMinClasses, config.PasswordCommonList)
+	if err != nil {
+		return nil, err
+	}
+
+	transferLimits, err := txlimit.Parse(config.TransferLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	riskRules, err := risk.Parse(config.RiskRules)
+	if err != nil {
+		return nil, err
+	}
+
 	server := &Server{
-		store:  store,
-		config: config,
-		maker:  maker,
+		store:          store,
+		config:         config,
+		maker:          maker,
+		limiter:        ratelimit.New(ratelimit.NewMemoryBackend(), limits),
+		passwords:      passwords,
+		passwordPolicy: passwordPolicy,
+		transferLimits: transferLimits,
+		screener:       risk.New(riskRules...),
 	}
 
+	registerValidators()
+
 	// Create Routes
 	server.CreateRoutes()
 
+	// ClientIP only believes X-Forwarded-For from the proxies in TRUSTED_PROXIES, so that clients
+	// can't pick the address they are rate limited and audited by.
+	if err := server.router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
 	return server, nil
 }
 func (server *Server) CreateRoutes() {
 	router := gin.Default()
 
-	router.POST("/users", server.createUser)      // Create user
-	router.POST("/users/login", server.loginUser) // Login as a user
-	router.POST("/tokens/renew_token", server.renewToken)
-
-	authGroups := router.Group("/").Use(createAuthMiddleware(server.maker))
-
-	authGroups.POST("/accounts", server.createAccount) // Create an account
-	authGroups.GET("/accounts/:id", server.getAccount) // Get the account with ID equals id.
-	authGroups.GET("/accounts", server.listAccount)    // List accounts
-
-	authGroups.POST("/transfers", server.transfer)     // Perfomr account transfer
-	authGroups.GET("/users/:username", server.getUser) // Get user info
+	// Public routes are rate limited per IP address, authenticated ones per user.
+	router.POST("/users", server.rateLimit("create_user"), server.createUser)     // Create user
+	router.POST("/users/login", server.rateLimit("login_user"), server.loginUser) // Login as a user
+	router.POST("/tokens/renew_token", server.rateLimit("renew_token"), server.renewToken)
+	router.POST("/tokens/revoke_token", server.rateLimit("revoke_token"), server.revokeToken) // Log out: block the session of a refresh token
+	router.GET("/metrics", gin.WrapH(metrics.Handler())) // Prometheus metrics
+
+	authGroups := router.Group("/").Use(createAuthMiddleware(server.maker, server.store))
+
+	// Every authenticated route declares the scope the caller's token or API key must hold.
+	authGroups.POST("/accounts", requireScope(token.ScopeAccountsWrite), server.rateLimit("create_account"), server.createAccount) // Create an account
+	authGroups.GET("/accounts/:id", requireScope(token.ScopeAccountsRead), server.rateLimit("get_account"), server.getAccount)     // Get the account with ID equals id.
+	authGroups.GET("/accounts", requireScope(token.ScopeAccountsRead), server.rateLimit("list_accounts"), server.listAccount)      // List accounts
+	authGroups.PATCH("/accounts/:id", requireScope(token.ScopeAccountsWrite), server.rateLimit("update_account"), server.updateAccount)                 // Rename an account
+	authGroups.POST("/accounts/:id/default", requireScope(token.ScopeAccountsWrite), server.rateLimit("set_default_account"), server.setDefaultAccount) // Receive transfers by username on this account
+
+	authGroups.POST("/transfers", requireScope(token.ScopeTransfersWrite), server.rateLimit("transfer"), server.transfer) // Perfomr account transfer
+	authGroups.GET("/payees/lookup", requireScope(token.ScopeTransfersWrite), server.rateLimit("lookup_payee"), server.lookupPayee) // Confirm who a transfer by username or email goes to
+	authGroups.POST("/payees", requireScope(token.ScopeTransfersWrite), server.rateLimit("create_payee"), server.createPayee)          // Save a payee
+	authGroups.GET("/payees", requireScope(token.ScopeTransfersWrite), server.rateLimit("list_payees"), server.listPayees)             // List saved payees
+	authGroups.PATCH("/payees/:id", requireScope(token.ScopeTransfersWrite), server.rateLimit("update_payee"), server.updatePayee)     // Rename a payee or change its currency
+	authGroups.DELETE("/payees/:id", requireScope(token.ScopeTransfersWrite), server.rateLimit("delete_payee"), server.deletePayee)    // Remove a payee
+	authGroups.GET("/notifications", requireScope(token.ScopeAccountsRead), server.rateLimit("list_notifications"), server.listNotifications) // What became of the user's held transfers
+	authGroups.GET("/users/:username", requireScope(token.ScopeUsersRead), server.rateLimit("get_user"), server.getUser)  // Get user info
+
+	authGroups.POST("/api_keys", requireScope(token.ScopeAPIKeysManage), server.rateLimit("create_api_key"), server.createAPIKey)       // Create an API key for machine clients
+	authGroups.GET("/api_keys", requireScope(token.ScopeAPIKeysManage), server.rateLimit("list_api_keys"), server.listAPIKeys)          // List the user's API keys
+	authGroups.DELETE("/api_keys/:id", requireScope(token.ScopeAPIKeysManage), server.rateLimit("revoke_api_key"), server.revokeAPIKey) // Revoke an API key
+
+	authGroups.GET("/audit_log", requireScope(token.ScopeAuditRead), server.rateLimit("list_audit_log"), server.listAuditLog) // Search the audit log, for bankers
+	authGroups.POST("/accounts/:id/status", requireScope(token.ScopeAccountsAdmin), server.rateLimit("set_account_status"), server.setAccountStatus) // Freeze, unfreeze, close or reopen an account, for bankers
+	authGroups.GET("/accounts/:id/ledger/verify", requireScope(token.ScopeAuditRead), server.rateLimit("verify_ledger"), server.verifyLedger) // Check the hash chain of an account's entries
+	authGroups.GET("/accounts/:id/limits", requireScope(token.ScopeAccountsRead), server.rateLimit("get_limits"), server.getLimits)                  // What can still leave an account
+	authGroups.PUT("/accounts/:id/limits", requireScope(token.ScopeAccountsAdmin), server.rateLimit("set_limits"), server.setAccountLimits)          // Limit an account, for bankers
+	authGroups.PUT("/users/:username/limits", requireScope(token.ScopeAccountsAdmin), server.rateLimit("set_limits"), server.setUserLimits)         // Override a user's default limits, for bankers
+	authGroups.GET("/transfer_reviews", requireScope(token.ScopeTransfersApprove), server.rateLimit("list_transfer_reviews"), server.listTransferReviews)                 // The queue of held transfers, for bankers
+	authGroups.POST("/transfer_reviews/:id/decision", requireScope(token.ScopeTransfersApprove), server.rateLimit("decide_transfer_review"), server.decideTransferReview) // Approve or reject a held transfer, for bankers
+	authGroups.GET("/interest_rates", requireScope(token.ScopeAccountsRead), server.rateLimit("list_interest_rates"), server.listInterestRates)                           // What each type of account earns
+	authGroups.PUT("/interest_rates/:account_type", requireScope(token.ScopeAccountsAdmin), server.rateLimit("set_interest_rate"), server.setInterestRate)                // Change the rate of a type of account, for bankers
+	authGroups.GET("/interest_report", requireScope(token.ScopeAccountsAdmin), server.rateLimit("interest_report"), server.getInterestReport)                             // Interest accrued vs posted over a month, for bankers
+	authGroups.GET("/fees", requireScope(token.ScopeAccountsRead), server.rateLimit("list_fees"), server.listFees)                                                        // What transfers cost
+	authGroups.PUT("/fees/:currency/:transfer_type", requireScope(token.ScopeAccountsAdmin), server.rateLimit("set_fee"), server.setFee)                                  // Charge a type of transfer, for bankers
+	authGroups.DELETE("/fees/:currency/:transfer_type", requireScope(token.ScopeAccountsAdmin), server.rateLimit("delete_fee"), server.deleteFee)                         // Make a type of transfer free, for bankers
 
 	server.router = router
 
@@ -1,11 +1,12 @@
 package api
 
 import (
-	"database/sql"
-	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	"github.com/ashokmouli/simplebank/token"
 	"github.com/gin-gonic/gin"
 )
 
@@ -23,63 +24,109 @@ func (server *Server) renewToken(ctx *gin.Context) {
 	// Unmarshal the request
 	var req RenewTokenRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, bindError(err))
 		return
 	}
 
-	// Validate the token
-	payload, err := server.maker.VerifyToken(req.RefreshToken)
+	payload, err := server.checkRefreshToken(ctx, req.RefreshToken, audit.ActionSessionRenew)
 	if err != nil {
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		respondError(ctx, err)
 		return
 	}
 
-	// Fetch the session object from DB
-	session, err := server.store.GetSession(ctx, payload.ID)
+	// Create access token.
+	accessToken, accessPayload, err := server.maker.CreateToken(payload.Username, payload.Scopes, token.AccessToken, server.config.AccessTokenDuration)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, err)
 		return
 	}
+	server.auditSession(ctx, payload, audit.ActionSessionRenew, audit.OutcomeSuccess)
 
-	// Validate this token is not blocked
-	if session.IsBlocked {
-		err := fmt.Errorf("refresh token blocked")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	// Marshal back the response.
+	var resp RenewTokenResponse
+	resp.AccessToken = accessToken
+	resp.AccessTokenExpiresAt = accessPayload.ExpiredAt
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type RevokeTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type RevokeTokenResponse struct {
+	SessionID string `json:"session_id"`
+}
+
+// revokeToken logs out: the session of the refresh token is blocked, so it can't renew access
+// tokens any more. Access tokens already issued last until they expire.
+func (server *Server) revokeToken(ctx *gin.Context) {
+	var req RevokeTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
 		return
 	}
 
-	// Compare user names
-	if payload.Username != session.Username {
-		err := fmt.Errorf("mismatched user names")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	payload, err := server.checkRefreshToken(ctx, req.RefreshToken, audit.ActionSessionRevoke)
+	if err != nil {
+		respondError(ctx, err)
+		return
 	}
-
-	// Compare the refresh token passed in with the one in DB.
-	if req.RefreshToken != session.RefreshToken {
-		err := fmt.Errorf("session refresh token doesn't match incoming refresh token")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	session, err := server.store.BlockSession(ctx, payload.ID)
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "session"))
 		return
 	}
+	server.auditSession(ctx, payload, audit.ActionSessionRevoke, audit.OutcomeSuccess)
 
-	// Verify that the refresh token has not expired (shouldn't but just check)
-	if time.Now().After(session.ExpiresAt) {
-		err := fmt.Errorf("refresh token expired")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	ctx.JSON(http.StatusOK, RevokeTokenResponse{SessionID: session.ID.String()})
+}
+
+// checkRefreshToken returns the payload of a refresh token whose session can still be used.
+// Refusals of a genuine token are audited as failures of action.
+func (server *Server) checkRefreshToken(ctx *gin.Context, refreshToken string, action string) (*token.Payload, error) {
+	// Validate the token
+	payload, err := server.maker.VerifyToken(refreshToken, token.RefreshToken)
+	if err != nil {
+		return nil, apperr.Wrap(err, apperr.CodePermissionDenied, err.Error())
 	}
-	// Create access token.
-	accessToken, accessPayload, err := server.maker.CreateToken(payload.Username, server.config.AccessTokenDuration)
+
+	// Fetch the session object from DB
+	session, err := server.store.GetSession(ctx, payload.ID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, apperr.FromDB(err, "session")
 	}
 
-	// Marshal back the response.
-	var resp RenewTokenResponse
-	resp.AccessToken = accessToken
-	resp.AccessTokenExpiresAt = accessPayload.ExpiredAt
+	var refusal string
+	switch {
+	// Validate this token is not blocked
+	case session.IsBlocked:
+		refusal = "refresh token blocked"
+	// Compare user names
+	case payload.Username != session.Username:
+		refusal = "mismatched user names"
+	// Compare the refresh token passed in with the one in DB.
+	case refreshToken != session.RefreshToken:
+		refusal = "session refresh token doesn't match incoming refresh token"
+	// Verify that the refresh token has not expired (shouldn't but just check)
+	case time.Now().After(session.ExpiresAt):
+		refusal = "refresh token expired"
+	}
+	if refusal != "" {
+		server.auditSession(ctx, payload, action, audit.OutcomeFailure)
+		return nil, apperr.New(apperr.CodePermissionDenied, refusal)
+	}
+	return payload, nil
+}
 
-	ctx.JSON(http.StatusOK, resp)
+// auditSession records what happened to the session of a refresh token.
+func (server *Server) auditSession(ctx *gin.Context, payload *token.Payload, action string, outcome string) {
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       action,
+		Outcome:      outcome,
+		ResourceType: audit.ResourceSession,
+		ResourceID:   payload.ID.String(),
+		Client:       auditClient(ctx),
+	})
 }
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ashokmouli/simplebank/audit"
 	mockdb "github.com/ashokmouli/simplebank/db/mock"
 	db "github.com/ashokmouli/simplebank/db/sqlc"
 	"github.com/ashokmouli/simplebank/db/util"
@@ -31,7 +32,7 @@ func TestRenewTokenAPI(t *testing.T) {
 			name: "ok",
 			
 			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
-				token, payload, err := tokenMaker.CreateToken(username, time.Hour)
+				token, payload, err := tokenMaker.CreateToken(username, token.UserScopes, token.RefreshToken, time.Hour)
 				require.NoError(t, err)
 				require.NotEmpty(t, payload)
 				return token, payload
@@ -48,6 +49,7 @@ func TestRenewTokenAPI(t *testing.T) {
 					CreatedAt: time.Now(),
 				}
 				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Return(session, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionSessionRenew, audit.OutcomeSuccess)).Times(1)
 			},
 			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder, maker token.Maker, payload *token.Payload) {
 				require.Equal(t, http.StatusOK, resp.Code)
@@ -57,16 +59,18 @@ func TestRenewTokenAPI(t *testing.T) {
 				var got RenewTokenResponse
 				err = json.Unmarshal(data, &got)
 				require.NoError(t, err)
-				gotPayload, err := maker.VerifyToken(got.AccessToken)
+				gotPayload, err := maker.VerifyToken(got.AccessToken, token.AccessToken)
 				require.NoError(t, err)
 				require.Equal(t, gotPayload.Username, payload.Username)
+				// The access token keeps the scopes granted at login.
+				require.Equal(t, gotPayload.Scopes, payload.Scopes)
 			},
 		},
 		{
 			name: "Forbidden",
 			
 			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
-				token, payload, err := tokenMaker.CreateToken(username, time.Hour)
+				token, payload, err := tokenMaker.CreateToken(username, token.UserScopes, token.RefreshToken, time.Hour)
 				require.NoError(t, err)
 				require.NotEmpty(t, payload)
 				return token, payload
@@ -76,7 +80,7 @@ func TestRenewTokenAPI(t *testing.T) {
 
 				// Create a different session object and return it to the handler to test error conditions.
 				username := util.RandomOwner()
-				token2, payload2, err := maker.CreateToken(username, time.Hour)
+				token2, payload2, err := maker.CreateToken(username, token.UserScopes, token.RefreshToken, time.Hour)
 				require.NoError(t, err)
 				require.NotEmpty(t, payload2)
 
@@ -89,6 +93,7 @@ func TestRenewTokenAPI(t *testing.T) {
 					CreatedAt: time.Now(),
 				}
 				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Return(session, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionSessionRenew, audit.OutcomeFailure)).Times(1)
 			},
 			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder, maker token.Maker, payload *token.Payload) {
 				require.Equal(t, http.StatusForbidden, resp.Code)
@@ -98,7 +103,7 @@ func TestRenewTokenAPI(t *testing.T) {
 			name: "Forbidden -- Blocked",
 			
 			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
-				token, payload, err := tokenMaker.CreateToken(username, time.Hour)
+				token, payload, err := tokenMaker.CreateToken(username, token.UserScopes, token.RefreshToken, time.Hour)
 				require.NoError(t, err)
 				require.NotEmpty(t, payload)
 				return token, payload
@@ -108,7 +113,7 @@ func TestRenewTokenAPI(t *testing.T) {
 
 				// Create a different session object and return it to the handler to test error conditions.
 				username := util.RandomOwner()
-				token2, payload2, err := maker.CreateToken(username, time.Hour)
+				token2, payload2, err := maker.CreateToken(username, token.UserScopes, token.RefreshToken, time.Hour)
 				require.NoError(t, err)
 				require.NotEmpty(t, payload2)
 
@@ -121,6 +126,25 @@ func TestRenewTokenAPI(t *testing.T) {
 					CreatedAt: time.Now(),
 				}
 				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Return(session, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionSessionRenew, audit.OutcomeFailure)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder, maker token.Maker, payload *token.Payload) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			name: "Forbidden -- Access token",
+
+			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				// An access token must not be accepted in place of a refresh token.
+				token, payload, err := tokenMaker.CreateToken(username, token.UserScopes, token.AccessToken, time.Hour)
+				require.NoError(t, err)
+				require.NotEmpty(t, payload)
+				return token, payload
+			},
+
+			buildStore: func(t *testing.T, store *mockdb.MockStore, maker token.Maker, token string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
 			},
 			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder, maker token.Maker, payload *token.Payload) {
 				require.Equal(t, http.StatusForbidden, resp.Code)
@@ -163,4 +187,74 @@ func TestRenewTokenAPI(t *testing.T) {
 		})
 	}
 
-}
\ No newline at end of file
+}
+
+func TestRevokeTokenAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name        string
+		buildStore  func(store *mockdb.MockStore, refreshToken string, payload *token.Payload)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder, payload *token.Payload)
+	}{
+		{
+			name: "OK",
+			buildStore: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := db.Session{
+					ID:           payload.ID,
+					Username:     username,
+					RefreshToken: refreshToken,
+					ExpiresAt:    payload.ExpiredAt,
+				}
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Return(session, nil).Times(1)
+				session.IsBlocked = true
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Eq(payload.ID)).Return(session, nil).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionSessionRevoke, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got RevokeTokenResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, payload.ID.String(), got.SessionID)
+			},
+		},
+		{
+			name: "AlreadyBlocked",
+			buildStore: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := db.Session{
+					ID:           payload.ID,
+					Username:     username,
+					IsBlocked:    true,
+					RefreshToken: refreshToken,
+					ExpiresAt:    payload.ExpiredAt,
+				}
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Return(session, nil).Times(1)
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionSessionRevoke, audit.OutcomeFailure)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			refreshToken, payload, err := server.maker.CreateToken(username, token.UserScopes, token.RefreshToken, time.Hour)
+			require.NoError(t, err)
+			tc.buildStore(store, refreshToken, payload)
+
+			data, err := json.Marshal(RevokeTokenRequest{RefreshToken: refreshToken})
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/tokens/revoke_token", bytes.NewReader(data))
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp, payload)
+		})
+	}
+}
@@ -1,79 +1,149 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
-	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
 	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/metrics"
+	"github.com/ashokmouli/simplebank/recipient"
+	"github.com/ashokmouli/simplebank/risk"
 	"github.com/ashokmouli/simplebank/token"
 	"github.com/gin-gonic/gin"
 )
 
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,oneof=USD EUR CAD"`
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	// Exactly one of to_account_id, to_username, to_email and payee_id. A username or email sends
+	// to the user's default account in the currency.
+	ToAccountID int64  `json:"to_account_id" binding:"omitempty,min=1"`
+	ToUsername  string `json:"to_username" binding:"omitempty,username"`
+	ToEmail     string `json:"to_email" binding:"omitempty,email_address"`
+	PayeeID     int64  `json:"payee_id" binding:"omitempty,min=1"`
+	Amount      int64  `json:"amount" binding:"required,amount"`
+	// Optional with a payee_id, which defaults to the payee's currency.
+	Currency string `json:"currency" binding:"omitempty,currency"`
 }
 
 func (server *Server) transfer(ctx *gin.Context) {
 	var req transferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, bindError(err))
 		return
 	}
-	account, valid := validateAccount(ctx, server.store, req.FromAccountID, req.Currency)
-	if !valid {
+	payload := (ctx.MustGet(authorizationPayloadKey)).(*token.Payload)
+
+	to := recipient.Request{
+		AccountID: req.ToAccountID,
+		Username:  req.ToUsername,
+		Email:     req.ToEmail,
+		PayeeID:   req.PayeeID,
+		Currency:  req.Currency,
+	}
+	if err := to.ExpandPayee(ctx, server.store, payload.Username); err != nil {
+		respondError(ctx, err)
 		return
 	}
+	req.Currency = to.Currency
 
-	_, valid = validateAccount(ctx, server.store, req.ToAccountID, req.Currency)
-	if !valid {
+	account, err := recipient.Account(ctx, server.store, "from_account_id", req.FromAccountID, req.Currency)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	payee, err := to.Resolve(ctx, server.store)
+	if err != nil {
+		respondError(ctx, err)
 		return
 	}
+	toAccountID, toOwner := payee.AccountID, payee.Owner
 
 	// Check that the from account is the authorized user.
-	payload := (ctx.MustGet(authorizationPayloadKey)).(*token.Payload)
+	event := audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionTransferCreate,
+		ResourceType: audit.ResourceTransfer,
+		Client:       auditClient(ctx),
+	}
 	if (account.Owner != payload.Username) {
-		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("account does not belong to logged in user")))
+		// Record the attempt against the account it tried to take money from.
+		event.Outcome = audit.OutcomeFailure
+		event.ResourceType = audit.ResourceAccount
+		event.ResourceID = strconv.FormatInt(req.FromAccountID, 10)
+		audit.Record(ctx, server.store, event)
+		respondError(ctx, apperr.New(apperr.CodePermissionDenied, "account does not belong to logged in user"))
 		return
 	}
 
+	limits := server.transferLimits[req.Currency]
 	input := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
+		ToAccountID:   toAccountID,
 		Amount:        req.Amount,
+		Limits:        &limits,
 	}
 
-	results, err := server.store.TransferTx(ctx, &input)
+	// Suspicious transfers are refused or wait for a banker instead of posting.
+	assessment, err := server.screener.Screen(ctx, risk.Transfer{
+		Username:      payload.Username,
+		FromAccountID: input.FromAccountID,
+		ToAccountID:   input.ToAccountID,
+		ToOwner:       toOwner,
+		Amount:        input.Amount,
+		Currency:      req.Currency,
+		ClientIP:      event.Client.IP,
+		At:            time.Now(),
+	}, risk.StoreHistory{Store: server.store})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, results)
-}
+	metrics.ObserveScreening(assessment.Verdict.String())
+	switch assessment.Verdict {
+	case risk.Deny:
+		event.Outcome = audit.OutcomeFailure
+		event.ResourceType = audit.ResourceAccount
+		event.ResourceID = strconv.FormatInt(req.FromAccountID, 10)
+		event.After = assessment
+		audit.Record(ctx, server.store, event)
+		respondError(ctx, apperr.New(apperr.CodePermissionDenied, "the transfer was declined"))
+		return
+	case risk.Review:
+		server.reviewTransfer(ctx, event, input, req.Currency, assessment)
+		return
+	}
+
+	// The transfer is recorded in the audit log in the same transaction as the transfer itself.
+	event.Outcome = audit.OutcomeSuccess
+	auditEntry, err := event.Params()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	input.Audit = &auditEntry
 
-// Returns true if the currency on the account object pointed to by account id matches 'currency'
-func validateAccount(ctx *gin.Context, store db.Store, accountId int64, currency string) (db.Account, bool) {
-	account, err := store.GetAccount(ctx, accountId)
+	results, err := server.store.TransferTx(ctx, &input)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusBadRequest, errorResponse(err))
-			return account, false
+		respondError(ctx, accountError(err))
+		return
+	}
+	metrics.ObserveTransfer(req.Currency, req.Amount)
+
+	// Someone else's balance is none of the sender's business.
+	if results.ToAccount.Owner != payload.Username {
+		results.ToAccount = db.Account{
+			ID:       results.ToAccount.ID,
+			Owner:    results.ToAccount.Owner,
+			Currency: results.ToAccount.Currency,
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return account, false
+		results.ToEntry = db.Entry{}
 	}
-	if account.Currency != currency {
-		err := fmt.Errorf("currency on account does not match the specificed currency")
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return account, false
+	// Nor is the bank's side of the fee.
+	if results.Fee != nil {
+		results.Fee.ToEntry = db.Entry{}
 	}
-	return account, true
+	ctx.JSON(http.StatusOK, results)
 }
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type transferReviewResponse struct {
+	ID            int64     `json:"id"`
+	TransferID    int64     `json:"transfer_id"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func newTransferReviewResponse(review db.TransferReview, currency string) transferReviewResponse {
+	return transferReviewResponse{
+		ID:            review.ID,
+		TransferID:    review.TransferID,
+		FromAccountID: review.FromAccount,
+		ToAccountID:   review.ToAccount,
+		Amount:        review.Amount,
+		Currency:      currency,
+		Status:        review.Status,
+		CreatedAt:     review.CreatedAt,
+		ExpiresAt:     review.ExpiresAt,
+	}
+}
+
+// queuedReviewResponse is a review as bankers see it, with why it was flagged and who decided.
+type queuedReviewResponse struct {
+	transferReviewResponse
+	RequestedBy string          `json:"requested_by"`
+	ClientIP    string          `json:"client_ip"`
+	Findings    json.RawMessage `json:"findings"`
+	DecidedBy   string          `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time      `json:"decided_at,omitempty"`
+	Reason      string          `json:"reason,omitempty"`
+}
+
+type listTransferReviewsRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending approved rejected expired"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// listTransferReviews lists the transfers waiting for a banker, oldest first. Decided reviews can
+// be listed by status.
+func (server *Server) listTransferReviews(ctx *gin.Context) {
+	var req listTransferReviewsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	if req.Status == "" {
+		req.Status = db.ReviewPending
+	}
+
+	reviews, err := server.store.ListTransferReviews(ctx, db.ListTransferReviewsParams{
+		Status:     req.Status,
+		PageLimit:  req.PageSize,
+		PageOffset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	rsp := make([]queuedReviewResponse, 0, len(reviews))
+	for _, row := range reviews {
+		review := db.TransferReview{
+			ID:          row.ID,
+			FromAccount: row.FromAccount,
+			ToAccount:   row.ToAccount,
+			Amount:      row.Amount,
+			RequestedBy: row.RequestedBy,
+			ClientIp:    row.ClientIp,
+			Findings:    row.Findings,
+			Status:      row.Status,
+			CreatedAt:   row.CreatedAt,
+			TransferID:  row.TransferID,
+			ExpiresAt:   row.ExpiresAt,
+			DecidedBy:   row.DecidedBy,
+			DecidedAt:   row.DecidedAt,
+			Reason:      row.Reason,
+		}
+		rsp = append(rsp, newQueuedReviewResponse(review, row.Currency))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func newQueuedReviewResponse(review db.TransferReview, currency string) queuedReviewResponse {
+	rsp := queuedReviewResponse{
+		transferReviewResponse: newTransferReviewResponse(review, currency),
+		RequestedBy:            review.RequestedBy,
+		ClientIP:               review.ClientIp,
+		Findings:               review.Findings,
+		DecidedBy:              review.DecidedBy.String,
+		Reason:                 review.Reason,
+	}
+	if review.DecidedAt.Valid {
+		rsp.DecidedAt = &review.DecidedAt.Time
+	}
+	return rsp
+}
+
+type transferReviewURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type decideTransferReviewRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+	Reason   string `json:"reason" binding:"required,max=200"`
+}
+
+// decideTransferReview lets a banker approve a held transfer, which posts it, or reject it, which
+// releases the amount it held. The sender is notified either way. Bankers can't decide on their
+// own transfers.
+func (server *Server) decideTransferReview(ctx *gin.Context) {
+	var uri transferReviewURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+	var req decideTransferReviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+
+	review, err := server.store.GetTransferReview(ctx, uri.ID)
+	if err != nil {
+		respondError(ctx, apperr.FromDB(err, "transfer review"))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if review.RequestedBy == payload.Username {
+		respondError(ctx, apperr.New(apperr.CodePermissionDenied, "bankers can't decide on their own transfers"))
+		return
+	}
+
+	status := db.ReviewApproved
+	if req.Decision == "reject" {
+		status = db.ReviewRejected
+	}
+	event := audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionTransferDecide,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceTransferReview,
+		ResourceID:   strconv.FormatInt(review.ID, 10),
+		Client:       auditClient(ctx),
+	}
+	auditEntry, err := event.Params()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	result, err := server.store.DecideTransferReviewTx(ctx, db.DecideTransferReviewTxParams{
+		ReviewID:  review.ID,
+		Status:    status,
+		DecidedBy: payload.Username,
+		Reason:    req.Reason,
+		Now:       time.Now(),
+		Audit:     &auditEntry,
+	})
+	if err != nil {
+		appErr := accountError(err)
+		if appErr.Code == apperr.CodeFailedPrecondition {
+			event.Outcome = audit.OutcomeFailure
+			event.After = gin.H{"decision": req.Decision, "error": err.Error()}
+			audit.Record(ctx, server.store, event)
+		}
+		respondError(ctx, appErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, newQueuedReviewResponse(result.Review, result.Transfer.FromAccount.Currency))
+}
+
+type listNotificationsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+type notificationResponse struct {
+	ID         int64     `json:"id"`
+	Kind       string    `json:"kind"`
+	Message    string    `json:"message"`
+	TransferID int64     `json:"transfer_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// listNotifications lists the caller's notifications, newest first.
+func (server *Server) listNotifications(ctx *gin.Context) {
+	var req listNotificationsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, bindError(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	notifications, err := server.store.ListNotifications(ctx, db.ListNotificationsParams{
+		Username: payload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	rsp := make([]notificationResponse, 0, len(notifications))
+	for _, n := range notifications {
+		rsp = append(rsp, notificationResponse{
+			ID:         n.ID,
+			Kind:       n.Kind,
+			Message:    n.Message,
+			TransferID: n.TransferID.Int64,
+			CreatedAt:  n.CreatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
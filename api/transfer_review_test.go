@@ -0,0 +1,350 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/audit"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomTransferReview(requestedBy string) db.TransferReview {
+	return db.TransferReview{
+		ID:          util.RandomInt(1, 1000),
+		TransferID:  util.RandomInt(1, 1000),
+		FromAccount: util.RandomInt(1, 1000),
+		ToAccount:   util.RandomInt(1, 1000),
+		Amount:      util.RandomMoney(),
+		RequestedBy: requestedBy,
+		ClientIp:    "10.0.0.1",
+		Findings:    json.RawMessage(`[{"rule":"large","verdict":"review","reason":"large"}]`),
+		Status:      db.ReviewPending,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+}
+
+func TestListTransferReviewsAPI(t *testing.T) {
+	review := randomTransferReview(util.RandomOwner())
+
+	testCases := []struct {
+		name        string
+		query       string
+		scopes      []string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Pending",
+			query:  "page_id=1&page_size=5",
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransferReviews(gomock.Any(), gomock.Eq(db.ListTransferReviewsParams{
+					Status:     db.ReviewPending,
+					PageLimit:  5,
+					PageOffset: 0,
+				})).Return([]db.ListTransferReviewsRow{{
+					ID:          review.ID,
+					TransferID:  review.TransferID,
+					FromAccount: review.FromAccount,
+					ToAccount:   review.ToAccount,
+					Amount:      review.Amount,
+					RequestedBy: review.RequestedBy,
+					Findings:    review.Findings,
+					Status:      review.Status,
+					ExpiresAt:   review.ExpiresAt,
+					Currency:    util.USD,
+				}}, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got []queuedReviewResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Len(t, got, 1)
+				require.Equal(t, review.ID, got[0].ID)
+				require.Equal(t, util.USD, got[0].Currency)
+				require.JSONEq(t, string(review.Findings), string(got[0].Findings))
+				require.Nil(t, got[0].DecidedAt)
+			},
+		},
+		{
+			name:   "Rejected",
+			query:  "status=rejected&page_id=2&page_size=5",
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransferReviews(gomock.Any(), gomock.Eq(db.ListTransferReviewsParams{
+					Status:     db.ReviewRejected,
+					PageLimit:  5,
+					PageOffset: 5,
+				})).Return([]db.ListTransferReviewsRow{}, nil).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				require.JSONEq(t, `[]`, resp.Body.String())
+			},
+		},
+		{
+			name:   "InvalidStatus",
+			query:  "status=posted&page_id=1&page_size=5",
+			scopes: token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransferReviews(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:   "Depositor",
+			query:  "page_id=1&page_size=5",
+			scopes: token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransferReviews(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(util.RandomOwner(), tc.scopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/transfer_reviews?"+tc.query, nil)
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestDecideTransferReviewAPI(t *testing.T) {
+	banker := util.RandomOwner()
+	review := randomTransferReview(util.RandomOwner())
+	own := randomTransferReview(banker)
+
+	decided := func(status string) func(_ interface{}, arg db.DecideTransferReviewTxParams) (db.DecideTransferReviewTxResult, error) {
+		return func(_ interface{}, arg db.DecideTransferReviewTxParams) (db.DecideTransferReviewTxResult, error) {
+			require.Equal(t, review.ID, arg.ReviewID)
+			require.Equal(t, status, arg.Status)
+			require.Equal(t, banker, arg.DecidedBy)
+			require.Equal(t, audit.ActionTransferDecide, arg.Audit.Action)
+			result := review
+			result.Status = status
+			result.DecidedBy = sql.NullString{String: banker, Valid: true}
+			result.DecidedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			result.Reason = arg.Reason
+			return db.DecideTransferReviewTxResult{
+				Review:   result,
+				Transfer: db.TransferTxResults{FromAccount: db.Account{ID: review.FromAccount, Currency: util.EUR}},
+			}, nil
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		reviewID    int64
+		body        gin.H
+		scopes      []string
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Approve",
+			reviewID: review.ID,
+			body:     gin.H{"decision": "approve", "reason": "called the customer"},
+			scopes:   token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferReview(gomock.Any(), gomock.Eq(review.ID)).Return(review, nil).Times(1)
+				store.EXPECT().DecideTransferReviewTx(gomock.Any(), gomock.Any()).DoAndReturn(decided(db.ReviewApproved)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got queuedReviewResponse
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, db.ReviewApproved, got.Status)
+				require.Equal(t, banker, got.DecidedBy)
+				require.Equal(t, util.EUR, got.Currency)
+				require.NotNil(t, got.DecidedAt)
+			},
+		},
+		{
+			name:     "Reject",
+			reviewID: review.ID,
+			body:     gin.H{"decision": "reject", "reason": "customer did not make it"},
+			scopes:   token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferReview(gomock.Any(), gomock.Eq(review.ID)).Return(review, nil).Times(1)
+				store.EXPECT().DecideTransferReviewTx(gomock.Any(), gomock.Any()).DoAndReturn(decided(db.ReviewRejected)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				require.Contains(t, resp.Body.String(), "customer did not make it")
+			},
+		},
+		{
+			name:     "OwnTransfer",
+			reviewID: own.ID,
+			body:     gin.H{"decision": "approve", "reason": "looks fine to me"},
+			scopes:   token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferReview(gomock.Any(), gomock.Eq(own.ID)).Return(own, nil).Times(1)
+				store.EXPECT().DecideTransferReviewTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			name:     "Closed",
+			reviewID: review.ID,
+			body:     gin.H{"decision": "approve", "reason": "too late"},
+			scopes:   token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferReview(gomock.Any(), gomock.Eq(review.ID)).Return(review, nil).Times(1)
+				store.EXPECT().DecideTransferReviewTx(gomock.Any(), gomock.Any()).
+					Return(db.DecideTransferReviewTxResult{}, fmt.Errorf("review is expired: %w", db.ErrReviewClosed)).Times(1)
+				store.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionTransferDecide, audit.OutcomeFailure)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "failed_precondition")
+			},
+		},
+		{
+			name:     "NotFound",
+			reviewID: review.ID,
+			body:     gin.H{"decision": "approve", "reason": "fine"},
+			scopes:   token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferReview(gomock.Any(), gomock.Eq(review.ID)).Return(db.TransferReview{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().DecideTransferReviewTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, resp.Code)
+			},
+		},
+		{
+			name:     "NoReason",
+			reviewID: review.ID,
+			body:     gin.H{"decision": "reject"},
+			scopes:   token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferReview(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "reason")
+			},
+		},
+		{
+			name:     "UnknownDecision",
+			reviewID: review.ID,
+			body:     gin.H{"decision": "maybe", "reason": "unsure"},
+			scopes:   token.BankerScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferReview(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name:     "Depositor",
+			reviewID: review.ID,
+			body:     gin.H{"decision": "approve", "reason": "please"},
+			scopes:   token.UserScopes,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferReview(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			accessToken, _, err := server.maker.CreateToken(banker, tc.scopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			url := fmt.Sprintf("/transfer_reviews/%d/decision", tc.reviewID)
+			req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			req.Header.Set("authorization", "bearer "+accessToken)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
+
+func TestListNotificationsAPI(t *testing.T) {
+	username := util.RandomOwner()
+	notification := db.Notification{
+		ID:         1,
+		Username:   username,
+		Kind:       "transfer.rejected",
+		Message:    "Your transfer of 10 USD to account 2 was rejected: unusual",
+		TransferID: sql.NullInt64{Int64: 7, Valid: true},
+		CreatedAt:  time.Now(),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListNotifications(gomock.Any(), gomock.Eq(db.ListNotificationsParams{
+		Username: username,
+		Limit:    5,
+		Offset:   0,
+	})).Return([]db.Notification{notification}, nil).Times(1)
+	server := newTestServer(t, store)
+
+	req := httptest.NewRequest(http.MethodGet, "/notifications?page_id=1&page_size=5", nil)
+	addAuthHeader(t, req, server.maker, username, time.Minute)
+	resp := httptest.NewRecorder()
+	server.router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	var got []notificationResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, notification.Message, got[0].Message)
+	require.Equal(t, int64(7), got[0].TransferID)
+
+	req = httptest.NewRequest(http.MethodGet, "/notifications?page_id=0&page_size=5", nil)
+	addAuthHeader(t, req, server.maker, username, time.Minute)
+	resp = httptest.NewRecorder()
+	server.router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,330 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/txlimit"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferAPI(t *testing.T) {
+	amount := int64(10)
+	username := util.RandomOwner()
+	account1 := randomAccount(username)
+	account2 := randomAccount(util.RandomOwner())
+	account2.ID = account1.ID + 1
+	account2.Currency = account1.Currency
+	account2.IsDefault = true
+
+	frozen := account1
+	frozen.Status = db.AccountFrozen
+	payee := db.GetPayeeAccountRow{ID: account2.ID, Owner: account2.Owner, Status: db.AccountActive, FullName: "Jane Smith"}
+	email := util.RandomEmail()
+
+	transferResult := func(_ interface{}, arg *db.TransferTxParams) (db.TransferTxResults, error) {
+		require.Equal(t, account1.ID, arg.FromAccountID)
+		require.Equal(t, account2.ID, arg.ToAccountID)
+		require.Equal(t, amount, arg.Amount)
+		require.NotNil(t, arg.Limits)
+		return db.TransferTxResults{
+			Transfer:    db.Transfer{ID: 1, FromAccount: account1.ID, ToAccount: account2.ID, Amount: amount},
+			FromAccount: account1,
+			ToAccount:   account2,
+			ToEntry:     db.Entry{ID: 2, AccountID: account2.ID, Amount: amount},
+		}, nil
+	}
+
+	testCases := []struct {
+		name        string
+		body        gin.H
+		buildStore  func(store *mockdb.MockStore)
+		matchResult func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ToAccountID",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Return(account2, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).DoAndReturn(transferResult).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name: "ToUsername",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_username":     account2.Owner,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Eq(db.GetPayeeAccountParams{
+					Username: sql.NullString{String: account2.Owner, Valid: true},
+					Currency: account1.Currency,
+				})).Return(payee, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).DoAndReturn(transferResult).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+
+				var got db.TransferTxResults
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.Equal(t, account2.ID, got.ToAccount.ID)
+				// The recipient's balance is not disclosed to the sender.
+				require.Zero(t, got.ToAccount.Balance)
+				require.Zero(t, got.ToEntry.ID)
+				require.Equal(t, account1.Balance, got.FromAccount.Balance)
+			},
+		},
+		{
+			name: "ToEmail",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_email":        email,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Eq(db.GetPayeeAccountParams{
+					Email:    sql.NullString{String: email, Valid: true},
+					Currency: account1.Currency,
+				})).Return(payee, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).DoAndReturn(transferResult).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name: "PayeeID",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"payee_id":        7,
+				"amount":          amount,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(db.GetPayeeParams{ID: 7, Owner: username})).
+					Return(db.Payee{ID: 7, Owner: username, Username: sql.NullString{String: account2.Owner, Valid: true}, Currency: account1.Currency}, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Eq(db.GetPayeeAccountParams{
+					Username: sql.NullString{String: account2.Owner, Valid: true},
+					Currency: account1.Currency,
+				})).Return(payee, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).DoAndReturn(transferResult).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name: "UnknownPayee",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"payee_id":        7,
+				"amount":          amount,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPayee(gomock.Any(), gomock.Any()).Return(db.Payee{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "payee_id")
+			},
+		},
+		{
+			name: "NoCurrency",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "currency")
+			},
+		},
+		{
+			name: "NoDefaultAccount",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_username":     account2.Owner,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Any()).Return(db.GetPayeeAccountRow{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "to_username")
+			},
+		},
+		{
+			name: "BothRecipients",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"to_username":     account2.Owner,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_email":        "not-an-email",
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "to_email")
+			},
+		},
+		{
+			name: "LimitExceeded",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Return(account2, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Return(db.TransferTxResults{}, fmt.Errorf("the user daily limit of 100 USD leaves 5: %w", txlimit.ErrExceeded)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "daily limit")
+			},
+		},
+		{
+			name: "Fee",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Return(account2, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx interface{}, arg *db.TransferTxParams) (db.TransferTxResults, error) {
+						result, err := transferResult(ctx, arg)
+						result.Fee = &db.FeeCharge{
+							Transfer:  db.Transfer{ID: 2, FromAccount: account1.ID, ToAccount: 99, Amount: 1, Type: db.TransferFee},
+							FromEntry: db.Entry{ID: 3, AccountID: account1.ID, Amount: -1},
+							ToEntry:   db.Entry{ID: 4, AccountID: 99, Amount: 1},
+						}
+						return result, err
+					}).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+				var got db.TransferTxResults
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				require.NotNil(t, got.Fee)
+				require.Equal(t, int64(1), got.Fee.Transfer.Amount)
+				require.Equal(t, int64(-1), got.Fee.FromEntry.Amount)
+				// The bank's side of the fee is not shown.
+				require.Zero(t, got.Fee.ToEntry.ID)
+			},
+		},
+		{
+			name: "SystemAccount",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Return(account2, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Return(db.TransferTxResults{}, fmt.Errorf("account %d: %w", account2.ID, db.ErrSystemAccount)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "failed_precondition")
+			},
+		},
+		{
+			name: "FrozenAccount",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			},
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(frozen, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				require.Contains(t, resp.Body.String(), "failed_precondition")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStore(store)
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			addAuthHeader(t, req, server.maker, username, time.Minute)
+			resp := httptest.NewRecorder()
+			server.router.ServeHTTP(resp, req)
+			tc.matchResult(t, resp)
+		})
+	}
+}
@@ -2,21 +2,25 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
 	db "github.com/ashokmouli/simplebank/db/sqlc"
-	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/metrics"
+	"github.com/ashokmouli/simplebank/password"
 	"github.com/ashokmouli/simplebank/token"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type createUserRequest struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
-	FullName string `json:"fullname" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
+	Username string `json:"username" binding:"required,username"`
+	Password string `json:"password" binding:"required"`
+	FullName string `json:"fullname" binding:"required,fullname"`
+	Email    string `json:"email" binding:"required,email_address"`
 }
 
 type createUserResponse struct {
@@ -30,13 +34,27 @@ type createUserResponse struct {
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		appErr := bindError(err)
+		// Report the password policy along with the binding rules, as the gRPC server does. An
+		// empty password has already been reported as required.
+		if len(appErr.Violations) > 0 && req.Password != "" {
+			if err := server.passwordPolicy.Validate(req.Password, req.Username); err != nil {
+				appErr.Violations = append(appErr.Violations, apperr.Violation("password", err.Error()))
+			}
+		}
+		respondError(ctx, appErr)
+		return
+	}
+
+	if err := server.passwordPolicy.Validate(req.Password, req.Username); err != nil {
+		respondError(ctx, apperr.InvalidArgument(apperr.Violation("password", err.Error())))
 		return
 	}
 
-	hashedPassword, err := util.HashPassword(req.Password)
+	hashedPassword, err := server.passwords.Hash(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err)
+		respondError(ctx, err)
+		return
 	}
 
 	user, err := server.store.CreateUser(ctx, db.CreateUserParams{
@@ -47,7 +65,7 @@ func (server *Server) createUser(ctx *gin.Context) {
 	})
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, apperr.FromDB(err, "user"))
 		return
 	}
 	resp := createUserResponse{
@@ -67,11 +85,7 @@ func (server *Server) getUser(ctx *gin.Context) {
 	user, err := server.store.GetUser(ctx, payload.Username)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, apperr.FromDB(err, "user"))
 		return
 	}
 	resp := createUserResponse{
@@ -85,8 +99,11 @@ func (server *Server) getUser(ctx *gin.Context) {
 }
 
 type createLoginRequest struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username string `json:"username" binding:"required,username"`
+	Password string `json:"password" binding:"required,login_password"`
+	// Optional; narrows the scopes of the issued tokens. All scopes of the user's role are granted
+	// when empty.
+	Scopes []string `json:"scopes"`
 }
 
 type createLoginResponse struct {
@@ -103,38 +120,66 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	// Unmarshal the request
 	var req createLoginRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, bindError(err))
 		return
 	}
 
+	if len(req.Scopes) > 0 {
+		if err := token.CheckScopes(req.Scopes, token.BankerScopes); err != nil {
+			respondError(ctx, apperr.InvalidArgument(apperr.Violation("scopes", err.Error())))
+			return
+		}
+	}
+
 	// Fetch the user object from DB
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
+		if errors.Is(err, sql.ErrNoRows) {
+			metrics.ObserveLogin(false)
+			server.auditLogin(ctx, req.Username, audit.OutcomeFailure)
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, apperr.FromDB(err, "user"))
 		return
 	}
 
 	// Check the password.
-	err = util.CheckPassword(req.Password, user.HashedPassword)
+	rehash, err := server.passwords.Check(req.Password, user.HashedPassword)
 	if err != nil {
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		if errors.Is(err, password.ErrMismatch) {
+			metrics.ObserveLogin(false)
+			server.auditLogin(ctx, req.Username, audit.OutcomeFailure)
+			respondError(ctx, apperr.Wrap(err, apperr.CodePermissionDenied, "password mismatch"))
+			return
+		}
+		respondError(ctx, err)
 		return
 	}
+	if rehash {
+		server.passwords.Rehash(ctx, server.store, user, req.Password)
+	}
+
+	// Tokens get all the scopes of the user's role unless the caller asks for fewer.
+	scopes := token.RoleScopes(user.Role)
+	if len(req.Scopes) > 0 {
+		if err := token.CheckScopes(req.Scopes, scopes); err != nil {
+			respondError(ctx, apperr.Wrap(err, apperr.CodePermissionDenied, err.Error()))
+			return
+		}
+		scopes = req.Scopes
+	}
 
 	// Create access token.
-	accessToken, accessPayload, err := server.maker.CreateToken(req.Username, server.config.AccessTokenDuration)
+	accessToken, accessPayload, err := server.maker.CreateToken(req.Username, scopes, token.AccessToken, server.config.AccessTokenDuration)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, err)
+		return
 	}
 
 	// Create refresh token.
-	refresh_token, refreshPayload, err := server.maker.CreateToken(req.Username, server.config.RefreshTokenDuration)
+	refresh_token, refreshPayload, err := server.maker.CreateToken(req.Username, scopes, token.RefreshToken, server.config.RefreshTokenDuration)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, err)
+		return
 	}
 
 	// Create a session record.
@@ -142,16 +187,29 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		ID:           refreshPayload.ID,
 		Username:     req.Username,
 		IsBlocked:    false,
-		ClientIp:     sql.NullString{String: ctx.Request.UserAgent(), Valid: true},
-		UserAgent:    sql.NullString{String: ctx.ClientIP(), Valid: true},
+		ClientIp:     sql.NullString{String: ctx.ClientIP(), Valid: true},
+		UserAgent:    sql.NullString{String: ctx.Request.UserAgent(), Valid: true},
 		RefreshToken: refresh_token,
 		ExpiresAt:    refreshPayload.ExpiredAt,
 	})
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, err)
+		return
 	}
 
+	metrics.ObserveLogin(true)
+	server.auditLogin(ctx, req.Username, audit.OutcomeSuccess)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        user.Username,
+		Action:       audit.ActionSessionCreate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceSession,
+		ResourceID:   session.ID.String(),
+		Client:       auditClient(ctx),
+		After:        gin.H{"expires_at": session.ExpiresAt, "scopes": scopes},
+	})
+
 	// Marshal back the response.
 	var resp createLoginResponse
 	resp.SessionID = session.ID
@@ -170,3 +228,15 @@ func (server *Server) loginUser(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, resp)
 }
+
+// auditLogin records a login attempt. Failed attempts are recorded under the username that was tried.
+func (server *Server) auditLogin(ctx *gin.Context, username string, outcome string) {
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        username,
+		Action:       audit.ActionLogin,
+		Outcome:      outcome,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   username,
+		Client:       auditClient(ctx),
+	})
+}
@@ -10,17 +10,22 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
 	mockdb "github.com/ashokmouli/simplebank/db/mock"
 	db "github.com/ashokmouli/simplebank/db/sqlc"
 	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/password"
+	"github.com/ashokmouli/simplebank/token"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func randomUser(t *testing.T) (db.User, string) {
-	password := util.RandomString(6)
-	hashedPassword, err := util.HashPassword(password)
+	clear := util.RandomString(6)
+	hashedPassword, err := testHasher.Hash(clear)
 	require.NoError(t, err)
 
 	user := db.User{
@@ -29,9 +34,14 @@ func randomUser(t *testing.T) (db.User, string) {
 		FullName:       util.RandomString(6),
 		Email:          util.RandomEmail(),
 	}
-	return user, password
+	return user, clear
 }
 
+var testHasher = password.Argon2Hasher{Params: password.DefaultArgon2Params}
+
+// testBcrypt makes the hashes of users who signed up before passwords were hashed with Argon2id.
+var testBcrypt = password.BcryptHasher{Cost: bcrypt.MinCost}
+
 // This struct is used to provide a Custom Matcher interface
 type eqCreateUserParamsMatcher struct {
 	expected         db.CreateUserParams
@@ -44,7 +54,7 @@ func (e eqCreateUserParamsMatcher) Matches(x interface{}) bool {
 	if !ok {
 		return false
 	}
-	err := util.CheckPassword(e.expectedPassword, actual.HashedPassword)
+	err := testHasher.Check(e.expectedPassword, actual.HashedPassword)
 	if err != nil {
 		return false
 	}
@@ -118,6 +128,38 @@ func TestCreateUser(t *testing.T) {
 				require.Equal(t, http.StatusInternalServerError, resp.Code)
 			},
 		},
+		{
+			name: "WeakPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": user.Username + "1",
+				"fullname": user.FullName,
+				"email":    user.Email,
+			},
+			buildStore: func(t *testing.T, mock *mockdb.MockStore) {
+				mock.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				requireErrorBody(t, resp, apperr.CodeInvalidArgument, "password")
+			},
+		},
+		{
+			name: "InvalidFields",
+			body: gin.H{
+				"username": "bob smith",
+				"password": "123",
+				"fullname": user.FullName,
+				"email":    "Bob <bob@email.com>",
+			},
+			buildStore: func(t *testing.T, mock *mockdb.MockStore) {
+				mock.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+				requireErrorBody(t, resp, apperr.CodeInvalidArgument, "username", "password", "email")
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -161,12 +203,58 @@ func TestLoginUser(t *testing.T) {
 			buildStore: func(t *testing.T, mock *mockdb.MockStore) {
 				mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(user, nil).Times(1)
 				mock.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				mock.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionLogin, audit.OutcomeSuccess)).Times(1)
+				mock.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionSessionCreate, audit.OutcomeSuccess)).Times(1)
 			},
 			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, resp.Code)
 				matchReturnedLogin(t, resp.Body, &user)
 			},
 		},
+		{
+			name: "RehashBcrypt",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStore: func(t *testing.T, mock *mockdb.MockStore) {
+				bcryptUser := user
+				bcryptUser.HashedPassword, _ = testBcrypt.Hash(password)
+				mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(bcryptUser, nil).Times(1)
+				mock.EXPECT().UpdateUserHashedPassword(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.UpdateUserHashedPasswordParams) (int64, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, bcryptUser.HashedPassword, arg.OldHashedPassword)
+						require.NoError(t, testHasher.Check(password, arg.HashedPassword))
+						return 1, nil
+					}).Times(1)
+				mock.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				mock.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionLogin, audit.OutcomeSuccess)).Times(1)
+				mock.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionSessionCreate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
+		{
+			name: "RehashFailureDoesNotFailLogin",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStore: func(t *testing.T, mock *mockdb.MockStore) {
+				bcryptUser := user
+				bcryptUser.HashedPassword, _ = testBcrypt.Hash(password)
+				mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(bcryptUser, nil).Times(1)
+				mock.EXPECT().UpdateUserHashedPassword(gomock.Any(), gomock.Any()).Return(int64(0), sql.ErrConnDone).Times(1)
+				mock.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				mock.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionLogin, audit.OutcomeSuccess)).Times(1)
+				mock.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionSessionCreate, audit.OutcomeSuccess)).Times(1)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
 		{
 			name: "Forbidden",
 			body: gin.H{
@@ -175,11 +263,45 @@ func TestLoginUser(t *testing.T) {
 			},
 			buildStore: func(t *testing.T, mock *mockdb.MockStore) {
 				mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(user, nil).Times(1)
+				mock.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionLogin, audit.OutcomeFailure)).Times(1)
 			},
 			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusForbidden, resp.Code)
 			},
 		},
+		{
+			name: "ScopeOutsideRole",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+				"scopes":   []string{token.ScopeAuditRead},
+			},
+			buildStore: func(t *testing.T, mock *mockdb.MockStore) {
+				mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(user, nil).Times(1)
+				mock.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, resp.Code)
+			},
+		},
+		{
+			name: "Banker",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+				"scopes":   []string{token.ScopeAuditRead},
+			},
+			buildStore: func(t *testing.T, mock *mockdb.MockStore) {
+				banker := user
+				banker.Role = token.RoleBanker
+				mock.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Return(banker, nil).Times(1)
+				mock.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1)
+				mock.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Times(2)
+			},
+			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, resp.Code)
+			},
+		},
 		{
 			name: "NotFound",
 			body: gin.H{
@@ -188,6 +310,7 @@ func TestLoginUser(t *testing.T) {
 			},
 			buildStore: func(t *testing.T, mock *mockdb.MockStore) {
 				mock.EXPECT().GetUser(gomock.Any(), gomock.Eq("nouser")).Return(db.User{}, sql.ErrNoRows).Times(1)
+				mock.EXPECT().CreateAuditLog(gomock.Any(), EqAuditEvent(audit.ActionLogin, audit.OutcomeFailure)).Times(1)
 			},
 			matchResult: func(t *testing.T, resp *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, resp.Code)
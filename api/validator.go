@@ -0,0 +1,64 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ashokmouli/simplebank/val"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// fieldRules are binding tags backed by the val package, the same rules the gRPC server checks.
+var fieldRules = map[string]func(reflect.Value) error{
+	"username":       stringRule(val.ValidateUsername),
+	"fullname":       stringRule(val.ValidateFullName),
+	"email_address":  stringRule(val.ValidateEmail),
+	"login_password": stringRule(val.ValidateLoginPassword),
+	"currency":       stringRule(val.ValidateCurrency),
+	"amount":         intRule(val.ValidateAmount),
+	"account_type":   stringRule(val.ValidateAccountType),
+	"nickname":       stringRule(val.ValidateNickname),
+	"payee_label":    stringRule(val.ValidatePayeeLabel),
+}
+
+func stringRule(validate func(string) error) func(reflect.Value) error {
+	return func(v reflect.Value) error {
+		return validate(v.String())
+	}
+}
+
+func intRule(validate func(int64) error) func(reflect.Value) error {
+	return func(v reflect.Value) error {
+		return validate(v.Int())
+	}
+}
+
+// registerValidators adds the val rules to Gin's validator, and makes validation errors name
+// fields by their json, form or uri tag.
+func registerValidators() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+
+	for tag, rule := range fieldRules {
+		rule := rule
+		v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return rule(fl.Field()) == nil
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/token"
+)
+
+// API keys look like sb_<prefix>_<secret>. The prefix is stored in clear so the key can be looked up,
+// the secret is only stored hashed.
+const (
+	keyTag       = "sb"
+	prefixBytes  = 4
+	secretBytes  = 32
+	keySeparator = "_"
+)
+
+var (
+	ErrInvalidKey = errors.New("api key is invalid")
+	ErrRevokedKey = errors.New("api key has been revoked")
+	ErrExpiredKey = errors.New("api key has expired")
+)
+
+// Generate creates a new random API key. It returns the key to hand to the user, along with the
+// prefix and hashed secret to store.
+func Generate() (key string, prefix string, hashedSecret string, err error) {
+	prefix, err = randomHex(prefixBytes)
+	if err != nil {
+		return
+	}
+	secret, err := randomHex(secretBytes)
+	if err != nil {
+		return
+	}
+	key = strings.Join([]string{keyTag, prefix, secret}, keySeparator)
+	hashedSecret = hashSecret(secret)
+	return
+}
+
+// Parse splits a key into its lookup prefix and secret.
+func Parse(key string) (prefix string, secret string, err error) {
+	parts := strings.Split(key, keySeparator)
+	if len(parts) != 3 || parts[0] != keyTag || len(parts[1]) != 2*prefixBytes || len(parts[2]) != 2*secretBytes {
+		return "", "", ErrInvalidKey
+	}
+	return parts[1], parts[2], nil
+}
+
+// Check verifies secret against the stored hash.
+func Check(secret string, hashedSecret string) error {
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(hashedSecret)) != 1 {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// Authenticate looks up the key, checks that it is usable and records its use.
+func Authenticate(ctx context.Context, store db.Store, key string) (db.APIKey, error) {
+	prefix, secret, err := Parse(key)
+	if err != nil {
+		return db.APIKey{}, err
+	}
+
+	apiKey, err := store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.APIKey{}, ErrInvalidKey
+		}
+		return db.APIKey{}, fmt.Errorf("could not look up api key: %w", err)
+	}
+
+	if err := Check(secret, apiKey.HashedSecret); err != nil {
+		return db.APIKey{}, err
+	}
+	if apiKey.RevokedAt.Valid {
+		return db.APIKey{}, ErrRevokedKey
+	}
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		return db.APIKey{}, ErrExpiredKey
+	}
+
+	// Only records the use if the last one is more than a minute old, so busy keys don't write on every request.
+	err = store.TouchAPIKey(ctx, apiKey.ID)
+	if err != nil {
+		return db.APIKey{}, fmt.Errorf("could not record api key use: %w", err)
+	}
+	return apiKey, nil
+}
+
+// NewPayload describes the owner of an API key in the same terms as a decoded token.
+func NewPayload(apiKey db.APIKey) *token.Payload {
+	return &token.Payload{
+		ID:        apiKey.ID,
+		Username:  apiKey.Username,
+		TokenType: token.APIKeyToken,
+		Scopes:    apiKey.Scopes,
+		IssuedAt:  apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiresAt.Time,
+	}
+}
+
+func hashSecret(secret string) string {
+	// The secret is 256 random bits, so a fast hash is enough; there is nothing to brute force.
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,57 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAndParse(t *testing.T) {
+	key, prefix, hashedSecret, err := Generate()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(key, "sb_"+prefix+"_"))
+	require.NotEmpty(t, hashedSecret)
+
+	gotPrefix, secret, err := Parse(key)
+	require.NoError(t, err)
+	require.Equal(t, prefix, gotPrefix)
+	require.NotContains(t, hashedSecret, secret)
+	require.NoError(t, Check(secret, hashedSecret))
+}
+
+func TestGenerateIsRandom(t *testing.T) {
+	key1, prefix1, _, err := Generate()
+	require.NoError(t, err)
+	key2, prefix2, _, err := Generate()
+	require.NoError(t, err)
+	require.NotEqual(t, key1, key2)
+	require.NotEqual(t, prefix1, prefix2)
+}
+
+func TestParseInvalidKey(t *testing.T) {
+	key, _, _, err := Generate()
+	require.NoError(t, err)
+
+	for _, bad := range []string{
+		"",
+		"sb",
+		"xx" + key[2:],
+		key + "0",
+		strings.Replace(key, "_", "-", -1),
+	} {
+		_, _, err := Parse(bad)
+		require.ErrorIs(t, err, ErrInvalidKey, bad)
+	}
+}
+
+func TestCheckWrongSecret(t *testing.T) {
+	_, _, hashedSecret, err := Generate()
+	require.NoError(t, err)
+	key, _, _, err := Generate()
+	require.NoError(t, err)
+
+	_, secret, err := Parse(key)
+	require.NoError(t, err)
+	require.ErrorIs(t, Check(secret, hashedSecret), ErrInvalidKey)
+}
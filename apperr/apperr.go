@@ -0,0 +1,224 @@
+// Package apperr is the error model shared by the Gin API, the gRPC server and the gateway. An
+// Error carries a stable code that clients can switch on and a message that is safe to show them;
+// the underlying cause is kept for logs only.
+package apperr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/lib/pq"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// Code identifies the kind of error. Codes are part of the API and must not change.
+type Code string
+
+const (
+	CodeInvalidArgument    Code = "invalid_argument"
+	CodeUnauthenticated    Code = "unauthenticated"
+	CodePermissionDenied   Code = "permission_denied"
+	CodeNotFound           Code = "not_found"
+	CodeAlreadyExists      Code = "already_exists"
+	CodeFailedPrecondition Code = "failed_precondition"
+	CodeRateLimited        Code = "rate_limited"
+	CodeUnavailable        Code = "unavailable"
+	CodeInternal           Code = "internal"
+)
+
+// grpcCodes maps each code to its gRPC status code. HTTP statuses follow from these the same way
+// the gateway derives them, so REST and gateway clients see the same status for the same error.
+var grpcCodes = map[Code]codes.Code{
+	CodeInvalidArgument:    codes.InvalidArgument,
+	CodeUnauthenticated:    codes.Unauthenticated,
+	CodePermissionDenied:   codes.PermissionDenied,
+	CodeNotFound:           codes.NotFound,
+	CodeAlreadyExists:      codes.AlreadyExists,
+	CodeFailedPrecondition: codes.FailedPrecondition,
+	CodeRateLimited:        codes.ResourceExhausted,
+	CodeUnavailable:        codes.Unavailable,
+	CodeInternal:           codes.Internal,
+}
+
+// errorDomain is the ErrorInfo domain of errors raised by this service.
+const errorDomain = "simplebank"
+
+// FieldViolation describes what is wrong with one field of a request.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Error is an error that can be returned to clients.
+type Error struct {
+	Code       Code
+	Message    string
+	Violations []FieldViolation
+	cause      error
+}
+
+// New returns an error with a message for the client.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf is like New with a formatted message.
+func Newf(code Code, format string, args ...interface{}) *Error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Wrap returns an error with a message for the client that keeps err as its cause.
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, cause: err}
+}
+
+// Internal hides err from the client behind a generic message.
+func Internal(err error) *Error {
+	return Wrap(err, CodeInternal, "internal error")
+}
+
+// InvalidArgument reports the fields that made a request invalid.
+func InvalidArgument(violations ...FieldViolation) *Error {
+	return &Error{Code: CodeInvalidArgument, Message: "invalid request", Violations: violations}
+}
+
+// Violation is a shorthand for a FieldViolation.
+func Violation(field, description string) FieldViolation {
+	return FieldViolation{Field: field, Description: description}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %s", e.Code, e.Message, e.cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors with the same code, so that errors.Is(err, apperr.New(apperr.CodeNotFound, ""))
+// checks the kind of an error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Message == ""
+}
+
+// GRPCStatus lets gRPC handlers return an *Error directly. The code is sent as the reason of an
+// ErrorInfo detail, and field violations as a BadRequest detail.
+func (e *Error) GRPCStatus() *status.Status {
+	code, ok := grpcCodes[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	st := status.New(code, e.Message)
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: string(e.Code), Domain: errorDomain}}
+	if len(e.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range e.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		details = append(details, badRequest)
+	}
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// HTTPStatus returns the HTTP status for the error.
+func (e *Error) HTTPStatus() int {
+	return runtime.HTTPStatusFromCode(e.GRPCStatus().Code())
+}
+
+// From returns err as an *Error. Errors that are not already one are treated as internal.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// FromStatus rebuilds the error a gRPC status was made from, for the gateway. Statuses that did
+// not come from an *Error, such as those raised by gRPC itself, get a code from their status code.
+func FromStatus(st *status.Status) *Error {
+	e := &Error{Code: codeFromGRPC(st.Code()), Message: st.Message()}
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if d.GetDomain() == errorDomain {
+				e.Code = Code(d.GetReason())
+			}
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				e.Violations = append(e.Violations, Violation(v.GetField(), v.GetDescription()))
+			}
+		}
+	}
+	return e
+}
+
+func codeFromGRPC(code codes.Code) Code {
+	for appCode, grpcCode := range grpcCodes {
+		if grpcCode == code {
+			return appCode
+		}
+	}
+	return CodeInternal
+}
+
+// FromDB maps a database error to an error for the client. what names the record involved, as in
+// "account not found".
+func FromDB(err error, what string) *Error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return Wrap(err, CodeNotFound, what+" not found")
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return Wrap(err, CodeAlreadyExists, what+" already exists")
+		case "foreign_key_violation":
+			return Wrap(err, CodeFailedPrecondition, what+" refers to a record that does not exist")
+		case "check_violation":
+			return Wrap(err, CodeFailedPrecondition, what+" violates a constraint")
+		case "serialization_failure", "deadlock_detected":
+			return Wrap(err, CodeUnavailable, "the request conflicted with another one, please retry")
+		}
+	}
+	return Internal(err)
+}
+
+// Envelope is the JSON body of an error response, the same from the Gin API and the gateway.
+type Envelope struct {
+	Error EnvelopeError `json:"error"`
+}
+
+// EnvelopeError is the error inside an Envelope.
+type EnvelopeError struct {
+	Code       Code             `json:"code"`
+	Message    string           `json:"message"`
+	Violations []FieldViolation `json:"field_violations,omitempty"`
+}
+
+// Envelope returns the JSON body for the error.
+func (e *Error) Envelope() Envelope {
+	return Envelope{Error: EnvelopeError{
+		Code:       e.Code,
+		Message:    e.Message,
+		Violations: e.Violations,
+	}}
+}
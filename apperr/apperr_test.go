@@ -0,0 +1,93 @@
+package apperr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFromDB(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		code    Code
+		message string
+	}{
+		{"NoRows", sql.ErrNoRows, CodeNotFound, "account not found"},
+		{"WrappedNoRows", fmt.Errorf("get: %w", sql.ErrNoRows), CodeNotFound, "account not found"},
+		{"Unique", &pq.Error{Code: "23505"}, CodeAlreadyExists, "account already exists"},
+		{"ForeignKey", &pq.Error{Code: "23503"}, CodeFailedPrecondition, "account refers to a record that does not exist"},
+		{"Check", &pq.Error{Code: "23514"}, CodeFailedPrecondition, "account violates a constraint"},
+		{"Serialization", &pq.Error{Code: "40001"}, CodeUnavailable, "the request conflicted with another one, please retry"},
+		{"Other", sql.ErrConnDone, CodeInternal, "internal error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := FromDB(tc.err, "account")
+			require.Equal(t, tc.code, err.Code)
+			require.Equal(t, tc.message, err.Message)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestInternalHidesCause(t *testing.T) {
+	err := Internal(errors.New("password=secret"))
+	require.NotContains(t, err.Envelope().Error.Message, "secret")
+	require.NotContains(t, err.GRPCStatus().Message(), "secret")
+	// The cause is kept for logs.
+	require.Contains(t, err.Error(), "secret")
+	require.Equal(t, http.StatusInternalServerError, err.HTTPStatus())
+}
+
+func TestFrom(t *testing.T) {
+	notFound := New(CodeNotFound, "user not found")
+	require.Same(t, notFound, From(fmt.Errorf("wrapped: %w", notFound)))
+	require.Equal(t, CodeInternal, From(errors.New("boom")).Code)
+	require.ErrorIs(t, fmt.Errorf("wrapped: %w", notFound), New(CodeNotFound, ""))
+}
+
+func TestGRPCStatusRoundTrip(t *testing.T) {
+	err := InvalidArgument(Violation("amount", "must be greater than 0"), Violation("currency", "is required"))
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Equal(t, http.StatusBadRequest, err.HTTPStatus())
+
+	var badRequest *errdetails.BadRequest
+	var info *errdetails.ErrorInfo
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			badRequest = d
+		case *errdetails.ErrorInfo:
+			info = d
+		}
+	}
+	require.NotNil(t, badRequest)
+	require.Len(t, badRequest.FieldViolations, 2)
+	require.NotNil(t, info)
+	require.Equal(t, string(CodeInvalidArgument), info.Reason)
+
+	// The gateway sees the same envelope as the Gin API.
+	require.Equal(t, err.Envelope(), FromStatus(st).Envelope())
+}
+
+func TestFromStatusWithoutDetails(t *testing.T) {
+	err := FromStatus(status.New(codes.NotFound, "Not Found"))
+	require.Equal(t, CodeNotFound, err.Code)
+	require.Equal(t, "Not Found", err.Message)
+
+	err = FromStatus(status.New(codes.DeadlineExceeded, "deadline exceeded"))
+	require.Equal(t, CodeInternal, err.Code)
+}
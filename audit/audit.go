@@ -0,0 +1,139 @@
+// Package audit records security and money events in the append-only audit_log table: who did
+// what, from where, and what changed.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/rs/zerolog/log"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionLogin          = "user.login"
+	ActionSessionCreate  = "session.create"
+	ActionSessionRenew   = "session.renew"
+	ActionSessionRevoke  = "session.revoke"
+	ActionAccountCreate  = "account.create"
+	ActionAccountStatus  = "account.status"
+	ActionAccountUpdate  = "account.update"
+	ActionTransferCreate = "transfer.create"
+	ActionTransferHold   = "transfer.hold"
+	ActionTransferDecide = "transfer.decide"
+	ActionTransferExpire = "transfer.expire"
+	ActionAPIKeyCreate   = "api_key.create"
+	ActionAPIKeyRevoke   = "api_key.revoke"
+	ActionPayeeCreate    = "payee.create"
+	ActionPayeeUpdate    = "payee.update"
+	ActionPayeeDelete    = "payee.delete"
+	ActionLimitsUpdate   = "limits.update"
+	ActionInterestRate   = "interest.rate"
+	ActionInterestPost   = "interest.post"
+	ActionFeeUpdate      = "fee.update"
+	ActionFeeDelete      = "fee.delete"
+)
+
+// ActorSystem is the actor of what the bank does on its own, such as expiring held transfers.
+const ActorSystem = "system"
+
+// Outcomes of an action.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Types of the resources actions apply to.
+const (
+	ResourceUser            = "user"
+	ResourceSession         = "session"
+	ResourceAccount         = "account"
+	ResourceTransfer        = "transfer"
+	ResourceAPIKey          = "api_key"
+	ResourcePayee           = "payee"
+	ResourceTransferReview  = "transfer_review"
+	ResourceInterestRate    = "interest_rate"
+	ResourceInterestPosting = "interest_posting"
+	ResourceFeeSchedule     = "fee_schedule"
+)
+
+// Client is where a request came from.
+type Client struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+// Event is one entry of the audit log. Before and After are stored as JSON; leave them nil when
+// nothing was changed or there was nothing before.
+type Event struct {
+	Actor        string
+	Action       string
+	Outcome      string
+	ResourceType string
+	ResourceID   string
+	Client       Client
+	Before       interface{}
+	After        interface{}
+}
+
+// Params returns the row to insert for the event.
+func (e Event) Params() (db.CreateAuditLogParams, error) {
+	before, err := marshalState(e.Before)
+	if err != nil {
+		return db.CreateAuditLogParams{}, fmt.Errorf("cannot encode state before %s: %w", e.Action, err)
+	}
+	after, err := marshalState(e.After)
+	if err != nil {
+		return db.CreateAuditLogParams{}, fmt.Errorf("cannot encode state after %s: %w", e.Action, err)
+	}
+	return db.CreateAuditLogParams{
+		Actor:        e.Actor,
+		Action:       e.Action,
+		Outcome:      e.Outcome,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		ClientIp:     e.Client.IP,
+		UserAgent:    e.Client.UserAgent,
+		RequestID:    e.Client.RequestID,
+		Before:       before,
+		After:        after,
+	}, nil
+}
+
+func marshalState(state interface{}) (json.RawMessage, error) {
+	if state == nil {
+		return json.RawMessage("{}"), nil
+	}
+	return json.Marshal(state)
+}
+
+// Writer stores audit log entries; db.Store and *db.Queries are writers.
+type Writer interface {
+	CreateAuditLog(ctx context.Context, arg db.CreateAuditLogParams) (db.AuditLog, error)
+}
+
+// Record writes event to the audit log. A failure is logged instead of failing the request it
+// describes; events that must be recorded with the change they describe, such as transfers, are
+// written in the same transaction instead.
+func Record(ctx context.Context, w Writer, event Event) {
+	params, err := event.Params()
+	if err == nil {
+		_, err = w.CreateAuditLog(ctx, params)
+	}
+	if err != nil {
+		log.Error().Err(err).
+			Str("action", event.Action).
+			Str("actor", event.Actor).
+			Str("outcome", event.Outcome).
+			Msg("cannot write audit log")
+	}
+}
+
+// NullString turns an optional filter of ListAuditLog into its argument: empty matches anything.
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
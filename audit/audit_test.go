@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventParams(t *testing.T) {
+	event := Event{
+		Actor:        "alice",
+		Action:       ActionAPIKeyRevoke,
+		Outcome:      OutcomeSuccess,
+		ResourceType: ResourceAPIKey,
+		ResourceID:   "key",
+		Client:       Client{IP: "192.0.2.1", UserAgent: "curl", RequestID: "req"},
+		After:        map[string]string{"name": "batch"},
+	}
+
+	params, err := event.Params()
+	require.NoError(t, err)
+	require.Equal(t, "alice", params.Actor)
+	require.Equal(t, "192.0.2.1", params.ClientIp)
+	require.Equal(t, "req", params.RequestID)
+	require.JSONEq(t, `{}`, string(params.Before))
+	require.JSONEq(t, `{"name":"batch"}`, string(params.After))
+
+	event.After = func() {}
+	_, err = event.Params()
+	require.Error(t, err)
+}
+
+type failingWriter struct {
+	calls int
+}
+
+func (w *failingWriter) CreateAuditLog(ctx context.Context, arg db.CreateAuditLogParams) (db.AuditLog, error) {
+	w.calls++
+	return db.AuditLog{}, errors.New("database is down")
+}
+
+func TestRecordDoesNotFail(t *testing.T) {
+	w := &failingWriter{}
+	Record(context.Background(), w, Event{Action: ActionLogin, Outcome: OutcomeFailure})
+	require.Equal(t, 1, w.calls)
+
+	// An event that can't be encoded is never written.
+	Record(context.Background(), w, Event{Action: ActionLogin, After: make(chan int)})
+	require.Equal(t, 1, w.calls)
+}
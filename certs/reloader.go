@@ -0,0 +1,169 @@
+// Package certs loads the TLS certificates the servers present and trust, and reloads them when
+// the files change so that rotated certificates take effect without a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// Reloader holds a certificate and key pair, and optionally the internal CA that signs client
+// certificates, as last read from disk.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	pool *x509.CertPool
+}
+
+// NewReloader reads the certificate and key, and the CA bundle if caFile is not empty.
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again. The previous certificates stay in use if any file is invalid.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("cannot read CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA file %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.pool = pool
+	return nil
+}
+
+// MutualTLS reports whether a CA was configured, in which case the gRPC server requires client
+// certificates signed by it.
+func (r *Reloader) MutualTLS() bool {
+	return r.caFile != ""
+}
+
+func (r *Reloader) certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+func (r *Reloader) certPool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
+
+// ServerConfig returns the TLS config for a listener. With verifyClients, clients must present a
+// certificate signed by the CA.
+func (r *Reloader) ServerConfig(verifyClients bool) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.certificate(), nil
+		},
+	}
+	if verifyClients {
+		// Look the CA up per connection so that a reloaded bundle applies to new clients.
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clientConfig := config.Clone()
+			clientConfig.GetConfigForClient = nil
+			clientConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			clientConfig.ClientCAs = r.certPool()
+			return clientConfig, nil
+		}
+	}
+	return config
+}
+
+// ClientConfig returns the TLS config for dialing serverName. The server is verified against the
+// CA if there is one, and the system roots otherwise, and the reloader's certificate is offered
+// to servers that ask for one. The CA is read when the config is built, not on every handshake.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		RootCAs:    r.certPool(),
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.certificate(), nil
+		},
+	}
+}
+
+// Watch reloads the certificates whenever their files change, until ctx is done. It watches the
+// directories rather than the files, since Kubernetes updates mounted secrets by swapping symlinks.
+func (r *Reloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("cannot watch certificates: %w", err)
+	}
+	defer watcher.Close()
+
+	dirs := map[string]bool{}
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file != "" {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("cannot watch %s: %w", dir, err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			// A rotation writes several files, so a reload can see a new certificate with the old
+			// key. That attempt fails and keeps the old pair, and the next event picks up both.
+			if err := r.Reload(); err != nil {
+				log.Warn().Err(err).Msg("cannot reload certificates")
+				continue
+			}
+			log.Info().Msg("reloaded certificates")
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Warn().Err(err).Msg("certificate watcher error")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a PEM certificate and key for localhost, usable by servers and clients.
+func (ca *testCA) issue(t *testing.T, serial int64) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func newTestReloader(t *testing.T) (*Reloader, *testCA, string) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, 2)
+	writeFile(t, filepath.Join(dir, "tls.crt"), certPEM)
+	writeFile(t, filepath.Join(dir, "tls.key"), keyPEM)
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.pem)
+
+	reloader, err := NewReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt"))
+	require.NoError(t, err)
+	return reloader, ca, dir
+}
+
+// handshake connects a client with clientConfig to a server with serverConfig.
+func handshake(t *testing.T, serverConfig, clientConfig *tls.Config) error {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// With TLS 1.3 the server's verdict on the client certificate arrives after the handshake.
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return nil
+	}
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func TestMutualTLS(t *testing.T) {
+	reloader, _, _ := newTestReloader(t)
+	require.True(t, reloader.MutualTLS())
+
+	err := handshake(t, reloader.ServerConfig(true), reloader.ClientConfig("localhost"))
+	require.NoError(t, err)
+
+	// A client that verifies the server but has no certificate of its own is refused.
+	noCert := reloader.ClientConfig("localhost")
+	noCert.GetClientCertificate = nil
+	err = handshake(t, reloader.ServerConfig(true), noCert)
+	require.Error(t, err)
+
+	// Without client verification the same client gets in.
+	err = handshake(t, reloader.ServerConfig(false), noCert)
+	require.NoError(t, err)
+}
+
+func TestReloaderWatch(t *testing.T) {
+	reloader, ca, dir := newTestReloader(t)
+	before := reloader.certificate()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- reloader.Watch(ctx) }()
+
+	// Give the watcher a moment to start before rotating the files.
+	time.Sleep(100 * time.Millisecond)
+	certPEM, keyPEM := ca.issue(t, 3)
+	writeFile(t, filepath.Join(dir, "tls.key"), keyPEM)
+	writeFile(t, filepath.Join(dir, "tls.crt"), certPEM)
+
+	require.Eventually(t, func() bool {
+		return reloader.certificate() != before
+	}, 5*time.Second, 20*time.Millisecond)
+
+	leaf, err := x509.ParseCertificate(reloader.certificate().Certificate[0])
+	require.NoError(t, err)
+	require.Equal(t, int64(3), leaf.SerialNumber.Int64())
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestReloadKeepsOldCertificate(t *testing.T) {
+	reloader, _, dir := newTestReloader(t)
+	before := reloader.certificate()
+
+	writeFile(t, filepath.Join(dir, "tls.crt"), []byte("not a certificate"))
+	require.Error(t, reloader.Reload())
+	require.Same(t, before, reloader.certificate())
+}
@@ -0,0 +1,2 @@
+ALTER TABLE IF exists "api_keys" DROP CONSTRAINT IF exists "api_keys_username_fkey";
+DROP TABLE IF exists "api_keys";
@@ -0,0 +1,22 @@
+CREATE TABLE "api_keys" (
+  "id" uuid PRIMARY KEY NOT NULL,
+  "username" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "prefix" varchar UNIQUE NOT NULL,
+  "hashed_secret" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "expires_at" timestamptz,
+  "last_used_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "api_keys" ("username");
+
+COMMENT ON COLUMN "api_keys"."prefix" IS 'Stored in clear to look the key up';
+
+COMMENT ON COLUMN "api_keys"."hashed_secret" IS 'SHA-256 of the secret part of the key';
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "api_keys" ADD CONSTRAINT "username_name_key" UNIQUE("username", "name");
@@ -0,0 +1,4 @@
+DROP TABLE IF exists "audit_log";
+DROP FUNCTION IF exists "audit_log_append_only";
+ALTER TABLE IF exists "users" DROP CONSTRAINT IF exists "users_role_check";
+ALTER TABLE IF exists "users" DROP COLUMN IF exists "role";
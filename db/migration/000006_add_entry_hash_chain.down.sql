@@ -0,0 +1,6 @@
+DROP INDEX IF exists "entries_account_id_id_idx";
+ALTER TABLE IF exists "entries" DROP CONSTRAINT IF exists "entries_transfer_id_fkey";
+ALTER TABLE IF exists "entries" DROP COLUMN IF exists "hash";
+ALTER TABLE IF exists "entries" DROP COLUMN IF exists "prev_hash";
+ALTER TABLE IF exists "entries" DROP COLUMN IF exists "balance";
+ALTER TABLE IF exists "entries" DROP COLUMN IF exists "transfer_id";
@@ -0,0 +1,17 @@
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD COLUMN "balance" bigint;
+
+ALTER TABLE "entries" ADD COLUMN "prev_hash" bytea;
+
+ALTER TABLE "entries" ADD COLUMN "hash" bytea;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("account_id", "id");
+
+COMMENT ON COLUMN "entries"."balance" IS 'Balance of the account after the entry';
+
+COMMENT ON COLUMN "entries"."prev_hash" IS 'Hash of the previous entry of the account; all zeros for the first one';
+
+COMMENT ON COLUMN "entries"."hash" IS 'HMAC-SHA256, keyed with LEDGER_KEY, over the simplebank/entry/v2 domain, the entry, its transfer and prev_hash. NULL for entries made before the chain existed';
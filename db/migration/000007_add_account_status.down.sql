@@ -0,0 +1,3 @@
+ALTER TABLE IF exists "accounts" DROP COLUMN IF exists "status_changed_at";
+ALTER TABLE IF exists "accounts" DROP CONSTRAINT IF exists "accounts_status_check";
+ALTER TABLE IF exists "accounts" DROP COLUMN IF exists "status";
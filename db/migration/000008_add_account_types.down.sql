@@ -0,0 +1,8 @@
+-- Fails if an owner has several accounts in one currency; close and merge them first.
+DROP INDEX IF exists "accounts_nickname_key";
+DROP INDEX IF exists "accounts_default_key";
+ALTER TABLE IF exists "accounts" DROP COLUMN IF exists "is_default";
+ALTER TABLE IF exists "accounts" DROP COLUMN IF exists "nickname";
+ALTER TABLE IF exists "accounts" DROP CONSTRAINT IF exists "accounts_type_check";
+ALTER TABLE IF exists "accounts" DROP COLUMN IF exists "type";
+ALTER TABLE IF exists "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
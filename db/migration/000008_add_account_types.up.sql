@@ -0,0 +1,18 @@
+ALTER TABLE "accounts" DROP CONSTRAINT IF exists "owner_currency_key";
+
+ALTER TABLE "accounts" ADD COLUMN "type" varchar NOT NULL DEFAULT 'checking';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_type_check" CHECK ("type" IN ('checking', 'savings'));
+
+ALTER TABLE "accounts" ADD COLUMN "nickname" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "accounts" ADD COLUMN "is_default" boolean NOT NULL DEFAULT false;
+
+-- Owners had a single account per currency so far, which becomes their default one.
+UPDATE "accounts" SET "is_default" = true;
+
+CREATE UNIQUE INDEX "accounts_default_key" ON "accounts" ("owner", "currency") WHERE "is_default";
+
+CREATE UNIQUE INDEX "accounts_nickname_key" ON "accounts" ("owner", "nickname") WHERE "nickname" <> '';
+
+COMMENT ON COLUMN "accounts"."is_default" IS 'The account that receives transfers sent to the owner in its currency';
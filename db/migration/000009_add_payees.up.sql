@@ -0,0 +1,25 @@
+CREATE TABLE "payees" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "label" varchar NOT NULL,
+  "account_id" bigint,
+  "username" varchar,
+  "currency" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "payees" ("owner");
+
+ALTER TABLE "payees" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "payees" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "payees" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "payees" ADD CONSTRAINT "payees_owner_label_key" UNIQUE ("owner", "label");
+
+ALTER TABLE "payees" ADD CONSTRAINT "payees_target_check" CHECK (("account_id" IS NULL) <> ("username" IS NULL));
+
+COMMENT ON COLUMN "payees"."username" IS 'Transfers go to the default account of the user in the currency';
+
+COMMENT ON COLUMN "payees"."currency" IS 'Used by transfers to the payee that name no currency';
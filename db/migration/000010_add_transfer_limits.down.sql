@@ -0,0 +1,2 @@
+DROP INDEX IF exists "transfers_from_account_created_at_idx";
+DROP TABLE IF exists "transfer_limits";
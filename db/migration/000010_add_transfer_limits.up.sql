@@ -0,0 +1,33 @@
+CREATE TABLE "transfer_limits" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar,
+  "account_id" bigint,
+  "currency" varchar NOT NULL,
+  "single_max" bigint,
+  "daily_max" bigint,
+  "monthly_max" bigint,
+  "set_by" varchar NOT NULL,
+  "reason" varchar NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("set_by") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_limits" ADD CONSTRAINT "transfer_limits_username_currency_key" UNIQUE ("username", "currency");
+
+ALTER TABLE "transfer_limits" ADD CONSTRAINT "transfer_limits_account_id_key" UNIQUE ("account_id");
+
+ALTER TABLE "transfer_limits" ADD CONSTRAINT "transfer_limits_target_check" CHECK (("username" IS NULL) <> ("account_id" IS NULL));
+
+ALTER TABLE "transfer_limits" ADD CONSTRAINT "transfer_limits_max_check" CHECK ("single_max" >= 0 AND "daily_max" >= 0 AND "monthly_max" >= 0);
+
+-- Sums of what left an account since a point in time.
+CREATE INDEX ON "transfers" ("from_account", "created_at");
+
+COMMENT ON TABLE "transfer_limits" IS 'Limits set by bankers, replacing the default ones of a user or adding some to an account';
+
+COMMENT ON COLUMN "transfer_limits"."single_max" IS 'NULL keeps the default limit, 0 lifts it';
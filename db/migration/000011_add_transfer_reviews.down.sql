@@ -0,0 +1 @@
+DROP TABLE IF exists "transfer_reviews";
@@ -0,0 +1,25 @@
+CREATE TABLE "transfer_reviews" (
+  "id" bigserial PRIMARY KEY,
+  "from_account" bigint NOT NULL,
+  "to_account" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "requested_by" varchar NOT NULL,
+  "client_ip" varchar NOT NULL DEFAULT '',
+  "findings" jsonb NOT NULL DEFAULT '[]',
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "transfer_reviews" ("status", "created_at");
+
+ALTER TABLE "transfer_reviews" ADD FOREIGN KEY ("from_account") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_reviews" ADD FOREIGN KEY ("to_account") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_reviews" ADD FOREIGN KEY ("requested_by") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_reviews" ADD CONSTRAINT "transfer_reviews_amount_check" CHECK ("amount" > 0);
+
+COMMENT ON TABLE "transfer_reviews" IS 'Transfers the risk rules parked until a banker looks at them';
+
+COMMENT ON COLUMN "transfer_reviews"."findings" IS 'The risk rules the transfer matched, and why';
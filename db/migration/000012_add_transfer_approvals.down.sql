@@ -0,0 +1,22 @@
+DROP TABLE IF exists "notifications";
+
+-- Pending transfers never posted: they go back to being reviews that hold nothing.
+UPDATE "accounts" SET "held" = 0;
+
+ALTER TABLE "transfer_reviews" DROP CONSTRAINT IF exists "transfer_reviews_status_check";
+
+ALTER TABLE "transfer_reviews" DROP COLUMN IF exists "transfer_id";
+
+ALTER TABLE "transfer_reviews" DROP COLUMN IF exists "expires_at";
+
+ALTER TABLE "transfer_reviews" DROP COLUMN IF exists "decided_by";
+
+ALTER TABLE "transfer_reviews" DROP COLUMN IF exists "decided_at";
+
+ALTER TABLE "transfer_reviews" DROP COLUMN IF exists "reason";
+
+DELETE FROM "transfers" WHERE "status" <> 'posted';
+
+ALTER TABLE "accounts" DROP COLUMN IF exists "held";
+
+ALTER TABLE "transfers" DROP COLUMN IF exists "status";
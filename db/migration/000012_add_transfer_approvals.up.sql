@@ -0,0 +1,69 @@
+ALTER TABLE "transfers" ADD COLUMN "status" varchar NOT NULL DEFAULT 'posted';
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_status_check" CHECK ("status" IN ('pending', 'posted', 'rejected', 'expired'));
+
+COMMENT ON COLUMN "transfers"."status" IS 'pending transfers hold their amount on the sender''s account until a banker posts or rejects them';
+
+ALTER TABLE "accounts" ADD COLUMN "held" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_held_check" CHECK ("held" >= 0);
+
+COMMENT ON COLUMN "accounts"."held" IS 'Money set aside for pending transfers, that other transfers can''t spend';
+
+ALTER TABLE "transfer_reviews" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "transfer_reviews" ADD COLUMN "expires_at" timestamptz;
+
+ALTER TABLE "transfer_reviews" ADD COLUMN "decided_by" varchar;
+
+ALTER TABLE "transfer_reviews" ADD COLUMN "decided_at" timestamptz;
+
+ALTER TABLE "transfer_reviews" ADD COLUMN "reason" varchar NOT NULL DEFAULT '';
+
+-- Reviews parked so far didn't hold anything: they become pending transfers holding their amount.
+DO $$
+DECLARE
+  review record;
+  pending_id bigint;
+BEGIN
+  FOR review IN SELECT * FROM "transfer_reviews" WHERE "status" = 'pending' ORDER BY "id" LOOP
+    INSERT INTO "transfers" ("from_account", "to_account", "amount", "status", "created_at")
+    VALUES (review.from_account, review.to_account, review.amount, 'pending', review.created_at)
+    RETURNING "id" INTO pending_id;
+    UPDATE "transfer_reviews" SET "transfer_id" = pending_id WHERE "id" = review.id;
+    UPDATE "accounts" SET "held" = "held" + review.amount WHERE "id" = review.from_account;
+  END LOOP;
+END $$;
+
+UPDATE "transfer_reviews" SET "expires_at" = "created_at" + interval '3 days';
+
+ALTER TABLE "transfer_reviews" ALTER COLUMN "transfer_id" SET NOT NULL;
+
+ALTER TABLE "transfer_reviews" ALTER COLUMN "expires_at" SET NOT NULL;
+
+ALTER TABLE "transfer_reviews" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfer_reviews" ADD FOREIGN KEY ("decided_by") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_reviews" ADD CONSTRAINT "transfer_reviews_transfer_id_key" UNIQUE ("transfer_id");
+
+ALTER TABLE "transfer_reviews" ADD CONSTRAINT "transfer_reviews_status_check" CHECK ("status" IN ('pending', 'approved', 'rejected', 'expired'));
+
+CREATE INDEX ON "transfer_reviews" ("expires_at") WHERE "status" = 'pending';
+
+COMMENT ON COLUMN "transfer_reviews"."reason" IS 'Why the banker approved or rejected the transfer';
+
+CREATE TABLE "notifications" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "kind" varchar NOT NULL,
+  "message" varchar NOT NULL,
+  "transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "notifications" ("username", "id");
+
+ALTER TABLE "notifications" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "notifications" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,39 @@
+-- The bank's accounts hold the other side of the interest already paid, and of any other money
+-- that went through them, which can't be undone.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM "interest_postings" WHERE "transfer_id" IS NOT NULL) THEN
+    RAISE EXCEPTION 'interest was paid from the bank''s accounts';
+  END IF;
+  IF EXISTS (
+    SELECT 1 FROM "transfers"
+    JOIN "system_accounts" ON "system_accounts"."account_id" IN ("transfers"."from_account", "transfers"."to_account")
+  ) OR EXISTS (
+    SELECT 1 FROM "transfer_reviews"
+    JOIN "system_accounts" ON "system_accounts"."account_id" IN ("transfer_reviews"."from_account", "transfer_reviews"."to_account")
+  ) OR EXISTS (
+    SELECT 1 FROM "entries"
+    JOIN "system_accounts" ON "system_accounts"."account_id" = "entries"."account_id"
+  ) THEN
+    RAISE EXCEPTION 'transfers went through the bank''s accounts';
+  END IF;
+END $$;
+
+DROP TABLE IF exists "interest_accruals";
+
+DROP TABLE IF exists "interest_postings";
+
+DROP TABLE IF exists "interest_rates";
+
+-- The bank's accounts can only go once system_accounts no longer refers to them. They are all
+-- the accounts the bank owns, now that 000014 is down.
+DROP TABLE IF exists "system_accounts";
+
+DELETE FROM "payees"
+WHERE "username" = 'simple-bank' OR "account_id" IN (SELECT "id" FROM "accounts" WHERE "owner" = 'simple-bank');
+
+DELETE FROM "transfer_limits" WHERE "account_id" IN (SELECT "id" FROM "accounts" WHERE "owner" = 'simple-bank');
+
+DELETE FROM "accounts" WHERE "owner" = 'simple-bank';
+
+DELETE FROM "users" WHERE "username" = 'simple-bank';
@@ -0,0 +1,87 @@
+-- The bank owns the accounts its own money goes through. The username can't be registered and
+-- the password hash matches no password, so nobody can log in as the bank.
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email")
+VALUES ('simple-bank', '!', 'Simple Bank', 'system@simple-bank.invalid');
+
+CREATE TABLE "system_accounts" (
+  "purpose" varchar NOT NULL,
+  "currency" varchar NOT NULL,
+  "account_id" bigint UNIQUE NOT NULL,
+  PRIMARY KEY ("purpose", "currency")
+);
+
+ALTER TABLE "system_accounts" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "system_accounts" ADD CONSTRAINT "system_accounts_purpose_check" CHECK ("purpose" IN ('interest'));
+
+COMMENT ON TABLE "system_accounts" IS 'Accounts of the bank itself, one per purpose and currency';
+
+WITH "created" AS (
+  INSERT INTO "accounts" ("owner", "balance", "currency", "nickname")
+  SELECT 'simple-bank', 0, "currency", 'interest ' || "currency"
+  FROM unnest(ARRAY['USD', 'EUR', 'CAD']) AS "currency"
+  RETURNING "id", "currency"
+)
+INSERT INTO "system_accounts" ("purpose", "currency", "account_id")
+SELECT 'interest', "currency", "id" FROM "created";
+
+CREATE TABLE "interest_rates" (
+  "account_type" varchar PRIMARY KEY,
+  "annual_rate_bps" integer NOT NULL,
+  "set_by" varchar,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "interest_rates" ADD FOREIGN KEY ("set_by") REFERENCES "users" ("username");
+
+ALTER TABLE "interest_rates" ADD CONSTRAINT "interest_rates_account_type_check" CHECK ("account_type" IN ('checking', 'savings'));
+
+ALTER TABLE "interest_rates" ADD CONSTRAINT "interest_rates_rate_check" CHECK ("annual_rate_bps" BETWEEN 0 AND 10000);
+
+COMMENT ON COLUMN "interest_rates"."annual_rate_bps" IS 'Yearly rate in basis points: 250 is 2.5%';
+
+INSERT INTO "interest_rates" ("account_type", "annual_rate_bps") VALUES ('checking', 0), ('savings', 200);
+
+CREATE TABLE "interest_postings" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "month" date NOT NULL,
+  "accrued" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "carry" bigint NOT NULL,
+  "transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "interest_postings" ("account_id");
+
+CREATE INDEX ON "interest_postings" ("month");
+
+COMMENT ON COLUMN "interest_postings"."accrued" IS 'The accruals posted plus the carry of the previous posting, in accrual units';
+
+COMMENT ON COLUMN "interest_postings"."amount" IS 'What was paid into the account: accrued divided by 3650000, in the currency''s minor unit';
+
+COMMENT ON COLUMN "interest_postings"."carry" IS 'The remainder of the division, carried to the next posting';
+
+CREATE TABLE "interest_accruals" (
+  "account_id" bigint NOT NULL,
+  "accrual_date" date NOT NULL,
+  "balance" bigint NOT NULL,
+  "annual_rate_bps" integer NOT NULL,
+  "amount" bigint NOT NULL,
+  "posting_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "accrual_date")
+);
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("posting_id") REFERENCES "interest_postings" ("id");
+
+CREATE INDEX ON "interest_accruals" ("accrual_date") WHERE "posting_id" IS NULL;
+
+COMMENT ON COLUMN "interest_accruals"."amount" IS 'balance × annual_rate_bps: the interest of the day in units of 1/3650000 of the currency''s minor unit';
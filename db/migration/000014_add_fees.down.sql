@@ -0,0 +1,24 @@
+-- The fees account holds the other side of the fees already charged, which can't be undone.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM "transfers" WHERE "type" = 'fee') THEN
+    RAISE EXCEPTION 'fees were charged to the bank''s accounts';
+  END IF;
+END $$;
+
+DROP TABLE IF exists "fee_schedules";
+
+ALTER TABLE "transfer_reviews" DROP COLUMN IF exists "fee";
+
+ALTER TABLE "transfers" DROP COLUMN IF exists "fee_for";
+
+ALTER TABLE "transfers" DROP COLUMN IF exists "type";
+
+DELETE FROM "system_accounts" WHERE "purpose" IN ('fees', 'suspense');
+
+DELETE FROM "accounts"
+WHERE "owner" = 'simple-bank' AND "id" NOT IN (SELECT "account_id" FROM "system_accounts");
+
+ALTER TABLE "system_accounts" DROP CONSTRAINT "system_accounts_purpose_check";
+
+ALTER TABLE "system_accounts" ADD CONSTRAINT "system_accounts_purpose_check" CHECK ("purpose" IN ('interest'));
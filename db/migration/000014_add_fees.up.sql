@@ -0,0 +1,60 @@
+ALTER TABLE "system_accounts" DROP CONSTRAINT "system_accounts_purpose_check";
+
+ALTER TABLE "system_accounts" ADD CONSTRAINT "system_accounts_purpose_check" CHECK ("purpose" IN ('interest', 'fees', 'suspense'));
+
+COMMENT ON COLUMN "system_accounts"."purpose" IS 'interest pays savers, fees collects transfer fees, suspense parks money the bank can''t place yet';
+
+WITH "created" AS (
+  INSERT INTO "accounts" ("owner", "balance", "currency", "nickname")
+  SELECT 'simple-bank', 0, "currency", "purpose" || ' ' || "currency"
+  FROM unnest(ARRAY['fees', 'suspense']) AS "purpose", unnest(ARRAY['USD', 'EUR', 'CAD']) AS "currency"
+  RETURNING "id", "currency", "nickname"
+)
+INSERT INTO "system_accounts" ("purpose", "currency", "account_id")
+SELECT split_part("nickname", ' ', 1), "currency", "id" FROM "created";
+
+ALTER TABLE "transfers" ADD COLUMN "type" varchar;
+
+UPDATE "transfers" SET "type" = CASE
+  WHEN "from_account" IN (SELECT "account_id" FROM "system_accounts" WHERE "purpose" = 'interest') THEN 'interest'
+  WHEN (SELECT "owner" FROM "accounts" WHERE "id" = "from_account") = (SELECT "owner" FROM "accounts" WHERE "id" = "to_account") THEN 'internal'
+  ELSE 'external'
+END;
+
+ALTER TABLE "transfers" ALTER COLUMN "type" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_type_check" CHECK ("type" IN ('internal', 'external', 'fee', 'interest'));
+
+COMMENT ON COLUMN "transfers"."type" IS 'internal between the accounts of one owner, external to someone else''s, or fee and interest, to and from the bank';
+
+ALTER TABLE "transfers" ADD COLUMN "fee_for" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("fee_for") REFERENCES "transfers" ("id");
+
+COMMENT ON COLUMN "transfers"."fee_for" IS 'The transfer a fee was charged for';
+
+ALTER TABLE "transfer_reviews" ADD COLUMN "fee" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "transfer_reviews"."fee" IS 'The fee held with the amount, charged if the transfer is approved';
+
+CREATE TABLE "fee_schedules" (
+  "currency" varchar NOT NULL,
+  "transfer_type" varchar NOT NULL,
+  "kind" varchar NOT NULL,
+  "value" bigint NOT NULL,
+  "set_by" varchar NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("currency", "transfer_type")
+);
+
+ALTER TABLE "fee_schedules" ADD FOREIGN KEY ("set_by") REFERENCES "users" ("username");
+
+ALTER TABLE "fee_schedules" ADD CONSTRAINT "fee_schedules_transfer_type_check" CHECK ("transfer_type" IN ('internal', 'external'));
+
+ALTER TABLE "fee_schedules" ADD CONSTRAINT "fee_schedules_value_check" CHECK (
+  ("kind" = 'flat' AND "value" >= 0) OR ("kind" = 'percentage' AND "value" BETWEEN 0 AND 10000)
+);
+
+COMMENT ON TABLE "fee_schedules" IS 'What the bank charges the sender of a transfer, per currency and type of transfer';
+
+COMMENT ON COLUMN "fee_schedules"."value" IS 'Minor units for flat fees, basis points of the amount for percentage fees';
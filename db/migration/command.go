@@ -0,0 +1,93 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// Usage describes the arguments RunCommand accepts.
+const Usage = "migrate up [N] | down [N] | version | force VERSION"
+
+// Migrator is the part of *migrate.Migrate that RunCommand uses.
+type Migrator interface {
+	Up() error
+	Steps(n int) error
+	Version() (version uint, dirty bool, err error)
+	Force(version int) error
+}
+
+// RunCommand runs one migrate subcommand against m and writes its result to out. "up" without a
+// count applies every pending migration; "down" without a count rolls back only the latest one.
+func RunCommand(m Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: %s", Usage)
+	}
+
+	var err error
+	switch args[0] {
+	case "up":
+		if len(args) == 1 {
+			err = m.Up()
+			break
+		}
+		var n int
+		if n, err = parseCount(args[1]); err == nil {
+			err = m.Steps(n)
+		}
+	case "down":
+		n := 1
+		if len(args) == 2 {
+			if n, err = parseCount(args[1]); err != nil {
+				return err
+			}
+		}
+		err = m.Steps(-n)
+	case "version":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: %s", Usage)
+		}
+		version, dirty, verr := m.Version()
+		if errors.Is(verr, migrate.ErrNilVersion) {
+			fmt.Fprintln(out, "no migration has been applied")
+			return nil
+		}
+		if verr != nil {
+			return verr
+		}
+		if dirty {
+			fmt.Fprintf(out, "%d (dirty)\n", version)
+			return nil
+		}
+		fmt.Fprintln(out, version)
+		return nil
+	case "force":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s", Usage)
+		}
+		version, perr := strconv.Atoi(args[1])
+		if perr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = m.Force(version)
+	default:
+		return fmt.Errorf("unknown migrate command %q, usage: %s", args[0], Usage)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Fprintln(out, "no change")
+		return nil
+	}
+	return err
+}
+
+func parseCount(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid migration count %q", arg)
+	}
+	return n, nil
+}
@@ -0,0 +1,159 @@
+package migration
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMigrator records the calls RunCommand makes.
+type fakeMigrator struct {
+	calls   []string
+	steps   int
+	forced  int
+	version uint
+	dirty   bool
+	err     error
+}
+
+func (m *fakeMigrator) Up() error {
+	m.calls = append(m.calls, "up")
+	return m.err
+}
+
+func (m *fakeMigrator) Steps(n int) error {
+	m.calls = append(m.calls, "steps")
+	m.steps = n
+	return m.err
+}
+
+func (m *fakeMigrator) Version() (uint, bool, error) {
+	m.calls = append(m.calls, "version")
+	return m.version, m.dirty, m.err
+}
+
+func (m *fakeMigrator) Force(version int) error {
+	m.calls = append(m.calls, "force")
+	m.forced = version
+	return m.err
+}
+
+func TestRunCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		migrator *fakeMigrator
+		check    func(t *testing.T, m *fakeMigrator, out string, err error)
+	}{
+		{
+			name:     "UpAll",
+			args:     []string{"up"},
+			migrator: &fakeMigrator{},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []string{"up"}, m.calls)
+			},
+		},
+		{
+			name:     "UpSteps",
+			args:     []string{"up", "2"},
+			migrator: &fakeMigrator{},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 2, m.steps)
+			},
+		},
+		{
+			name:     "UpNoChange",
+			args:     []string{"up"},
+			migrator: &fakeMigrator{err: migrate.ErrNoChange},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "no change\n", out)
+			},
+		},
+		{
+			name:     "DownDefaultsToOne",
+			args:     []string{"down"},
+			migrator: &fakeMigrator{},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, -1, m.steps)
+			},
+		},
+		{
+			name:     "DownSteps",
+			args:     []string{"down", "3"},
+			migrator: &fakeMigrator{},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, -3, m.steps)
+			},
+		},
+		{
+			name:     "InvalidCount",
+			args:     []string{"down", "-3"},
+			migrator: &fakeMigrator{},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.Error(t, err)
+				require.Empty(t, m.calls)
+			},
+		},
+		{
+			name:     "Version",
+			args:     []string{"version"},
+			migrator: &fakeMigrator{version: 4, dirty: true},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "4 (dirty)\n", out)
+			},
+		},
+		{
+			name:     "NoVersion",
+			args:     []string{"version"},
+			migrator: &fakeMigrator{err: migrate.ErrNilVersion},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "no migration has been applied\n", out)
+			},
+		},
+		{
+			name:     "Force",
+			args:     []string{"force", "3"},
+			migrator: &fakeMigrator{},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 3, m.forced)
+			},
+		},
+		{
+			name:     "ForceWithoutVersion",
+			args:     []string{"force"},
+			migrator: &fakeMigrator{},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.Error(t, err)
+				require.Empty(t, m.calls)
+			},
+		},
+		{
+			name:     "UnknownCommand",
+			args:     []string{"drop"},
+			migrator: &fakeMigrator{},
+			check: func(t *testing.T, m *fakeMigrator, out string, err error) {
+				require.Error(t, err)
+				require.Empty(t, m.calls)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := RunCommand(tc.migrator, tc.args, &out)
+			tc.check(t, tc.migrator, out.String(), err)
+		})
+	}
+}
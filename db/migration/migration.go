@@ -0,0 +1,86 @@
+// Package migration embeds the schema migrations in the binary and applies them.
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion returns the version of the newest migration in fsys.
+func LatestVersion(fsys fs.FS) (uint, error) {
+	names, err := fs.Glob(fsys, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, name := range names {
+		prefix, _, found := strings.Cut(name, "_")
+		if !found {
+			return 0, fmt.Errorf("migration %s has no version prefix", name)
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has an invalid version: %w", name, err)
+		}
+		latest = max(latest, uint(version))
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("no migrations found")
+	}
+	return latest, nil
+}
+
+// New returns a migrator that applies the embedded migrations to the database at dbSource. The
+// postgres driver holds a Postgres advisory lock while it migrates, so replicas that start together
+// take turns instead of racing.
+func New(dbSource string) (*migrate.Migrate, error) {
+	source, err := iofs.New(FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", source, dbSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create migrator: %w", err)
+	}
+	m.Log = logger{}
+	return m, nil
+}
+
+// Up applies every pending migration.
+func Up(dbSource string) error {
+	m, err := New(dbSource)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Info().Msg("database schema is up to date")
+		return nil
+	}
+	return err
+}
+
+// logger sends migrate's progress to the application log.
+type logger struct{}
+
+func (logger) Printf(format string, v ...interface{}) {
+	log.Info().Msg(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (logger) Verbose() bool {
+	return false
+}
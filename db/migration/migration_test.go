@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestVersion(t *testing.T) {
+	version, err := LatestVersion(fstest.MapFS{
+		"000001_init_schema.up.sql":   {},
+		"000001_init_schema.down.sql": {},
+		"000012_add_fees.up.sql":      {},
+		"000012_add_fees.down.sql":    {},
+		"000003_add_sessions.up.sql":  {},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint(12), version)
+
+	_, err = LatestVersion(fstest.MapFS{})
+	require.Error(t, err)
+
+	_, err = LatestVersion(fstest.MapFS{"init.up.sql": {}})
+	require.Error(t, err)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	version, err := LatestVersion(FS)
+	require.NoError(t, err)
+	require.NotZero(t, version)
+}
@@ -1,8 +1,10 @@
 -- name: CreateAccount :one
+-- The first account of an owner in a currency becomes the default one.
 INSERT INTO accounts (
-  owner, balance, currency
+  owner, balance, currency, type, nickname, is_default
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5,
+  NOT EXISTS (SELECT 1 FROM accounts a WHERE a.owner = $1 AND a.currency = $3 AND a.is_default)
 )
 RETURNING *;
 
@@ -15,23 +17,68 @@ SELECT * FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE;
 
+-- name: GetDefaultAccount :one
+SELECT * FROM accounts
+WHERE owner = $1 AND currency = $2 AND is_default
+LIMIT 1;
+
 -- name: ListAccounts :many
 SELECT * FROM accounts
 WHERE accounts.owner = $1
 ORDER BY id LIMIT $2 OFFSET $3;
 
--- name: UpdateAccount :one
+-- name: AddAccountBalance :one
 UPDATE accounts
-  set balance = $2
-WHERE id = $1
+  set balance = balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
 RETURNING *;
 
--- name: AddAccountBalance :one
+-- name: AddAccountHeld :one
 UPDATE accounts
-  set balance = balance + sqlc.arg(amount)
+  set held = held + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
 -- name: DeleteAccount :exec
 DELETE FROM accounts
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: UpdateAccountStatus :one
+UPDATE accounts
+  set status = sqlc.arg(status),
+      status_changed_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetPayeeAccount :one
+-- The account that receives transfers sent to a user addressed by username or email.
+SELECT accounts.id, accounts.owner, accounts.status, users.full_name
+FROM accounts
+JOIN users ON users.username = accounts.owner
+WHERE (users.username = sqlc.narg(username) OR users.email = sqlc.narg(email))
+  AND accounts.currency = sqlc.arg(currency)
+  AND accounts.is_default
+LIMIT 1;
+
+-- name: UpdateAccountNickname :one
+UPDATE accounts
+  set nickname = sqlc.arg(nickname)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ClearDefaultAccount :exec
+UPDATE accounts
+  set is_default = false
+WHERE owner = $1 AND currency = $2 AND is_default;
+
+-- name: MakeDefaultAccount :one
+UPDATE accounts
+  set is_default = true
+WHERE id = $1
+RETURNING *;
+
+-- name: ListAccountIDs :many
+SELECT id FROM accounts
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(page_limit);
\ No newline at end of file
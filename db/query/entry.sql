@@ -1,8 +1,8 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
-  account_id, amount
+  account_id, amount, transfer_id, balance, prev_hash, hash, created_at
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4, $5, $6, $7
 )
 RETURNING *;
 
@@ -15,4 +15,29 @@ SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY id 
 LIMIT $2 
-OFFSET $3;
\ No newline at end of file
+OFFSET $3;
+
+-- name: GetLastEntryHash :one
+SELECT hash FROM entries
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ListLedgerEntries :many
+SELECT
+  entries.id,
+  entries.account_id,
+  entries.amount,
+  entries.transfer_id,
+  entries.balance,
+  entries.prev_hash,
+  entries.hash,
+  entries.created_at,
+  COALESCE(transfers.from_account, 0)::bigint AS transfer_from_account,
+  COALESCE(transfers.to_account, 0)::bigint AS transfer_to_account,
+  COALESCE(transfers.amount, 0)::bigint AS transfer_amount
+FROM entries
+LEFT JOIN transfers ON transfers.id = entries.transfer_id
+WHERE entries.account_id = sqlc.arg(account_id) AND entries.id > sqlc.arg(after_id)
+ORDER BY entries.id
+LIMIT sqlc.arg(page_limit);
\ No newline at end of file
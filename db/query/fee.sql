@@ -0,0 +1,28 @@
+-- name: GetFeeSchedule :one
+SELECT * FROM fee_schedules
+WHERE currency = $1 AND transfer_type = $2 LIMIT 1;
+
+-- name: ListFeeSchedules :many
+SELECT * FROM fee_schedules
+ORDER BY currency, transfer_type;
+
+-- name: SetFeeSchedule :one
+INSERT INTO fee_schedules (
+  currency,
+  transfer_type,
+  kind,
+  value,
+  set_by
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (currency, transfer_type) DO UPDATE
+  set kind = EXCLUDED.kind,
+      value = EXCLUDED.value,
+      set_by = EXCLUDED.set_by,
+      updated_at = now()
+RETURNING *;
+
+-- name: DeleteFeeSchedule :execrows
+DELETE FROM fee_schedules
+WHERE currency = $1 AND transfer_type = $2;
@@ -0,0 +1,111 @@
+-- name: ListInterestRates :many
+SELECT * FROM interest_rates
+ORDER BY account_type;
+
+-- name: GetInterestRate :one
+SELECT * FROM interest_rates
+WHERE account_type = $1 LIMIT 1;
+
+-- name: SetInterestRate :one
+UPDATE interest_rates
+  set annual_rate_bps = sqlc.arg(annual_rate_bps),
+      set_by = sqlc.arg(set_by),
+      updated_at = now()
+WHERE account_type = sqlc.arg(account_type)
+RETURNING *;
+
+-- name: GetSystemAccount :one
+SELECT * FROM accounts
+WHERE id = (
+  SELECT account_id FROM system_accounts
+  WHERE purpose = sqlc.arg(purpose) AND currency = sqlc.arg(currency)
+)
+LIMIT 1;
+
+-- name: AccrueInterest :execrows
+-- Records a day of interest on the active accounts that had money at the end of the day and whose
+-- type earns some. The balance at the end of the day is the balance now less the entries made
+-- since, so that money moved later doesn't change the interest of the day. Accounts opened after
+-- the day and the bank's own don't earn any; days already accrued are left alone.
+INSERT INTO interest_accruals (
+  account_id, accrual_date, balance, annual_rate_bps, amount
+)
+SELECT accounts.id, sqlc.arg(accrual_date)::date, day_end.balance, interest_rates.annual_rate_bps,
+  day_end.balance * interest_rates.annual_rate_bps
+FROM accounts
+JOIN interest_rates ON interest_rates.account_type = accounts.type
+CROSS JOIN LATERAL (
+  SELECT accounts.balance - COALESCE(SUM(entries.amount), 0)::bigint AS balance
+  FROM entries
+  WHERE entries.account_id = accounts.id AND entries.created_at >= sqlc.arg(day_end)
+) AS day_end
+WHERE accounts.status = 'active' AND day_end.balance > 0 AND interest_rates.annual_rate_bps > 0
+  AND accounts.created_at < sqlc.arg(day_end)
+  AND NOT EXISTS (SELECT 1 FROM system_accounts WHERE system_accounts.account_id = accounts.id)
+ON CONFLICT (account_id, accrual_date) DO NOTHING;
+
+-- name: GetLastAccrualDate :one
+-- The last day up to until that interest was accrued for, or since if there is none.
+SELECT COALESCE(max(accrual_date), sqlc.arg(since)::date)::date AS last_accrual_date
+FROM interest_accruals
+WHERE accrual_date <= sqlc.arg(until)::date;
+
+-- name: ListUnpostedInterestAccounts :many
+-- The active accounts with interest accrued before a date that wasn't posted yet.
+SELECT DISTINCT interest_accruals.account_id
+FROM interest_accruals
+JOIN accounts ON accounts.id = interest_accruals.account_id
+WHERE interest_accruals.posting_id IS NULL AND interest_accruals.accrual_date < sqlc.arg(before)
+  AND accounts.status = 'active'
+ORDER BY interest_accruals.account_id
+LIMIT sqlc.arg(page_limit);
+
+-- name: SumUnpostedInterest :one
+SELECT
+  COUNT(*)::bigint AS days,
+  COALESCE(SUM(amount), 0)::bigint AS accrued
+FROM interest_accruals
+WHERE account_id = sqlc.arg(account_id) AND posting_id IS NULL AND accrual_date < sqlc.arg(before);
+
+-- name: GetLastInterestPosting :one
+SELECT * FROM interest_postings
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: CreateInterestPosting :one
+INSERT INTO interest_postings (
+  account_id, month, accrued, amount, carry, transfer_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: MarkInterestPosted :execrows
+UPDATE interest_accruals
+  set posting_id = sqlc.arg(posting_id)
+WHERE account_id = sqlc.arg(account_id) AND posting_id IS NULL AND accrual_date < sqlc.arg(before);
+
+-- name: SumInterestAccrued :many
+-- Interest accrued over the days of a month per currency, and the part of it not posted yet.
+SELECT
+  accounts.currency,
+  COUNT(DISTINCT interest_accruals.account_id)::bigint AS accounts,
+  COALESCE(SUM(interest_accruals.amount), 0)::bigint AS accrued,
+  COALESCE(SUM(interest_accruals.amount) FILTER (WHERE interest_accruals.posting_id IS NULL), 0)::bigint AS unposted
+FROM interest_accruals
+JOIN accounts ON accounts.id = interest_accruals.account_id
+WHERE interest_accruals.accrual_date >= sqlc.arg(month_start) AND interest_accruals.accrual_date < sqlc.arg(month_end)
+GROUP BY accounts.currency
+ORDER BY accounts.currency;
+
+-- name: SumInterestPosted :many
+-- Interest posted for a month per currency.
+SELECT
+  accounts.currency,
+  COALESCE(SUM(interest_postings.amount), 0)::bigint AS posted
+FROM interest_postings
+JOIN accounts ON accounts.id = interest_postings.account_id
+WHERE interest_postings.month = sqlc.arg(month)
+GROUP BY accounts.currency
+ORDER BY accounts.currency;
@@ -0,0 +1,17 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (
+  username,
+  kind,
+  message,
+  transfer_id
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: ListNotifications :many
+SELECT * FROM notifications
+WHERE username = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
@@ -0,0 +1,33 @@
+-- name: CreatePayee :one
+INSERT INTO payees (
+  owner,
+  label,
+  account_id,
+  username,
+  currency
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetPayee :one
+SELECT * FROM payees
+WHERE id = $1 AND owner = $2 LIMIT 1;
+
+-- name: ListPayees :many
+SELECT * FROM payees
+WHERE owner = $1
+ORDER BY label
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdatePayee :one
+UPDATE payees
+  set label = COALESCE(sqlc.narg(label), label),
+      currency = COALESCE(sqlc.narg(currency), currency)
+WHERE id = sqlc.arg(id) AND owner = sqlc.arg(owner)
+RETURNING *;
+
+-- name: DeletePayee :execrows
+DELETE FROM payees
+WHERE id = $1 AND owner = $2;
@@ -14,4 +14,19 @@ RETURNING *;
 
 -- name: GetSession :one
 SELECT * FROM sessions
-WHERE id = $1 LIMIT 1;
\ No newline at end of file
+WHERE id = $1 LIMIT 1;
+
+-- name: BlockSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1
+RETURNING *;
+
+-- name: CountSessionIPs :one
+-- When the user first logged in from an IP address, now if they never did, and how many of their
+-- sessions came from other addresses.
+SELECT
+  COALESCE(min(created_at) FILTER (WHERE client_ip = sqlc.arg(client_ip)::varchar), now())::timestamptz AS first_seen,
+  count(*) FILTER (WHERE client_ip IS DISTINCT FROM sqlc.arg(client_ip)::varchar) AS others
+FROM sessions
+WHERE username = sqlc.arg(username);
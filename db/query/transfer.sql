@@ -2,12 +2,44 @@
 INSERT INTO transfers (
   from_account, 
   to_account, 
-  amount
+  amount,
+  type
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4
 )
 RETURNING *;
 
+-- name: CreatePendingTransfer :one
+-- A transfer that holds its amount until a banker posts or rejects it.
+INSERT INTO transfers (
+  from_account,
+  to_account,
+  amount,
+  type,
+  status
+) VALUES (
+  $1, $2, $3, $4, 'pending'
+)
+RETURNING *;
+
+-- name: CreateFeeTransfer :one
+INSERT INTO transfers (
+  from_account,
+  to_account,
+  amount,
+  type,
+  fee_for
+) VALUES (
+  $1, $2, $3, 'fee', $4
+)
+RETURNING *;
+
+-- name: UpdateTransferStatus :one
+UPDATE transfers
+  set status = sqlc.arg(status)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
@@ -19,4 +51,19 @@ WHERE
     to_account = $2
 ORDER BY id
 LIMIT $3
-OFFSET $4;
\ No newline at end of file
+OFFSET $4;
+
+-- name: CountUserTransfersSince :one
+SELECT count(*) FROM transfers
+JOIN accounts ON accounts.id = transfers.from_account
+WHERE accounts.owner = sqlc.arg(owner)
+  AND transfers.created_at >= sqlc.arg(since)
+  AND transfers.type IN ('internal', 'external');
+
+-- name: CountUserTransfersTo :one
+SELECT count(*) FROM transfers
+JOIN accounts ON accounts.id = transfers.from_account
+WHERE accounts.owner = sqlc.arg(owner)
+  AND transfers.to_account = sqlc.arg(to_account)
+  AND transfers.status = 'posted'
+  AND transfers.type IN ('internal', 'external');
@@ -0,0 +1,72 @@
+-- name: GetUserTransferLimit :one
+SELECT * FROM transfer_limits
+WHERE username = $1 AND currency = $2 LIMIT 1;
+
+-- name: GetAccountTransferLimit :one
+SELECT * FROM transfer_limits
+WHERE account_id = $1 LIMIT 1;
+
+-- name: SetUserTransferLimit :one
+INSERT INTO transfer_limits (
+  username,
+  currency,
+  single_max,
+  daily_max,
+  monthly_max,
+  set_by,
+  reason
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (username, currency) DO UPDATE
+  set single_max = EXCLUDED.single_max,
+      daily_max = EXCLUDED.daily_max,
+      monthly_max = EXCLUDED.monthly_max,
+      set_by = EXCLUDED.set_by,
+      reason = EXCLUDED.reason,
+      updated_at = now()
+RETURNING *;
+
+-- name: SetAccountTransferLimit :one
+INSERT INTO transfer_limits (
+  account_id,
+  currency,
+  single_max,
+  daily_max,
+  monthly_max,
+  set_by,
+  reason
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (account_id) DO UPDATE
+  set single_max = EXCLUDED.single_max,
+      daily_max = EXCLUDED.daily_max,
+      monthly_max = EXCLUDED.monthly_max,
+      set_by = EXCLUDED.set_by,
+      reason = EXCLUDED.reason,
+      updated_at = now()
+RETURNING *;
+
+-- name: SumUserTransfers :one
+-- What the user sent from their accounts in the currency, or is held to leave them, since the start of the daily and monthly windows.
+SELECT
+  COALESCE(SUM(transfers.amount) FILTER (WHERE transfers.created_at >= sqlc.arg(day_start)), 0)::bigint AS daily,
+  COALESCE(SUM(transfers.amount) FILTER (WHERE transfers.created_at >= sqlc.arg(month_start)), 0)::bigint AS monthly
+FROM transfers
+JOIN accounts ON accounts.id = transfers.from_account
+WHERE accounts.owner = sqlc.arg(owner) AND accounts.currency = sqlc.arg(currency)
+  AND transfers.created_at >= LEAST(sqlc.arg(day_start)::timestamptz, sqlc.arg(month_start)::timestamptz)
+  AND transfers.status IN ('pending', 'posted')
+  AND transfers.type IN ('internal', 'external');
+
+-- name: SumAccountTransfers :one
+-- What was sent from the account, or is held to leave it, since the start of the daily and monthly windows.
+SELECT
+  COALESCE(SUM(amount) FILTER (WHERE created_at >= sqlc.arg(day_start)), 0)::bigint AS daily,
+  COALESCE(SUM(amount) FILTER (WHERE created_at >= sqlc.arg(month_start)), 0)::bigint AS monthly
+FROM transfers
+WHERE from_account = sqlc.arg(account_id)
+  AND created_at >= LEAST(sqlc.arg(day_start)::timestamptz, sqlc.arg(month_start)::timestamptz)
+  AND status IN ('pending', 'posted')
+  AND type IN ('internal', 'external');
@@ -0,0 +1,49 @@
+-- name: CreateTransferReview :one
+INSERT INTO transfer_reviews (
+  transfer_id,
+  from_account,
+  to_account,
+  amount,
+  requested_by,
+  client_ip,
+  findings,
+  expires_at,
+  fee
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: GetTransferReview :one
+SELECT * FROM transfer_reviews
+WHERE id = $1 LIMIT 1;
+
+-- name: GetTransferReviewForUpdate :one
+SELECT * FROM transfer_reviews
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListTransferReviews :many
+-- The review queue, oldest first, with the currency of the transfers.
+SELECT transfer_reviews.*, accounts.currency
+FROM transfer_reviews
+JOIN accounts ON accounts.id = transfer_reviews.from_account
+WHERE transfer_reviews.status = sqlc.arg(status)
+ORDER BY transfer_reviews.id
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
+
+-- name: ListExpiredTransferReviews :many
+SELECT * FROM transfer_reviews
+WHERE status = 'pending' AND expires_at <= sqlc.arg(now)
+ORDER BY id
+LIMIT sqlc.arg(page_limit);
+
+-- name: DecideTransferReview :one
+UPDATE transfer_reviews
+  set status = sqlc.arg(status),
+      decided_by = sqlc.narg(decided_by),
+      decided_at = now(),
+      reason = sqlc.arg(reason)
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,34 @@
+package db
+
+import "context"
+
+// SetDefaultAccountTx makes an account the one that receives transfers sent to its owner in its
+// currency, in place of the previous default account.
+func (store *SQLStore) SetDefaultAccountTx(ctx context.Context, accountID int64) (Account, error) {
+	var result Account
+	err := store.execTx(ctx, "SetDefaultAccountTx", func(ctx context.Context, q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+		if err = checkActive(account); err != nil {
+			return err
+		}
+		if account.IsDefault {
+			result = account
+			return nil
+		}
+
+		// The previous default goes first: an owner has at most one default account per currency.
+		err = q.ClearDefaultAccount(ctx, ClearDefaultAccountParams{
+			Owner:    account.Owner,
+			Currency: account.Currency,
+		})
+		if err != nil {
+			return err
+		}
+		result, err = q.MakeDefaultAccount(ctx, account.ID)
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Statuses of an account. Accounts with entries and transfers aren't deleted, so that those stay
+// in the ledger; they are closed instead.
+const (
+	AccountActive = "active"
+	AccountFrozen = "frozen"
+	AccountClosed = "closed"
+)
+
+var (
+	// ErrAccountNotActive is returned when a transfer would move money in or out of an account
+	// that is frozen or closed.
+	ErrAccountNotActive = errors.New("account is not active")
+	// ErrStatusTransition is returned for a status change the lifecycle doesn't allow.
+	ErrStatusTransition = errors.New("account status can't change that way")
+	// ErrBalanceNotZero is returned when closing an account that still holds money.
+	ErrBalanceNotZero = errors.New("account balance is not zero")
+)
+
+// statusTransitions lists the statuses each status can change to.
+var statusTransitions = map[string][]string{
+	AccountActive: {AccountFrozen, AccountClosed},
+	AccountFrozen: {AccountActive, AccountClosed},
+	AccountClosed: {AccountActive},
+}
+
+// CanChangeStatus reports whether an account can go from one status to another.
+func CanChangeStatus(from, to string) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+func checkActive(account Account) error {
+	if account.Status != AccountActive {
+		return fmt.Errorf("account %d is %s: %w", account.ID, account.Status, ErrAccountNotActive)
+	}
+	return nil
+}
+
+type SetAccountStatusTxParams struct {
+	AccountID int64
+	Status    string
+	// SweepToAccountID, when closing, receives what is left on the account first. It must be
+	// active, as must the account being closed.
+	SweepToAccountID int64
+	// Audit, when set, is written to the audit log in the same transaction, completed with the
+	// account ID and its state before and after.
+	Audit *CreateAuditLogParams
+}
+
+type SetAccountStatusTxResult struct {
+	Account Account `json:"account"`
+	// Sweep is the transfer of the balance, when closing swept one.
+	Sweep *TransferTxResults `json:"sweep,omitempty"`
+}
+
+// accountState is the state of an account recorded in the audit log for a status change.
+type accountState struct {
+	Status  string `json:"status"`
+	Balance int64  `json:"balance"`
+}
+
+// SetAccountStatusTx moves an account to another status. Closing needs a zero balance, unless the
+// balance is swept to another account in the same transaction.
+func (store *SQLStore) SetAccountStatusTx(ctx context.Context, arg SetAccountStatusTxParams) (SetAccountStatusTxResult, error) {
+	var result SetAccountStatusTxResult
+	err := store.execTx(ctx, "SetAccountStatusTx", func(ctx context.Context, q *Queries) error {
+		// The lock keeps transfers from changing the balance until the status has changed.
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		before := accountState{Status: account.Status, Balance: account.Balance}
+		if !CanChangeStatus(account.Status, arg.Status) {
+			return fmt.Errorf("account %d is %s and can't become %s: %w", account.ID, account.Status, arg.Status, ErrStatusTransition)
+		}
+
+		if arg.Status == AccountClosed {
+			if arg.SweepToAccountID != 0 && account.Balance > 0 {
+				sweep, err := store.transfer(ctx, q, &TransferTxParams{
+					FromAccountID: account.ID,
+					ToAccountID:   arg.SweepToAccountID,
+					Amount:        account.Balance,
+				})
+				if err != nil {
+					return err
+				}
+				result.Sweep = &sweep
+				account = sweep.FromAccount
+			}
+			if account.Balance != 0 {
+				return fmt.Errorf("account %d holds %d: %w", account.ID, account.Balance, ErrBalanceNotZero)
+			}
+			// Payees addressed by username or email would otherwise resolve to the closed account.
+			if account.IsDefault {
+				err = q.ClearDefaultAccount(ctx, ClearDefaultAccountParams{Owner: account.Owner, Currency: account.Currency})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		result.Account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:     account.ID,
+			Status: arg.Status,
+		})
+		if err != nil {
+			return err
+		}
+		if arg.Audit == nil {
+			return nil
+		}
+
+		entry := *arg.Audit
+		entry.ResourceID = strconv.FormatInt(account.ID, 10)
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return err
+		}
+		if entry.After, err = json.Marshal(accountState{Status: result.Account.Status, Balance: result.Account.Balance}); err != nil {
+			return err
+		}
+		_, err = q.CreateAuditLog(ctx, entry)
+		return err
+	})
+	return result, err
+}
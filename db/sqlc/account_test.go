@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 
 	"github.com/ashokmouli/simplebank/db/util"
@@ -15,6 +16,7 @@ func TestCreateAccount(t *testing.T) {
 		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Type:     util.Checking,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), args)
@@ -37,6 +39,7 @@ func makeAccount() Account {
 		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Type:     util.Checking,
 	}
 
 	account, _ := testQueries.CreateAccount(context.Background(), args)
@@ -91,13 +94,13 @@ func TestListAccounts(t *testing.T) {
 	*/
 }
 
-func TestUpdateAccounts(t *testing.T) {
+func TestAddAccountBalance(t *testing.T) {
 	test_account := makeAccount()
-	arg := UpdateAccountParams{
-		ID:      test_account.ID,
-		Balance: test_account.Balance + 100,
+	arg := AddAccountBalanceParams{
+		ID:     test_account.ID,
+		Amount: 100,
 	}
-	account, err := testQueries.UpdateAccount(context.Background(), arg)
+	account, err := testQueries.AddAccountBalance(context.Background(), arg)
 	require.NoError(t, err)
 	require.NotEmpty(t, account)
 	require.Equal(t, test_account.Owner, account.Owner)
@@ -115,3 +118,94 @@ func TestDeleteAccount(t *testing.T) {
 	require.NotEmpty(t, err)
 	require.Empty(t, account)
 }
+
+func TestUpdateAccountStatus(t *testing.T) {
+	test_account := makeAccount()
+	require.Equal(t, AccountActive, test_account.Status)
+
+	account, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     test_account.ID,
+		Status: AccountFrozen,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountFrozen, account.Status)
+	require.True(t, account.StatusChangedAt.After(test_account.StatusChangedAt))
+
+	_, err = testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     test_account.ID,
+		Status: "deleted",
+	})
+	require.Error(t, err)
+}
+
+func TestDefaultAccount(t *testing.T) {
+	user := makeUser()
+	arg := CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.USD,
+		Type:     util.Checking,
+	}
+
+	// The first account in a currency becomes the default one, later ones don't.
+	first, err := testQueries.CreateAccount(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, first.IsDefault)
+
+	arg.Type = util.Savings
+	arg.Nickname = "Rainy day"
+	second, err := testQueries.CreateAccount(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, second.IsDefault)
+	require.Equal(t, util.Savings, second.Type)
+
+	// Nicknames are unique per owner.
+	_, err = testQueries.CreateAccount(context.Background(), arg)
+	require.Error(t, err)
+
+	store := NewStore(testDB, testLedgerKey)
+	updated, err := store.SetDefaultAccountTx(context.Background(), second.ID)
+	require.NoError(t, err)
+	require.True(t, updated.IsDefault)
+
+	account, err := testQueries.GetDefaultAccount(context.Background(), GetDefaultAccountParams{
+		Owner:    user.Username,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, second.ID, account.ID)
+
+	first, err = testQueries.GetAccount(context.Background(), first.ID)
+	require.NoError(t, err)
+	require.False(t, first.IsDefault)
+}
+
+func TestGetPayeeAccount(t *testing.T) {
+	user := makeUser()
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.EUR,
+		Type:     util.Checking,
+	})
+	require.NoError(t, err)
+
+	byUsername, err := testQueries.GetPayeeAccount(context.Background(), GetPayeeAccountParams{
+		Username: sql.NullString{String: user.Username, Valid: true},
+		Currency: util.EUR,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.ID, byUsername.ID)
+	require.Equal(t, user.FullName, byUsername.FullName)
+
+	byEmail, err := testQueries.GetPayeeAccount(context.Background(), GetPayeeAccountParams{
+		Email:    sql.NullString{String: user.Email, Valid: true},
+		Currency: util.EUR,
+	})
+	require.NoError(t, err)
+	require.Equal(t, byUsername, byEmail)
+
+	_, err = testQueries.GetPayeeAccount(context.Background(), GetPayeeAccountParams{
+		Username: sql.NullString{String: user.Username, Valid: true},
+		Currency: util.CAD,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func makeAPIKey(t *testing.T, user User) APIKey {
+	args := CreateAPIKeyParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		Name:         util.RandomString(6),
+		Prefix:       util.RandomString(8),
+		HashedSecret: util.RandomString(64),
+		Scopes:       []string{"accounts:read"},
+	}
+
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), args)
+	require.NoError(t, err)
+	require.Equal(t, args.ID, apiKey.ID)
+	require.Equal(t, args.Username, apiKey.Username)
+	require.Equal(t, args.Prefix, apiKey.Prefix)
+	require.Equal(t, args.Scopes, apiKey.Scopes)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.False(t, apiKey.RevokedAt.Valid)
+	return apiKey
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	apiKey := makeAPIKey(t, makeUser())
+
+	got, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, got.ID)
+	require.Equal(t, apiKey.HashedSecret, got.HashedSecret)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	apiKey := makeAPIKey(t, makeUser())
+
+	err := testQueries.TouchAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+
+	got, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.NoError(t, err)
+	require.True(t, got.LastUsedAt.Valid)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	user := makeUser()
+	apiKey := makeAPIKey(t, user)
+
+	// Someone else can't revoke the key.
+	_, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:       apiKey.ID,
+		Username: util.RandomOwner(),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	revoked, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:       apiKey.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	// Revoking twice is reported as not found.
+	_, err = testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:       apiKey.ID,
+		Username: user.Username,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	apiKeys, err := testQueries.ListAPIKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/stretchr/testify/require"
+)
+
+func makeAuditLog(t *testing.T, actor string, action string) AuditLog {
+	entry, err := testQueries.CreateAuditLog(context.Background(), CreateAuditLogParams{
+		Actor:        actor,
+		Action:       action,
+		Outcome:      "success",
+		ResourceType: "account",
+		ResourceID:   strconv.FormatInt(util.RandomInt(1, 1000), 10),
+		ClientIp:     "10.0.0.1",
+		UserAgent:    "test",
+		Before:       json.RawMessage(`{}`),
+		After:        json.RawMessage(`{"balance": 10}`),
+	})
+	require.NoError(t, err)
+	return entry
+}
+
+func TestCreateAuditLog(t *testing.T) {
+	actor := util.RandomOwner()
+	entry := makeAuditLog(t, actor, "account.create")
+
+	require.NotZero(t, entry.ID)
+	require.Equal(t, actor, entry.Actor)
+	require.Equal(t, "10.0.0.1", entry.ClientIp)
+	require.JSONEq(t, `{"balance": 10}`, string(entry.After))
+	require.WithinDuration(t, time.Now(), entry.OccurredAt, time.Minute)
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	entry := makeAuditLog(t, util.RandomOwner(), "account.create")
+
+	_, err := testDB.Exec(`UPDATE audit_log SET actor = 'someone' WHERE id = $1`, entry.ID)
+	require.Error(t, err)
+	_, err = testDB.Exec(`DELETE FROM audit_log WHERE id = $1`, entry.ID)
+	require.Error(t, err)
+}
+
+func TestListAuditLog(t *testing.T) {
+	actor := util.RandomOwner()
+	first := makeAuditLog(t, actor, "account.create")
+	second := makeAuditLog(t, actor, "transfer.create")
+	makeAuditLog(t, util.RandomOwner(), "transfer.create")
+
+	entries, err := testQueries.ListAuditLog(context.Background(), ListAuditLogParams{
+		Actor:     sql.NullString{String: actor, Valid: true},
+		PageLimit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	// Newest first.
+	require.Equal(t, second.ID, entries[0].ID)
+	require.Equal(t, first.ID, entries[1].ID)
+
+	entries, err = testQueries.ListAuditLog(context.Background(), ListAuditLogParams{
+		Actor:     sql.NullString{String: actor, Valid: true},
+		Action:    sql.NullString{String: "transfer.create", Valid: true},
+		Since:     sql.NullTime{Time: first.OccurredAt, Valid: true},
+		PageLimit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, second.ID, entries[0].ID)
+}
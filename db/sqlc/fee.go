@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Kinds of fee.
+const (
+	// FeeFlat charges the schedule's value, in minor units, whatever the amount.
+	FeeFlat = "flat"
+	// FeePercentage charges the schedule's value in basis points of the amount.
+	FeePercentage = "percentage"
+)
+
+// Fee returns what the schedule charges for a transfer of amount. Percentages are rounded to the
+// nearest minor unit, halves up.
+func (f FeeSchedule) Fee(amount int64) int64 {
+	switch f.Kind {
+	case FeeFlat:
+		return f.Value
+	case FeePercentage:
+		return (amount*f.Value + 5000) / 10000
+	}
+	return 0
+}
+
+// FeeCharge is the fee charged for a transfer, posted from the sender's account to the bank's
+// fees account as a transfer of its own.
+type FeeCharge struct {
+	Transfer  Transfer `json:"transfer"`
+	FromEntry Entry    `json:"fromEntry"`
+	ToEntry   Entry    `json:"toEntry"`
+}
+
+// typeOfTransfer tells transfers between the accounts of one owner from those to someone else.
+// Transfers to or from the bank's own accounts have no type: they are refused with
+// ErrSystemAccount.
+func typeOfTransfer(ctx context.Context, q *Queries, fromAccountID int64, toAccountID int64) (string, error) {
+	from, err := q.GetAccount(ctx, fromAccountID)
+	if err != nil {
+		return "", err
+	}
+	to, err := q.GetAccount(ctx, toAccountID)
+	if err != nil {
+		return "", err
+	}
+	for _, account := range []Account{from, to} {
+		if account.Owner == BankOwner {
+			return "", fmt.Errorf("account %d: %w", account.ID, ErrSystemAccount)
+		}
+	}
+	if from.Owner == to.Owner {
+		return TransferInternal, nil
+	}
+	return TransferExternal, nil
+}
+
+// feeFor returns the fee of a transfer of amount in currency and of transferType. Transfers
+// without a fee schedule are free.
+func feeFor(ctx context.Context, q *Queries, currency string, transferType string, amount int64) (int64, error) {
+	schedule, err := q.GetFeeSchedule(ctx, GetFeeScheduleParams{
+		Currency:     currency,
+		TransferType: transferType,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return schedule.Fee(amount), nil
+}
+
+// chargeFee charges the sender of a posted transfer the fee of its currency and type, within the
+// transaction of q, and adds it to result.
+func (store *SQLStore) chargeFee(ctx context.Context, q *Queries, result *TransferTxResults) error {
+	amount, err := feeFor(ctx, q, result.FromAccount.Currency, result.Transfer.Type, result.Transfer.Amount)
+	if err != nil {
+		return err
+	}
+	return store.postFee(ctx, q, result, amount)
+}
+
+// postFee posts a fee of amount from the sender of a posted transfer to the bank's fees account,
+// within the transaction of q, and adds it to result.
+//
+// The fees account is locked last, after the sender and recipient rows the transfer locked, rather
+// than in ID order with them. That can't deadlock because customers can't transfer to or from the
+// bank's accounts (see ErrSystemAccount), so no transaction holding a fees account waits for
+// another account. Every paid transfer in a currency still waits on the one fees row from its fee
+// to its commit; postFee is the last write to an account in both TransferTx and
+// DecideTransferReviewTx to keep that wait short.
+func (store *SQLStore) postFee(ctx context.Context, q *Queries, result *TransferTxResults, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+	fees, err := q.GetSystemAccount(ctx, GetSystemAccountParams{
+		Purpose:  SystemFees,
+		Currency: result.FromAccount.Currency,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot find the %s fees account: %w", result.FromAccount.Currency, err)
+	}
+	t, err := q.CreateFeeTransfer(ctx, CreateFeeTransferParams{
+		FromAccount: result.Transfer.FromAccount,
+		ToAccount:   fees.ID,
+		Amount:      amount,
+		FeeFor:      sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	posted, err := store.post(ctx, q, t)
+	if err != nil {
+		return err
+	}
+	result.FromAccount = posted.FromAccount
+	result.Fee = &FeeCharge{
+		Transfer:  posted.Transfer,
+		FromEntry: posted.FromEntry,
+		ToEntry:   posted.ToEntry,
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeScheduleFee(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schedule FeeSchedule
+		amount   int64
+		fee      int64
+	}{
+		{"Flat", FeeSchedule{Kind: FeeFlat, Value: 25}, 1000, 25},
+		{"Percentage", FeeSchedule{Kind: FeePercentage, Value: 150}, 1000, 15},
+		{"RoundsHalfUp", FeeSchedule{Kind: FeePercentage, Value: 50}, 100, 1},
+		{"RoundsDown", FeeSchedule{Kind: FeePercentage, Value: 49}, 100, 0},
+		{"UnknownKind", FeeSchedule{Kind: "tiered", Value: 25}, 1000, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.fee, tc.schedule.Fee(tc.amount))
+		})
+	}
+}
+
+func TestSystemAccounts(t *testing.T) {
+	for _, purpose := range []string{SystemInterest, SystemFees, SystemSuspense} {
+		for _, currency := range util.SupportedCurrencies {
+			account, err := testQueries.GetSystemAccount(context.Background(), GetSystemAccountParams{
+				Purpose:  purpose,
+				Currency: currency,
+			})
+			require.NoError(t, err, "%s %s", purpose, currency)
+			require.Equal(t, BankOwner, account.Owner)
+			require.Equal(t, currency, account.Currency)
+		}
+	}
+}
+
+func makeCADAccount(t *testing.T, owner string) Account {
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    owner,
+		Balance:  1000,
+		Currency: util.CAD,
+		Type:     util.Checking,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestTransferTxFee(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	ctx := context.Background()
+	banker := makeUser()
+	_, err := store.SetFeeSchedule(ctx, SetFeeScheduleParams{
+		Currency:     util.CAD,
+		TransferType: TransferExternal,
+		Kind:         FeePercentage,
+		Value:        100,
+		SetBy:        banker.Username,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.DeleteFeeSchedule(ctx, DeleteFeeScheduleParams{Currency: util.CAD, TransferType: TransferExternal})
+	})
+
+	fees, err := store.GetSystemAccount(ctx, GetSystemAccountParams{Purpose: SystemFees, Currency: util.CAD})
+	require.NoError(t, err)
+	owner := makeUser().Username
+	from := makeCADAccount(t, owner)
+	to := makeCADAccount(t, makeUser().Username)
+
+	result, err := store.TransferTx(ctx, &TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 500})
+	require.NoError(t, err)
+	require.Equal(t, TransferExternal, result.Transfer.Type)
+	require.NotNil(t, result.Fee)
+	require.Equal(t, int64(5), result.Fee.Transfer.Amount)
+	require.Equal(t, TransferFee, result.Fee.Transfer.Type)
+	require.Equal(t, result.Transfer.ID, result.Fee.Transfer.FeeFor.Int64)
+	require.Equal(t, fees.ID, result.Fee.Transfer.ToAccount)
+	require.Equal(t, fees.ID, result.Fee.ToEntry.AccountID)
+	require.Equal(t, int64(-5), result.Fee.FromEntry.Amount)
+	require.Equal(t, from.Balance-500-5, result.FromAccount.Balance)
+	require.Equal(t, to.Balance+500, result.ToAccount.Balance)
+
+	// The fee doesn't count towards the sender's limits.
+	now := time.Now()
+	sent, err := store.SumAccountTransfers(ctx, SumAccountTransfersParams{
+		DayStart:   now.Add(-time.Hour),
+		MonthStart: now.Add(-time.Hour),
+		AccountID:  from.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(500), sent.Daily)
+
+	// Transfers between the owner's own accounts have no schedule, and are free.
+	own := makeCADAccount(t, owner)
+	result, err = store.TransferTx(ctx, &TransferTxParams{FromAccountID: from.ID, ToAccountID: own.ID, Amount: 100})
+	require.NoError(t, err)
+	require.Equal(t, TransferInternal, result.Transfer.Type)
+	require.Nil(t, result.Fee)
+	require.Equal(t, from.Balance-500-5-100, result.FromAccount.Balance)
+}
+
+func TestTransferTxHoldFee(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	ctx := context.Background()
+	banker := makeUser()
+	setFee := func(value int64) {
+		_, err := store.SetFeeSchedule(ctx, SetFeeScheduleParams{
+			Currency:     util.CAD,
+			TransferType: TransferExternal,
+			Kind:         FeeFlat,
+			Value:        value,
+			SetBy:        banker.Username,
+		})
+		require.NoError(t, err)
+	}
+	setFee(10)
+	t.Cleanup(func() {
+		store.DeleteFeeSchedule(ctx, DeleteFeeScheduleParams{Currency: util.CAD, TransferType: TransferExternal})
+	})
+
+	from := makeCADAccount(t, makeUser().Username)
+	to := makeCADAccount(t, makeUser().Username)
+
+	// The fee is held with the amount, so the balance can't cover both.
+	_, err := store.TransferTx(ctx, &TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        from.Balance,
+		Review:        &CreateTransferReviewParams{RequestedBy: from.Owner, Findings: json.RawMessage(`[]`), ExpiresAt: time.Now().Add(time.Hour)},
+	})
+	require.ErrorIs(t, err, ErrFundsHeld)
+
+	held := holdTransfer(t, store, from, to, from.Balance-10, time.Now().Add(time.Hour))
+	require.Equal(t, from.Balance, held.FromAccount.Held)
+	require.Equal(t, int64(10), held.Review.Fee)
+
+	// The sender pays the fee that was held, not the one of the day.
+	setFee(50)
+	result, err := store.DecideTransferReviewTx(ctx, DecideTransferReviewTxParams{
+		ReviewID:  held.Review.ID,
+		Status:    ReviewApproved,
+		DecidedBy: banker.Username,
+		Now:       time.Now(),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result.Transfer.Fee)
+	require.Equal(t, int64(10), result.Transfer.Fee.Transfer.Amount)
+
+	account, err := store.GetAccount(ctx, from.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.Held)
+	require.Zero(t, account.Balance)
+}
+
+func TestTransferTxToFeesAccount(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	ctx := context.Background()
+	_, err := store.SetFeeSchedule(ctx, SetFeeScheduleParams{
+		Currency:     util.CAD,
+		TransferType: TransferExternal,
+		Kind:         FeeFlat,
+		Value:        1,
+		SetBy:        makeUser().Username,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.DeleteFeeSchedule(ctx, DeleteFeeScheduleParams{Currency: util.CAD, TransferType: TransferExternal})
+	})
+
+	fees, err := store.GetSystemAccount(ctx, GetSystemAccountParams{Purpose: SystemFees, Currency: util.CAD})
+	require.NoError(t, err)
+	from := makeCADAccount(t, makeUser().Username)
+	to := makeCADAccount(t, makeUser().Username)
+	// The fees account was made by a migration, before any customer's.
+	require.Less(t, fees.ID, from.ID)
+
+	// Transfers straight to the fees account would lock it before the sender, while the
+	// fee-charged transfers lock it after: they are refused instead of deadlocking.
+	errs := make(chan error)
+	const n = 10
+	for i := 0; i < n; i++ {
+		arg := &TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 10}
+		if i%2 == 1 {
+			arg.ToAccountID = fees.ID
+		}
+		go func() {
+			_, err := store.TransferTx(ctx, arg)
+			errs <- err
+		}()
+	}
+	refused := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if errors.Is(err, ErrSystemAccount) {
+			refused++
+			continue
+		}
+		require.NoError(t, err)
+	}
+	require.Equal(t, n/2, refused)
+
+	account, err := store.GetAccount(ctx, from.ID)
+	require.NoError(t, err)
+	require.Equal(t, from.Balance-n/2*(10+1), account.Balance)
+
+	// Nor can the bank's accounts send money.
+	_, err = store.TransferTx(ctx, &TransferTxParams{FromAccountID: fees.ID, ToAccountID: to.ID, Amount: 1})
+	require.ErrorIs(t, err, ErrSystemAccount)
+}
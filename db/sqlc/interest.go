@@ -0,0 +1,263 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// InterestDenominator is what accruals are divided by to get minor units of the currency. A day of
+// interest is balance × rate in basis points / 10000 / 365: accruals keep the numerator, so that
+// they add up exactly, and postings divide once, carrying the remainder to the next posting.
+const InterestDenominator = 10000 * 365
+
+// InterestDay returns the start of the UTC day of t, which is how accrual dates are kept.
+func InterestDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// InterestMonth returns the first day of the UTC month of t, which is how postings are kept.
+func InterestMonth(t time.Time) time.Time {
+	year, month, _ := t.UTC().Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// AccrueInterest records the interest of a day on every account that earns some, at its balance
+// at the end of the day and its type's rate now, and returns how many accounts it accrued on.
+// Running it again for the same day accrues nothing more.
+func AccrueInterest(ctx context.Context, q Querier, day time.Time) (int64, error) {
+	day = InterestDay(day)
+	return q.AccrueInterest(ctx, AccrueInterestParams{
+		AccrualDate: day,
+		DayEnd:      day.AddDate(0, 0, 1),
+	})
+}
+
+// AccrueInterestUntil accrues every day after the last one accrued up to until, oldest first, so
+// that the days missed while nothing accrued are caught up. When no day was ever accrued, it
+// starts with until. It returns how many accruals it recorded.
+func AccrueInterestUntil(ctx context.Context, q Querier, until time.Time) (int64, error) {
+	until = InterestDay(until)
+	last, err := q.GetLastAccrualDate(ctx, GetLastAccrualDateParams{
+		Since: until.AddDate(0, 0, -1),
+		Until: until,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var accrued int64
+	for day := InterestDay(last).AddDate(0, 0, 1); !day.After(until); day = day.AddDate(0, 0, 1) {
+		n, err := AccrueInterest(ctx, q, day)
+		if err != nil {
+			return accrued, fmt.Errorf("cannot accrue the interest of %s: %w", day.Format("2006-01-02"), err)
+		}
+		accrued += n
+	}
+	return accrued, nil
+}
+
+type PostInterestTxParams struct {
+	AccountID int64
+	// Month is the first day of the month posted. The accruals of its days, and those of earlier
+	// days that weren't posted, are paid together.
+	Month time.Time
+	// Audit, when set, is written to the audit log in the same transaction, completed with the
+	// posting ID and the posting.
+	Audit *CreateAuditLogParams
+}
+
+type PostInterestTxResult struct {
+	// Posting is zero when the account had nothing left to post.
+	Posting InterestPosting `json:"posting"`
+	// Transfer pays the posting from the bank's interest account. It is zero when the interest
+	// accrued is still less than a minor unit, which is carried to the next posting.
+	Transfer TransferTxResults `json:"transfer"`
+}
+
+// PostInterestTx pays the interest an account accrued up to the end of a month from the bank's
+// interest account in its currency.
+func (store *SQLStore) PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error) {
+	var result PostInterestTxResult
+	err := store.execTx(ctx, "PostInterestTx", func(ctx context.Context, q *Queries) error {
+		month := InterestMonth(arg.Month)
+		before := month.AddDate(0, 1, 0)
+		account, err := q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		source, err := q.GetSystemAccount(ctx, GetSystemAccountParams{
+			Purpose:  SystemInterest,
+			Currency: account.Currency,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot find the %s interest account: %w", account.Currency, err)
+		}
+		// Lock both accounts in ID order, as transfers do, before reading what is left to post.
+		// The lock on the saver's account keeps another run from posting the same accruals.
+		first, second := account.ID, source.ID
+		if second < first {
+			first, second = second, first
+		}
+		if _, err = q.GetAccountForUpdate(ctx, first); err != nil {
+			return err
+		}
+		if _, err = q.GetAccountForUpdate(ctx, second); err != nil {
+			return err
+		}
+		unposted, err := q.SumUnpostedInterest(ctx, SumUnpostedInterestParams{
+			AccountID: account.ID,
+			Before:    before,
+		})
+		if err != nil || unposted.Days == 0 {
+			return err
+		}
+
+		var carry int64
+		last, err := q.GetLastInterestPosting(ctx, account.ID)
+		if err == nil {
+			carry = last.Carry
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		posting := CreateInterestPostingParams{
+			AccountID: account.ID,
+			Month:     month,
+			Accrued:   unposted.Accrued + carry,
+		}
+		posting.Amount = posting.Accrued / InterestDenominator
+		posting.Carry = posting.Accrued % InterestDenominator
+
+		if posting.Amount > 0 {
+			t, err := q.CreateTransfer(ctx, CreateTransferParams{
+				FromAccount: source.ID,
+				ToAccount:   account.ID,
+				Amount:      posting.Amount,
+				Type:        TransferInterest,
+			})
+			if err != nil {
+				return err
+			}
+			result.Transfer, err = store.post(ctx, q, t)
+			if err != nil {
+				return err
+			}
+			posting.TransferID = sql.NullInt64{Int64: t.ID, Valid: true}
+		}
+
+		result.Posting, err = q.CreateInterestPosting(ctx, posting)
+		if err != nil {
+			return err
+		}
+		_, err = q.MarkInterestPosted(ctx, MarkInterestPostedParams{
+			PostingID: sql.NullInt64{Int64: result.Posting.ID, Valid: true},
+			AccountID: account.ID,
+			Before:    before,
+		})
+		if err != nil {
+			return err
+		}
+		if arg.Audit != nil {
+			entry := *arg.Audit
+			entry.ResourceID = strconv.FormatInt(result.Posting.ID, 10)
+			if entry.After, err = json.Marshal(result.Posting); err != nil {
+				return err
+			}
+			_, err = q.CreateAuditLog(ctx, entry)
+		}
+		return err
+	})
+	return result, err
+}
+
+// PostInterest posts the interest accrued up to the end of a month on up to limit accounts, each
+// in its own transaction, and returns how many it posted. Accounts that aren't active keep their
+// interest until they are again. Running it again for the same month posts nothing more.
+func PostInterest(ctx context.Context, store Store, month time.Time, limit int32, audit *CreateAuditLogParams) (int, error) {
+	month = InterestMonth(month)
+	accounts, err := store.ListUnpostedInterestAccounts(ctx, ListUnpostedInterestAccountsParams{
+		Before:    month.AddDate(0, 1, 0),
+		PageLimit: limit,
+	})
+	if err != nil {
+		return 0, err
+	}
+	posted := 0
+	for _, accountID := range accounts {
+		result, err := store.PostInterestTx(ctx, PostInterestTxParams{
+			AccountID: accountID,
+			Month:     month,
+			Audit:     audit,
+		})
+		if errors.Is(err, ErrAccountNotActive) {
+			continue
+		}
+		if err != nil {
+			return posted, err
+		}
+		if result.Posting.ID != 0 {
+			posted++
+		}
+	}
+	return posted, nil
+}
+
+// InterestReportLine sums up the interest of a month in one currency, in minor units.
+type InterestReportLine struct {
+	Currency string `json:"currency"`
+	// Accounts is how many accounts earned interest over the month.
+	Accounts int64 `json:"accounts"`
+	// Accrued is the interest earned over the days of the month, and Unposted the part of it not
+	// posted yet, both rounded down.
+	Accrued  int64 `json:"accrued"`
+	Unposted int64 `json:"unposted"`
+	// Posted is what the postings for the month paid, which includes what earlier months left
+	// unposted and the fractions they carried.
+	Posted int64 `json:"posted"`
+}
+
+// InterestReport compares the interest accrued over a month with what was posted for it.
+func (store *SQLStore) InterestReport(ctx context.Context, month time.Time) ([]InterestReportLine, error) {
+	month = InterestMonth(month)
+	var report []InterestReportLine
+	err := store.ReadTx(ctx, func(ctx context.Context, q Querier) error {
+		accrued, err := q.SumInterestAccrued(ctx, SumInterestAccruedParams{
+			MonthStart: month,
+			MonthEnd:   month.AddDate(0, 1, 0),
+		})
+		if err != nil {
+			return err
+		}
+		posted, err := q.SumInterestPosted(ctx, month)
+		if err != nil {
+			return err
+		}
+
+		report = make([]InterestReportLine, 0, len(accrued))
+		lines := make(map[string]int, len(accrued))
+		for _, row := range accrued {
+			lines[row.Currency] = len(report)
+			report = append(report, InterestReportLine{
+				Currency: row.Currency,
+				Accounts: row.Accounts,
+				Accrued:  row.Accrued / InterestDenominator,
+				Unposted: row.Unposted / InterestDenominator,
+			})
+		}
+		// Postings can pay interest of earlier months in a currency that accrued none this month.
+		for _, row := range posted {
+			i, ok := lines[row.Currency]
+			if !ok {
+				i = len(report)
+				report = append(report, InterestReportLine{Currency: row.Currency})
+			}
+			report[i].Posted = row.Posted
+		}
+		return nil
+	})
+	return report, err
+}
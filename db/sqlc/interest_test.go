@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/stretchr/testify/require"
+)
+
+func makeSavingsAccount(t *testing.T, balance int64) Account {
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    makeUser().Username,
+		Balance:  balance,
+		Currency: util.USD,
+		Type:     util.Savings,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestInterest(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	ctx := context.Background()
+	account := makeSavingsAccount(t, 1000000)
+	rate, err := store.GetInterestRate(ctx, util.Savings)
+	require.NoError(t, err)
+	require.NotZero(t, rate.AnnualRateBps)
+	daily := account.Balance * int64(rate.AnnualRateBps)
+
+	source, err := store.GetSystemAccount(ctx, GetSystemAccountParams{Purpose: SystemInterest, Currency: util.USD})
+	require.NoError(t, err)
+	require.Equal(t, BankOwner, source.Owner)
+
+	// Two days of this month, accrued twice.
+	today := InterestDay(time.Now())
+	for _, day := range []time.Time{today, today.Add(time.Hour), today.AddDate(0, 0, 1)} {
+		_, err := AccrueInterest(ctx, store, day)
+		require.NoError(t, err)
+	}
+	n, err := AccrueInterest(ctx, store, today)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	month := InterestMonth(today.AddDate(0, 0, 1))
+	result, err := store.PostInterestTx(ctx, PostInterestTxParams{AccountID: account.ID, Month: month})
+	require.NoError(t, err)
+	require.Equal(t, 2*daily, result.Posting.Accrued)
+	require.Equal(t, 2*daily/InterestDenominator, result.Posting.Amount)
+	require.Equal(t, 2*daily%InterestDenominator, result.Posting.Carry)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Posting.TransferID.Int64)
+	require.Equal(t, source.ID, result.Transfer.Transfer.FromAccount)
+	require.Equal(t, account.Balance+result.Posting.Amount, result.Transfer.ToAccount.Balance)
+
+	// Savers can't send money to the bank's interest account.
+	_, err = store.TransferTx(ctx, &TransferTxParams{FromAccountID: account.ID, ToAccountID: source.ID, Amount: 1})
+	require.ErrorIs(t, err, ErrSystemAccount)
+
+	// Posting again finds nothing left.
+	again, err := store.PostInterestTx(ctx, PostInterestTxParams{AccountID: account.ID, Month: month})
+	require.NoError(t, err)
+	require.Zero(t, again.Posting.ID)
+
+	// The next month starts from what the last posting carried, at the new balance.
+	next := month.AddDate(0, 1, 0)
+	_, err = AccrueInterest(ctx, store, next)
+	require.NoError(t, err)
+	balance := result.Transfer.ToAccount.Balance
+	result, err = store.PostInterestTx(ctx, PostInterestTxParams{AccountID: account.ID, Month: next})
+	require.NoError(t, err)
+	require.Equal(t, balance*int64(rate.AnnualRateBps)+2*daily%InterestDenominator, result.Posting.Accrued)
+	require.Equal(t, next, result.Posting.Month.UTC())
+
+	report, err := store.InterestReport(ctx, month)
+	require.NoError(t, err)
+	var usd *InterestReportLine
+	for i := range report {
+		if report[i].Currency == util.USD {
+			usd = &report[i]
+		}
+	}
+	require.NotNil(t, usd)
+	require.GreaterOrEqual(t, usd.Accrued, 2*daily/InterestDenominator)
+	require.GreaterOrEqual(t, usd.Posted, 2*daily/InterestDenominator)
+}
+
+func TestAccrueInterestSkips(t *testing.T) {
+	ctx := context.Background()
+	checking := makeAccount()
+	empty := makeSavingsAccount(t, 0)
+	frozen := makeSavingsAccount(t, 1000)
+	_, err := testQueries.UpdateAccountStatus(ctx, UpdateAccountStatusParams{ID: frozen.ID, Status: AccountFrozen})
+	require.NoError(t, err)
+	saver := makeSavingsAccount(t, 1000)
+
+	// The accounts were opened after the first day, so only the second one earns interest.
+	_, err = AccrueInterest(ctx, testQueries, time.Now().AddDate(0, 0, -2))
+	require.NoError(t, err)
+	_, err = AccrueInterest(ctx, testQueries, time.Now().AddDate(0, 0, 2))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		account Account
+		days    int64
+	}{
+		{"Checking", checking, 0},
+		{"Empty", empty, 0},
+		{"Frozen", frozen, 0},
+		{"Savings", saver, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			unposted, err := testQueries.SumUnpostedInterest(ctx, SumUnpostedInterestParams{
+				AccountID: tc.account.ID,
+				Before:    time.Now().AddDate(0, 2, 0),
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.days, unposted.Days)
+		})
+	}
+}
+
+func TestAccrueInterestUntil(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	ctx := context.Background()
+	account := makeSavingsAccount(t, 1000000)
+	rate, err := store.GetInterestRate(ctx, util.Savings)
+	require.NoError(t, err)
+
+	// The account was opened two days ago, and money arrives today.
+	today := InterestDay(time.Now())
+	_, err = testDB.ExecContext(ctx, "UPDATE accounts SET created_at = $1 WHERE id = $2", today.AddDate(0, 0, -2).Add(time.Hour), account.ID)
+	require.NoError(t, err)
+	from := makeSavingsAccount(t, 1000)
+	_, err = store.TransferTx(ctx, &TransferTxParams{FromAccountID: from.ID, ToAccountID: account.ID, Amount: 500})
+	require.NoError(t, err)
+
+	yesterday := today.AddDate(0, 0, -1)
+	_, err = AccrueInterestUntil(ctx, store, yesterday)
+	require.NoError(t, err)
+	last, err := store.GetLastAccrualDate(ctx, GetLastAccrualDateParams{Since: today.AddDate(0, 0, -10), Until: yesterday})
+	require.NoError(t, err)
+	require.Equal(t, yesterday, last.UTC())
+
+	// Other tests may have accrued up to yesterday already; the days before are accrued either way.
+	for _, day := range []time.Time{today.AddDate(0, 0, -2), yesterday} {
+		_, err = AccrueInterest(ctx, store, day)
+		require.NoError(t, err)
+	}
+	unposted, err := store.SumUnpostedInterest(ctx, SumUnpostedInterestParams{AccountID: account.ID, Before: today})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), unposted.Days)
+	// Both days earned interest on the balance at their end, without the money that came today.
+	require.Equal(t, 2*account.Balance*int64(rate.AnnualRateBps), unposted.Accrued)
+}
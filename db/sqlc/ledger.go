@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// The entries of an account form a hash chain: the hash of each entry covers the entry, the
+// transfer it belongs to and the hash of the account's previous entry. Editing or removing an
+// entry or a transfer breaks every later link of the chain; the ledger package walks it. The
+// hashes are HMACs keyed with LEDGER_KEY, so that whoever can write to the database but doesn't
+// hold the key can't recompute the chain after changing it.
+
+// hashDomain keeps entry hashes apart from any other digest made with the key, and versions the
+// layout.
+const hashDomain = "simplebank/entry/v2"
+
+// GenesisHash is the prev_hash of the first entry of an account.
+var GenesisHash = make([]byte, sha256.Size)
+
+// ChainedEntry is what the hash of an entry covers.
+type ChainedEntry struct {
+	AccountID int64
+	Amount    int64
+	// Balance of the account after the entry.
+	Balance   int64
+	CreatedAt time.Time
+	// The transfer the entry belongs to; zero for entries outside a transfer.
+	TransferID          int64
+	TransferFromAccount int64
+	TransferToAccount   int64
+	TransferAmount      int64
+}
+
+// Hash returns the hash of the entry, keyed with key, when prev is the hash of the entry before it.
+func (e ChainedEntry) Hash(key []byte, prev []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(hashDomain))
+	h.Write(prev)
+	var buf [8]byte
+	for _, v := range []int64{
+		e.AccountID,
+		e.Amount,
+		e.Balance,
+		e.CreatedAt.UnixMicro(),
+		e.TransferID,
+		e.TransferFromAccount,
+		e.TransferToAccount,
+		e.TransferAmount,
+	} {
+		binary.BigEndian.PutUint64(buf[:], uint64(v))
+		h.Write(buf[:])
+	}
+	return h.Sum(nil)
+}
+
+// appendEntry adds an entry of amount to the end of the account's chain. account must already
+// carry the balance after the entry, and the caller must hold its row lock so that no other entry
+// is appended to the chain at the same time.
+func (store *SQLStore) appendEntry(ctx context.Context, q *Queries, account Account, amount int64, transfer Transfer) (Entry, error) {
+	prev, err := q.GetLastEntryHash(ctx, account.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	// Entries made before the chain existed have no hash; the chain starts after them.
+	if prev == nil {
+		prev = GenesisHash
+	}
+
+	entry := ChainedEntry{
+		AccountID: account.ID,
+		Amount:    amount,
+		Balance:   account.Balance,
+		// Postgres keeps microseconds, so the hash is computed over what is stored.
+		CreatedAt:           time.Now().UTC().Truncate(time.Microsecond),
+		TransferID:          transfer.ID,
+		TransferFromAccount: transfer.FromAccount,
+		TransferToAccount:   transfer.ToAccount,
+		TransferAmount:      transfer.Amount,
+	}
+	return q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  entry.AccountID,
+		Amount:     entry.Amount,
+		TransferID: sql.NullInt64{Int64: transfer.ID, Valid: transfer.ID != 0},
+		Balance:    sql.NullInt64{Int64: entry.Balance, Valid: true},
+		PrevHash:   prev,
+		Hash:       entry.Hash(store.ledgerKey, prev),
+		CreatedAt:  entry.CreatedAt,
+	})
+}
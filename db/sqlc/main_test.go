@@ -12,6 +12,7 @@ import (
 
 var testQueries *Queries
 var testDB *sql.DB
+var testLedgerKey []byte
 
 func TestMain(m *testing.M) {
 
@@ -25,5 +26,6 @@ func TestMain(m *testing.M) {
 		log.Fatal("Cannot connect to db: ", err)
 	}
 	testQueries = New(testDB)
+	testLedgerKey = []byte(config.LedgerKey)
 	os.Exit(m.Run())
 }
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayees(t *testing.T) {
+	owner := makeUser()
+	recipient := makeUser()
+	account := makeAccount()
+
+	byAccount, err := testQueries.CreatePayee(context.Background(), CreatePayeeParams{
+		Owner:     owner.Username,
+		Label:     "Landlord",
+		AccountID: sql.NullInt64{Int64: account.ID, Valid: true},
+		Currency:  account.Currency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.ID, byAccount.AccountID.Int64)
+	require.False(t, byAccount.Username.Valid)
+
+	byUsername, err := testQueries.CreatePayee(context.Background(), CreatePayeeParams{
+		Owner:    owner.Username,
+		Label:    "Mom",
+		Username: sql.NullString{String: recipient.Username, Valid: true},
+		Currency: util.EUR,
+	})
+	require.NoError(t, err)
+
+	// Labels are unique per owner, and a payee has exactly one target.
+	_, err = testQueries.CreatePayee(context.Background(), CreatePayeeParams{
+		Owner:    owner.Username,
+		Label:    "Mom",
+		Username: sql.NullString{String: recipient.Username, Valid: true},
+		Currency: util.EUR,
+	})
+	require.Error(t, err)
+	_, err = testQueries.CreatePayee(context.Background(), CreatePayeeParams{
+		Owner:    owner.Username,
+		Label:    "Nobody",
+		Currency: util.EUR,
+	})
+	require.Error(t, err)
+
+	// Other users don't see the payee.
+	_, err = testQueries.GetPayee(context.Background(), GetPayeeParams{ID: byUsername.ID, Owner: recipient.Username})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	payees, err := testQueries.ListPayees(context.Background(), ListPayeesParams{Owner: owner.Username, Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, payees, 2)
+	require.Equal(t, "Landlord", payees[0].Label)
+
+	updated, err := testQueries.UpdatePayee(context.Background(), UpdatePayeeParams{
+		ID:       byUsername.ID,
+		Owner:    owner.Username,
+		Currency: sql.NullString{String: util.USD, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Mom", updated.Label)
+	require.Equal(t, util.USD, updated.Currency)
+
+	rows, err := testQueries.DeletePayee(context.Background(), DeletePayeeParams{ID: byUsername.ID, Owner: recipient.Username})
+	require.NoError(t, err)
+	require.Zero(t, rows)
+	rows, err = testQueries.DeletePayee(context.Background(), DeletePayeeParams{ID: byUsername.ID, Owner: owner.Username})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+}
@@ -13,6 +13,33 @@ import (
 	"github.com/google/uuid"
 )
 
+const countSessionIPs = `-- name: CountSessionIPs :one
+SELECT
+  COALESCE(min(created_at) FILTER (WHERE client_ip = $1::varchar), now())::timestamptz AS first_seen,
+  count(*) FILTER (WHERE client_ip IS DISTINCT FROM $1::varchar) AS others
+FROM sessions
+WHERE username = $2
+`
+
+type CountSessionIPsParams struct {
+	ClientIp string `json:"client_ip"`
+	Username string `json:"username"`
+}
+
+type CountSessionIPsRow struct {
+	FirstSeen time.Time `json:"first_seen"`
+	Others    int64     `json:"others"`
+}
+
+// When the user first logged in from an IP address, now if they never did, and how many of their
+// sessions came from other addresses.
+func (q *Queries) CountSessionIPs(ctx context.Context, arg CountSessionIPsParams) (CountSessionIPsRow, error) {
+	row := q.db.QueryRowContext(ctx, countSessionIPs, arg.ClientIp, arg.Username)
+	var i CountSessionIPsRow
+	err := row.Scan(&i.FirstSeen, &i.Others)
+	return i, err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions (
   id, 
@@ -82,3 +109,26 @@ func (q *Queries) GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	)
 	return i, err
 }
+
+const blockSession = `-- name: BlockSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1
+RETURNING id, username, is_blocked, client_ip, user_agent, refresh_token, expires_at, created_at
+`
+
+func (q *Queries) BlockSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	row := q.db.QueryRowContext(ctx, blockSession, id)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.IsBlocked,
+		&i.ClientIp,
+		&i.UserAgent,
+		&i.RefreshToken,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -3,38 +3,99 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ashokmouli/simplebank/txlimit"
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg *TransferTxParams) (TransferTxResults, error)
+	SetAccountStatusTx(ctx context.Context, arg SetAccountStatusTxParams) (SetAccountStatusTxResult, error)
+	SetDefaultAccountTx(ctx context.Context, accountID int64) (Account, error)
+	DecideTransferReviewTx(ctx context.Context, arg DecideTransferReviewTxParams) (DecideTransferReviewTxResult, error)
+	PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error)
+	TransferAllowance(ctx context.Context, account Account, defaults txlimit.Limits) (txlimit.Allowance, error)
+	InterestReport(ctx context.Context, month time.Time) ([]InterestReportLine, error)
+	ReadTx(ctx context.Context, fn func(context.Context, Querier) error) error
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (version uint, dirty bool, err error)
 }
 
 type SQLStore struct {
 	*Queries
 	db *sql.DB
+	// ledgerKey keys the hashes that chain the entries of every account.
+	ledgerKey []byte
 }
 
-func NewStore(db *sql.DB) Store {
+// NewStore returns a store that chains the entries it writes with ledgerKey, the LEDGER_KEY the
+// ledger is verified with.
+func NewStore(db *sql.DB, ledgerKey []byte) Store {
 	return &SQLStore {
-		db:      db,
-		Queries: New(db),
+		db:        db,
+		Queries:   New(tracedDBTX{db: db}),
+		ledgerKey: ledgerKey,
 	}
 }
 
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+// Ping checks that the database is reachable.
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the migration version recorded by golang-migrate, and whether the last
+// migration failed half way.
+func (store *SQLStore) SchemaVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	err = store.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	return
+}
+
+// execTx runs fn in a transaction, traced as a span called name. The context handed to fn carries
+// the span, so fn should use it for its queries. Transactions run once: a deadlock or a
+// serialization failure is returned to the caller like any other error.
+func (store *SQLStore) execTx(ctx context.Context, name string, fn func(context.Context, *Queries) error) error {
+	ctx, span := tracer.Start(ctx, name)
+	defer span.End()
+
+	span.SetAttributes(txAttempts.Int(1))
+	err := store.runTx(ctx, nil, fn)
+	if err != nil {
+		span.SetAttributes(txRollbackReason.String(err.Error()))
+		recordError(span, err)
+	}
+	return err
+}
+
+// ReadTx runs fn in a read-only transaction that sees a single snapshot of the database, for reads
+// spread over several queries that must agree with each other.
+func (store *SQLStore) ReadTx(ctx context.Context, fn func(context.Context, Querier) error) error {
+	ctx, span := tracer.Start(ctx, "ReadTx")
+	defer span.End()
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := store.runTx(ctx, opts, func(ctx context.Context, q *Queries) error {
+		return fn(ctx, q)
+	})
+	recordError(span, err)
+	return err
+}
+
+func (store *SQLStore) runTx(ctx context.Context, opts *sql.TxOptions, fn func(context.Context, *Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	q := New(tx)
-	err = fn(q)
+	q := New(tracedDBTX{db: tx})
+	err = fn(ctx, q)
 	if err != nil {
 		rberr := tx.Rollback()
 		if rberr != nil {
-			return fmt.Errorf("tx err: %v rb err: %v", err, rberr)
+			return fmt.Errorf("tx err: %w rb err: %v", err, rberr)
 		}
 		return err
 	}
@@ -42,10 +103,46 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 	return err
 }
 
+// Types of transfer. Fees are charged, per currency, on the transfers users make.
+const (
+	// TransferInternal transfers move money between the accounts of one owner.
+	TransferInternal = "internal"
+	// TransferExternal transfers send money to someone else's account.
+	TransferExternal = "external"
+	// TransferFee transfers charge the sender of another transfer for it.
+	TransferFee = "fee"
+	// TransferInterest transfers pay savers their interest.
+	TransferInterest = "interest"
+)
+
 type TransferTxParams struct {
 	FromAccountID int64 `json:"fromAccountID"`
 	ToAccountID   int64 `json:"toAccountID"`
 	Amount        int64 `json:"amount"`
+	// Limits, when set, are the default limits of the sender in the currency of the transfer.
+	// The transfer is refused if it would take the sender or the account over them, or over the
+	// limits bankers set instead. Transfers without Limits are not limited.
+	Limits *txlimit.Limits `json:"-"`
+	// Review, when set, parks the transfer for a banker instead of posting it: the amount is held
+	// on the sender's account until the review is decided or expires. The review is completed
+	// with the transfer.
+	Review *CreateTransferReviewParams `json:"-"`
+	// Audit, when set, is written to the audit log in the same transaction, completed with the
+	// transfer ID and the balances before and after.
+	Audit *CreateAuditLogParams `json:"-"`
+}
+
+// accountBalance is the state of an account recorded in the audit log for a transfer.
+type accountBalance struct {
+	ID      int64 `json:"id"`
+	Balance int64 `json:"balance"`
+}
+
+type transferState struct {
+	FromAccount accountBalance `json:"from_account"`
+	ToAccount   accountBalance `json:"to_account"`
+	// Fee is what the sender paid on top of the amount.
+	Fee int64 `json:"fee,omitempty"`
 }
 
 type TransferTxResults struct {
@@ -54,53 +151,132 @@ type TransferTxResults struct {
 	ToAccount   Account  `json:"toAccount"`
 	FromEntry   Entry    `json:"fromEntry"`
 	ToEntry     Entry    `json:"toEntry"`
+	// Review is the review a held transfer waits for.
+	Review *TransferReview `json:"review,omitempty"`
+	// Fee is what the sender was charged for the transfer. FromAccount is the sender's account
+	// once the fee was paid.
+	Fee *FeeCharge `json:"fee,omitempty"`
 }
 
 var txKey = struct{}{}
 
 func (store *SQLStore) TransferTx(ctx context.Context, arg *TransferTxParams) (TransferTxResults, error) {
 	var result TransferTxResults
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execTx(ctx, "TransferTx", func(ctx context.Context, q *Queries) error {
 		//txName := ctx.Value(txKey)
 		//fmt.Println(txName)
 		var err error
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccount: arg.FromAccountID,
-			ToAccount:   arg.ToAccountID,
-			Amount:      arg.Amount,
-		})
-		if err != nil {
-			return err
+		if arg.Limits != nil {
+			if err = checkLimits(ctx, q, arg); err != nil {
+				return err
+			}
 		}
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
-			return err
+		if arg.Review != nil {
+			result, err = hold(ctx, q, arg)
+			if err != nil {
+				return err
+			}
+			if arg.Audit != nil {
+				return auditHold(ctx, q, *arg.Audit, result)
+			}
+			return nil
 		}
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+		result, err = store.transfer(ctx, q, arg)
 		if err != nil {
 			return err
 		}
-		// Make sure you update the account with lowest id first to prevent deadlocks.
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-		}
-		if err != nil {
+		if err = store.chargeFee(ctx, q, &result); err != nil {
 			return err
 		}
+		if arg.Audit != nil {
+			return auditTransfer(ctx, q, *arg.Audit, arg.Amount, result)
+		}
 		return nil
 	})
 
 	return result, err
 }
 
+// transfer moves money between two active accounts within the transaction of q.
+func (store *SQLStore) transfer(ctx context.Context, q *Queries, arg *TransferTxParams) (TransferTxResults, error) {
+	transferType, err := typeOfTransfer(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return TransferTxResults{}, err
+	}
+	t, err := q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccount: arg.FromAccountID,
+		ToAccount:   arg.ToAccountID,
+		Amount:      arg.Amount,
+		Type:        transferType,
+	})
+	if err != nil {
+		return TransferTxResults{}, err
+	}
+	return store.post(ctx, q, t)
+}
+
+// post moves the money of a transfer between its accounts and appends their entries.
+func (store *SQLStore) post(ctx context.Context, q *Queries, t Transfer) (TransferTxResults, error) {
+	result := TransferTxResults{Transfer: t}
+	var err error
+	// Make sure you update the account with lowest id first to prevent deadlocks.
+	if t.FromAccount < t.ToAccount {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, t.FromAccount, -t.Amount, t.ToAccount, t.Amount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, t.ToAccount, t.Amount, t.FromAccount, -t.Amount)
+	}
+	if err != nil {
+		return result, err
+	}
+	// The status is read under the row lock the balance update took, so it can't change before
+	// the transaction commits.
+	if err = checkActive(result.FromAccount); err != nil {
+		return result, err
+	}
+	if err = checkActive(result.ToAccount); err != nil {
+		return result, err
+	}
+	if err = checkHeld(result.FromAccount); err != nil {
+		return result, err
+	}
+	// The entries are chained to the accounts' previous ones, which is safe now that the
+	// balance updates hold both rows locked.
+	result.FromEntry, err = store.appendEntry(ctx, q, result.FromAccount, -t.Amount, t)
+	if err != nil {
+		return result, err
+	}
+	result.ToEntry, err = store.appendEntry(ctx, q, result.ToAccount, t.Amount, t)
+	return result, err
+}
+
+func auditTransfer(ctx context.Context, q *Queries, entry CreateAuditLogParams, amount int64, result TransferTxResults) error {
+	var fee int64
+	if result.Fee != nil {
+		fee = result.Fee.Transfer.Amount
+	}
+	before, err := json.Marshal(transferState{
+		FromAccount: accountBalance{ID: result.FromAccount.ID, Balance: result.FromAccount.Balance + amount + fee},
+		ToAccount:   accountBalance{ID: result.ToAccount.ID, Balance: result.ToAccount.Balance - amount},
+	})
+	if err != nil {
+		return err
+	}
+	after, err := json.Marshal(transferState{
+		FromAccount: accountBalance{ID: result.FromAccount.ID, Balance: result.FromAccount.Balance},
+		ToAccount:   accountBalance{ID: result.ToAccount.ID, Balance: result.ToAccount.Balance},
+		Fee:         fee,
+	})
+	if err != nil {
+		return err
+	}
+
+	entry.ResourceID = strconv.FormatInt(result.Transfer.ID, 10)
+	entry.Before = before
+	entry.After = after
+	_, err = q.CreateAuditLog(ctx, entry)
+	return err
+}
+
 func addMoney(ctx context.Context,
 	q *Queries,
 	accountID1 int64,
@@ -2,14 +2,18 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strconv"
 	"testing"
 
+	"github.com/ashokmouli/simplebank/db/migration"
+	"github.com/ashokmouli/simplebank/txlimit"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTransferTx(t *testing.T) {
-	store := NewStore(testDB)
+	store := NewStore(testDB, testLedgerKey)
 	account1 := makeAccount()
 	account2 := makeAccount()
 	amount := int64(100)
@@ -88,8 +92,231 @@ func TestTransferTx(t *testing.T) {
 
 }
 
+func TestTransferTxAudit(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	account1 := makeAccount()
+	account2 := makeAccount()
+
+	result, err := store.TransferTx(context.Background(), &TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Audit: &CreateAuditLogParams{
+			Actor:        account1.Owner,
+			Action:       "transfer.create",
+			Outcome:      "success",
+			ResourceType: "transfer",
+		},
+	})
+	require.NoError(t, err)
+
+	entries, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		ResourceType: sql.NullString{String: "transfer", Valid: true},
+		ResourceID:   sql.NullString{String: strconv.FormatInt(result.Transfer.ID, 10), Valid: true},
+		PageLimit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, account1.Owner, entries[0].Actor)
+	require.JSONEq(t, fmt.Sprintf(`{"from_account": {"id": %d, "balance": %d}, "to_account": {"id": %d, "balance": %d}}`,
+		account1.ID, account1.Balance, account2.ID, account2.Balance), string(entries[0].Before))
+	require.JSONEq(t, fmt.Sprintf(`{"from_account": {"id": %d, "balance": %d}, "to_account": {"id": %d, "balance": %d}}`,
+		account1.ID, account1.Balance-10, account2.ID, account2.Balance+10), string(entries[0].After))
+}
+
+func TestTransferTxHashChain(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	account1 := makeAccount()
+	account2 := makeAccount()
+
+	// Concurrent transfers in both directions must still leave one unbroken chain per account.
+	errs := make(chan error)
+	const n = 6
+	for i := 0; i < n; i++ {
+		arg := &TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10}
+		if i%2 == 1 {
+			arg.FromAccountID, arg.ToAccountID = account2.ID, account1.ID
+		}
+		go func() {
+			_, err := store.TransferTx(context.Background(), arg)
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	for _, account := range []Account{account1, account2} {
+		rows, err := store.ListLedgerEntries(context.Background(), ListLedgerEntriesParams{
+			AccountID: account.ID,
+			PageLimit: 100,
+		})
+		require.NoError(t, err)
+		require.Len(t, rows, n)
+
+		prev := GenesisHash
+		balance := account.Balance
+		for _, row := range rows {
+			balance += row.Amount
+			require.Equal(t, prev, row.PrevHash)
+			require.Equal(t, balance, row.Balance.Int64)
+			require.True(t, row.TransferID.Valid)
+
+			entry := ChainedEntry{
+				AccountID:           row.AccountID,
+				Amount:              row.Amount,
+				Balance:             row.Balance.Int64,
+				CreatedAt:           row.CreatedAt,
+				TransferID:          row.TransferID.Int64,
+				TransferFromAccount: row.TransferFromAccount,
+				TransferToAccount:   row.TransferToAccount,
+				TransferAmount:      row.TransferAmount,
+			}
+			require.Equal(t, entry.Hash(testLedgerKey, prev), row.Hash)
+			prev = row.Hash
+		}
+	}
+}
+
+func TestReadTxIsReadOnly(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	account := makeAccount()
+
+	err := store.ReadTx(context.Background(), func(ctx context.Context, q Querier) error {
+		_, err := q.AddAccountBalance(ctx, AddAccountBalanceParams{ID: account.ID, Amount: 1})
+		return err
+	})
+	require.Error(t, err)
+}
+
+func TestTransferTxRefusesInactiveAccounts(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	account1 := makeAccount()
+	account2 := makeAccount()
+
+	_, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account2.ID,
+		Status:    AccountFrozen,
+	})
+	require.NoError(t, err)
+
+	// Neither debits nor credits go through, and nothing is left behind.
+	for _, arg := range []TransferTxParams{
+		{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10},
+		{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 10},
+	} {
+		_, err = store.TransferTx(context.Background(), &arg)
+		require.ErrorIs(t, err, ErrAccountNotActive)
+	}
+	updated, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updated.Balance)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	account1 := makeAccount()
+	account2 := makeAccount()
+	limits := txlimit.Limits{Single: 50, Daily: 100}
+
+	transfer := func(amount int64) error {
+		_, err := store.TransferTx(context.Background(), &TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+			Limits:        &limits,
+		})
+		return err
+	}
+	require.ErrorIs(t, transfer(60), txlimit.ErrExceeded)
+	require.NoError(t, transfer(50))
+	require.NoError(t, transfer(40))
+	// 90 of the daily 100 are gone.
+	require.ErrorIs(t, transfer(20), txlimit.ErrExceeded)
+
+	allowance, err := store.TransferAllowance(context.Background(), account1, limits)
+	require.NoError(t, err)
+	require.Equal(t, txlimit.Window{Limit: 100, Used: 90, Remaining: 10, Scope: txlimit.ScopeUser}, allowance.Daily)
+
+	// A banker lifts the owner's daily limit but caps the account's single transfers.
+	_, err = store.SetUserTransferLimit(context.Background(), SetUserTransferLimitParams{
+		Username: sql.NullString{String: account1.Owner, Valid: true},
+		Currency: account1.Currency,
+		DailyMax: sql.NullInt64{Int64: 0, Valid: true},
+		SetBy:    account1.Owner,
+		Reason:   "house purchase",
+	})
+	require.NoError(t, err)
+	_, err = store.SetAccountTransferLimit(context.Background(), SetAccountTransferLimitParams{
+		AccountID: sql.NullInt64{Int64: account1.ID, Valid: true},
+		Currency:  account1.Currency,
+		SingleMax: sql.NullInt64{Int64: 30, Valid: true},
+		SetBy:     account1.Owner,
+		Reason:    "house purchase",
+	})
+	require.NoError(t, err)
+	require.NoError(t, transfer(30))
+	require.ErrorIs(t, transfer(31), txlimit.ErrExceeded)
+}
+
+func TestSetAccountStatusTx(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	account := makeAccount()
+	sweepTo := makeAccount()
+	require.True(t, account.IsDefault)
+
+	// Closing needs a zero balance.
+	_, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountClosed,
+	})
+	require.ErrorIs(t, err, ErrBalanceNotZero)
+
+	result, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID:        account.ID,
+		Status:           AccountClosed,
+		SweepToAccountID: sweepTo.ID,
+		Audit: &CreateAuditLogParams{
+			Actor:        "banker",
+			Action:       "account.status",
+			Outcome:      "success",
+			ResourceType: "account",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountClosed, result.Account.Status)
+	require.Zero(t, result.Account.Balance)
+	require.False(t, result.Account.IsDefault)
+	require.NotNil(t, result.Sweep)
+	require.Equal(t, sweepTo.Balance+account.Balance, result.Sweep.ToAccount.Balance)
+
+	entries, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		ResourceType: sql.NullString{String: "account", Valid: true},
+		ResourceID:   sql.NullString{String: strconv.FormatInt(account.ID, 10), Valid: true},
+		PageLimit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.JSONEq(t, fmt.Sprintf(`{"status": "active", "balance": %d}`, account.Balance), string(entries[0].Before))
+	require.JSONEq(t, `{"status": "closed", "balance": 0}`, string(entries[0].After))
+
+	// Closed accounts can't be frozen, only reopened.
+	_, err = store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountFrozen,
+	})
+	require.ErrorIs(t, err, ErrStatusTransition)
+
+	result, err = store.SetAccountStatusTx(context.Background(), SetAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountActive,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountActive, result.Account.Status)
+}
+
 func TestTransferDeadlock(t *testing.T) {
-	store := NewStore(testDB)
+	store := NewStore(testDB, testLedgerKey)
 	account1 := makeAccount()
 	account2 := makeAccount()
 	amount := int64(100)
@@ -127,3 +354,16 @@ func TestTransferDeadlock(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, updatedAccount2.Balance, account2.Balance)
 }
+
+func TestSchemaVersion(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	require.NoError(t, store.Ping(context.Background()))
+
+	version, dirty, err := store.SchemaVersion(context.Background())
+	require.NoError(t, err)
+	require.False(t, dirty)
+
+	latest, err := migration.LatestVersion(migration.FS)
+	require.NoError(t, err)
+	require.Equal(t, latest, version)
+}
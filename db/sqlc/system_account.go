@@ -0,0 +1,21 @@
+package db
+
+import "errors"
+
+// BankOwner owns the bank's own accounts. Nobody can register or log in as it.
+const BankOwner = "simple-bank"
+
+// Purposes of the bank's own accounts. There is one account per purpose in every currency.
+const (
+	// SystemInterest accounts pay the interest savers earn.
+	SystemInterest = "interest"
+	// SystemFees accounts collect the fees charged on transfers.
+	SystemFees = "fees"
+	// SystemSuspense accounts park money the bank can't place yet.
+	SystemSuspense = "suspense"
+)
+
+// ErrSystemAccount is returned for a transfer to or from one of the bank's own accounts. Only
+// the bank moves money in and out of them, to charge fees and pay interest, and it locks them
+// after its customers' accounts: a transfer that locked one first could deadlock with those.
+var ErrSystemAccount = errors.New("the bank's own accounts don't take transfers")
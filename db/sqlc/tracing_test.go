@@ -0,0 +1,14 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "CreateSession", queryName(createSession))
+	require.Equal(t, "GetAccount", queryName("-- name: GetAccount :one\nSELECT * FROM accounts"))
+	require.Equal(t, "query", queryName("SELECT 1"))
+	require.Equal(t, "query", queryName("-- name: "))
+}
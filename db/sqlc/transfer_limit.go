@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ashokmouli/simplebank/txlimit"
+)
+
+// Apply returns limits with the ones the override sets replaced.
+func (l TransferLimit) Apply(limits txlimit.Limits) txlimit.Limits {
+	if l.SingleMax.Valid {
+		limits.Single = l.SingleMax.Int64
+	}
+	if l.DailyMax.Valid {
+		limits.Daily = l.DailyMax.Int64
+	}
+	if l.MonthlyMax.Valid {
+		limits.Monthly = l.MonthlyMax.Int64
+	}
+	return limits
+}
+
+// allowance computes what can still leave account at now. The owner's limits are the defaults,
+// unless a banker overrode them; the account only has limits a banker set.
+func allowance(ctx context.Context, q Querier, account Account, defaults txlimit.Limits, now time.Time) (txlimit.Allowance, error) {
+	userLimits := defaults
+	override, err := q.GetUserTransferLimit(ctx, GetUserTransferLimitParams{
+		Username: sql.NullString{String: account.Owner, Valid: true},
+		Currency: account.Currency,
+	})
+	if err == nil {
+		userLimits = override.Apply(userLimits)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return txlimit.Allowance{}, err
+	}
+
+	var accountLimits txlimit.Limits
+	override, err = q.GetAccountTransferLimit(ctx, sql.NullInt64{Int64: account.ID, Valid: true})
+	if err == nil {
+		accountLimits = override.Apply(accountLimits)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return txlimit.Allowance{}, err
+	}
+
+	dayStart, monthStart := txlimit.DayStart(now), txlimit.MonthStart(now)
+	userUsed, err := q.SumUserTransfers(ctx, SumUserTransfersParams{
+		DayStart:   dayStart,
+		MonthStart: monthStart,
+		Owner:      account.Owner,
+		Currency:   account.Currency,
+	})
+	if err != nil {
+		return txlimit.Allowance{}, err
+	}
+	accountUsed, err := q.SumAccountTransfers(ctx, SumAccountTransfersParams{
+		DayStart:   dayStart,
+		MonthStart: monthStart,
+		AccountID:  account.ID,
+	})
+	if err != nil {
+		return txlimit.Allowance{}, err
+	}
+
+	result := txlimit.NewAllowance(
+		userLimits, txlimit.Used{Daily: userUsed.Daily, Monthly: userUsed.Monthly},
+		accountLimits, txlimit.Used{Daily: accountUsed.Daily, Monthly: accountUsed.Monthly},
+	)
+	result.AccountID = account.ID
+	result.Currency = account.Currency
+	return result, nil
+}
+
+// TransferAllowance returns what can still leave account, given the default limits of its owner
+// in its currency.
+func (store *SQLStore) TransferAllowance(ctx context.Context, account Account, defaults txlimit.Limits) (txlimit.Allowance, error) {
+	var result txlimit.Allowance
+	err := store.ReadTx(ctx, func(ctx context.Context, q Querier) error {
+		var err error
+		result, err = allowance(ctx, q, account, defaults, time.Now())
+		return err
+	})
+	return result, err
+}
+
+// checkLimits refuses a transfer that would take its sender or the account it leaves over a limit.
+func checkLimits(ctx context.Context, q *Queries, arg *TransferTxParams) error {
+	from, err := q.GetAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return err
+	}
+	// Transfers from the same user queue up on their row, so that each one sees the transfers
+	// the others made.
+	if _, err := q.GetUserForUpdate(ctx, from.Owner); err != nil {
+		return err
+	}
+	result, err := allowance(ctx, q, from, *arg.Limits, time.Now())
+	if err != nil {
+		return err
+	}
+	return result.Check(arg.Amount)
+}
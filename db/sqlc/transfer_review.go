@@ -0,0 +1,284 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Statuses of a transfer. Pending transfers hold their amount on the sender's account; they are
+// posted when a banker approves them, or release the hold when rejected or expired.
+const (
+	TransferPending  = "pending"
+	TransferPosted   = "posted"
+	TransferRejected = "rejected"
+	TransferExpired  = "expired"
+)
+
+// Statuses of a transfer review.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+	ReviewExpired  = "expired"
+)
+
+// reviewOutcomes maps the decision on a review to the status of its transfer.
+var reviewOutcomes = map[string]string{
+	ReviewApproved: TransferPosted,
+	ReviewRejected: TransferRejected,
+	ReviewExpired:  TransferExpired,
+}
+
+var (
+	// ErrFundsHeld is returned when a transfer would spend money held for pending transfers.
+	ErrFundsHeld = errors.New("funds are held for pending transfers")
+	// ErrReviewClosed is returned when deciding on a review that is no longer pending, or that
+	// expired.
+	ErrReviewClosed = errors.New("transfer review is closed")
+)
+
+// checkHeld refuses to leave an account with less money than it holds for pending transfers.
+func checkHeld(account Account) error {
+	if account.Held > 0 && account.Balance < account.Held {
+		return fmt.Errorf("account %d holds %d of a balance of %d: %w", account.ID, account.Held, account.Balance, ErrFundsHeld)
+	}
+	return nil
+}
+
+// hold parks a transfer for review within the transaction of q: the transfer is pending and its
+// amount is held on the sender's account, with the fee it will be charged if approved.
+func hold(ctx context.Context, q *Queries, arg *TransferTxParams) (TransferTxResults, error) {
+	var result TransferTxResults
+	transferType, err := typeOfTransfer(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+	from, err := q.GetAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return result, err
+	}
+	fee, err := feeFor(ctx, q, from.Currency, transferType, arg.Amount)
+	if err != nil {
+		return result, err
+	}
+	result.Transfer, err = q.CreatePendingTransfer(ctx, CreatePendingTransferParams{
+		FromAccount: arg.FromAccountID,
+		ToAccount:   arg.ToAccountID,
+		Amount:      arg.Amount,
+		Type:        transferType,
+	})
+	if err != nil {
+		return result, err
+	}
+	result.FromAccount, err = q.AddAccountHeld(ctx, AddAccountHeldParams{
+		ID:     arg.FromAccountID,
+		Amount: arg.Amount + fee,
+	})
+	if err != nil {
+		return result, err
+	}
+	if err = checkActive(result.FromAccount); err != nil {
+		return result, err
+	}
+	if err = checkHeld(result.FromAccount); err != nil {
+		return result, err
+	}
+	result.ToAccount, err = q.GetAccount(ctx, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+	if err = checkActive(result.ToAccount); err != nil {
+		return result, err
+	}
+
+	params := *arg.Review
+	params.TransferID = result.Transfer.ID
+	params.FromAccount = arg.FromAccountID
+	params.ToAccount = arg.ToAccountID
+	params.Amount = arg.Amount
+	params.Fee = fee
+	review, err := q.CreateTransferReview(ctx, params)
+	if err != nil {
+		return result, err
+	}
+	result.Review = &review
+	return result, nil
+}
+
+func auditHold(ctx context.Context, q *Queries, entry CreateAuditLogParams, result TransferTxResults) error {
+	entry.ResourceID = strconv.FormatInt(result.Transfer.ID, 10)
+	_, err := q.CreateAuditLog(ctx, entry)
+	return err
+}
+
+type DecideTransferReviewTxParams struct {
+	ReviewID int64
+	// Status is ReviewApproved, ReviewRejected or ReviewExpired. Only expired reviews can expire,
+	// and expired ones can't be approved or rejected any more.
+	Status string
+	// DecidedBy is the banker who decided, empty when the review expired.
+	DecidedBy string
+	Reason    string
+	Now       time.Time
+	// Audit, when set, is written to the audit log in the same transaction, completed with the
+	// review ID and the review after the decision.
+	Audit *CreateAuditLogParams
+}
+
+type DecideTransferReviewTxResult struct {
+	Review TransferReview `json:"review"`
+	// Transfer is the transfer the review was about. It only has entries when it was approved.
+	Transfer TransferTxResults `json:"transfer"`
+}
+
+// DecideTransferReviewTx posts, rejects or expires a transfer waiting for review, releases the
+// amount and fee it held and notifies the sender.
+func (store *SQLStore) DecideTransferReviewTx(ctx context.Context, arg DecideTransferReviewTxParams) (DecideTransferReviewTxResult, error) {
+	var result DecideTransferReviewTxResult
+	err := store.execTx(ctx, "DecideTransferReviewTx", func(ctx context.Context, q *Queries) error {
+		transferStatus, ok := reviewOutcomes[arg.Status]
+		if !ok {
+			return fmt.Errorf("unknown review status %q", arg.Status)
+		}
+		review, err := q.GetTransferReviewForUpdate(ctx, arg.ReviewID)
+		if err != nil {
+			return err
+		}
+		expired := !arg.Now.Before(review.ExpiresAt)
+		if review.Status != ReviewPending || expired != (arg.Status == ReviewExpired) {
+			return fmt.Errorf("review %d is %s: %w", review.ID, reviewState(review, expired), ErrReviewClosed)
+		}
+
+		t, err := q.GetTransfer(ctx, review.TransferID)
+		if err != nil {
+			return err
+		}
+		// Lock both accounts in ID order, as transfers do, before touching either.
+		first, second := t.FromAccount, t.ToAccount
+		if second < first {
+			first, second = second, first
+		}
+		if _, err = q.GetAccountForUpdate(ctx, first); err != nil {
+			return err
+		}
+		if _, err = q.GetAccountForUpdate(ctx, second); err != nil {
+			return err
+		}
+
+		from, err := q.AddAccountHeld(ctx, AddAccountHeldParams{
+			ID:     t.FromAccount,
+			Amount: -(t.Amount + review.Fee),
+		})
+		if err != nil {
+			return err
+		}
+		t, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+			ID:     t.ID,
+			Status: transferStatus,
+		})
+		if err != nil {
+			return err
+		}
+		if transferStatus == TransferPosted {
+			result.Transfer, err = store.post(ctx, q, t)
+			if err != nil {
+				return err
+			}
+			// The fee charged is the one held, whatever the schedule says now.
+			if err = store.postFee(ctx, q, &result.Transfer, review.Fee); err != nil {
+				return err
+			}
+		} else {
+			result.Transfer = TransferTxResults{Transfer: t, FromAccount: from}
+		}
+
+		result.Review, err = q.DecideTransferReview(ctx, DecideTransferReviewParams{
+			ID:        review.ID,
+			Status:    arg.Status,
+			DecidedBy: sql.NullString{String: arg.DecidedBy, Valid: arg.DecidedBy != ""},
+			Reason:    arg.Reason,
+		})
+		if err != nil {
+			return err
+		}
+		if err = notifyDecision(ctx, q, result.Review, t, from.Currency); err != nil {
+			return err
+		}
+		if arg.Audit != nil {
+			entry := *arg.Audit
+			entry.ResourceID = strconv.FormatInt(review.ID, 10)
+			if entry.After, err = json.Marshal(result.Review); err != nil {
+				return err
+			}
+			_, err = q.CreateAuditLog(ctx, entry)
+		}
+		return err
+	})
+	return result, err
+}
+
+// reviewState describes a review that can't be decided any more.
+func reviewState(review TransferReview, expired bool) string {
+	if review.Status == ReviewPending && expired {
+		return "past its expiry"
+	}
+	if review.Status == ReviewPending {
+		return "not expired yet"
+	}
+	return review.Status
+}
+
+// notifyDecision tells the sender of a transfer what became of it.
+func notifyDecision(ctx context.Context, q *Queries, review TransferReview, t Transfer, currency string) error {
+	message := fmt.Sprintf("Your transfer of %d %s to account %d was %s", t.Amount, currency, t.ToAccount, review.Status)
+	switch review.Status {
+	case ReviewRejected:
+		message += ": " + review.Reason
+	case ReviewExpired:
+		message += " before it was reviewed; the amount it held is available again"
+	}
+	_, err := q.CreateNotification(ctx, CreateNotificationParams{
+		Username:   review.RequestedBy,
+		Kind:       "transfer." + review.Status,
+		Message:    message,
+		TransferID: sql.NullInt64{Int64: t.ID, Valid: true},
+	})
+	return err
+}
+
+// ExpireTransferReviews expires up to limit reviews nobody decided on by now, each in its own
+// transaction, and returns how many it expired. Every expiry is recorded in the audit log as
+// audit, when set.
+func ExpireTransferReviews(ctx context.Context, store Store, now time.Time, limit int32, audit *CreateAuditLogParams) (int, error) {
+	reviews, err := store.ListExpiredTransferReviews(ctx, ListExpiredTransferReviewsParams{
+		Now:       now,
+		PageLimit: limit,
+	})
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for _, review := range reviews {
+		_, err := store.DecideTransferReviewTx(ctx, DecideTransferReviewTxParams{
+			ReviewID: review.ID,
+			Status:   ReviewExpired,
+			Reason:   "not reviewed in time",
+			Now:      now,
+			Audit:    audit,
+		})
+		// Another instance may have expired it first.
+		if errors.Is(err, ErrReviewClosed) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
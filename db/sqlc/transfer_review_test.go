@@ -0,0 +1,266 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferHistory(t *testing.T) {
+	from := makeAccount()
+	to := makeAccount()
+	start := time.Now().Add(-time.Second)
+
+	for i := 0; i < 2; i++ {
+		_, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+			FromAccount: from.ID,
+			ToAccount:   to.ID,
+			Amount:      10,
+			Type:        TransferExternal,
+		})
+		require.NoError(t, err)
+	}
+
+	count, err := testQueries.CountUserTransfersSince(context.Background(), CountUserTransfersSinceParams{
+		Owner: from.Owner,
+		Since: start,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	count, err = testQueries.CountUserTransfersSince(context.Background(), CountUserTransfersSinceParams{
+		Owner: from.Owner,
+		Since: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	count, err = testQueries.CountUserTransfersTo(context.Background(), CountUserTransfersToParams{
+		Owner:     from.Owner,
+		ToAccount: to.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	// The recipient never sent anything back.
+	count, err = testQueries.CountUserTransfersTo(context.Background(), CountUserTransfersToParams{
+		Owner:     to.Owner,
+		ToAccount: from.ID,
+	})
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestCountSessionIPs(t *testing.T) {
+	user := makeUser()
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"} {
+		_, err := testQueries.CreateSession(context.Background(), CreateSessionParams{
+			ID:           uuid.New(),
+			Username:     user.Username,
+			ClientIp:     sql.NullString{String: ip, Valid: true},
+			RefreshToken: util.RandomString(32),
+			ExpiresAt:    time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+	}
+
+	row, err := testQueries.CountSessionIPs(context.Background(), CountSessionIPsParams{
+		ClientIp: "10.0.0.1",
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), row.Others)
+	require.WithinDuration(t, time.Now(), row.FirstSeen, time.Minute)
+
+	// An address never logged in from is first seen now.
+	before := time.Now().Add(-time.Second)
+	row, err = testQueries.CountSessionIPs(context.Background(), CountSessionIPsParams{
+		ClientIp: "10.0.0.3",
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), row.Others)
+	require.True(t, row.FirstSeen.After(before))
+}
+
+// fundedAccount makes an account with enough money for the transfers of a test.
+func fundedAccount(t *testing.T) Account {
+	account, err := testQueries.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     makeAccount().ID,
+		Amount: 100,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func holdTransfer(t *testing.T, store Store, from, to Account, amount int64, expiresAt time.Time) TransferTxResults {
+	result, err := store.TransferTx(context.Background(), &TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		Review: &CreateTransferReviewParams{
+			RequestedBy: from.Owner,
+			ClientIp:    "10.0.0.1",
+			Findings:    json.RawMessage(`[{"rule": "new_ip", "verdict": "review", "reason": "10.0.0.1 is not among the addresses the user logged in from more than 24h0m0s ago"}]`),
+			ExpiresAt:   expiresAt,
+		},
+	})
+	require.NoError(t, err)
+	return result
+}
+
+func TestTransferTxHold(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	from := fundedAccount(t)
+	to := makeAccount()
+
+	result := holdTransfer(t, store, from, to, from.Balance, time.Now().Add(time.Hour))
+	require.Equal(t, TransferPending, result.Transfer.Status)
+	require.Empty(t, result.FromEntry)
+	require.Equal(t, from.Balance, result.FromAccount.Balance)
+	require.Equal(t, from.Balance, result.FromAccount.Held)
+	require.NotNil(t, result.Review)
+	require.Equal(t, result.Transfer.ID, result.Review.TransferID)
+	require.Equal(t, ReviewPending, result.Review.Status)
+	require.Equal(t, from.Owner, result.Review.RequestedBy)
+
+	// The held amount can't be spent by another transfer meanwhile.
+	_, err := store.TransferTx(context.Background(), &TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrFundsHeld)
+
+	// Nor held twice.
+	_, err = store.TransferTx(context.Background(), &TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        1,
+		Review:        &CreateTransferReviewParams{RequestedBy: from.Owner, Findings: json.RawMessage(`[]`), ExpiresAt: time.Now().Add(time.Hour)},
+	})
+	require.ErrorIs(t, err, ErrFundsHeld)
+
+	// Pending transfers aren't payees yet.
+	count, err := store.CountUserTransfersTo(context.Background(), CountUserTransfersToParams{
+		Owner:     from.Owner,
+		ToAccount: to.ID,
+	})
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestDecideTransferReviewTx(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	testCases := []struct {
+		name           string
+		status         string
+		transferStatus string
+		posted         bool
+	}{
+		{"Approve", ReviewApproved, TransferPosted, true},
+		{"Reject", ReviewRejected, TransferRejected, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			from := fundedAccount(t)
+			to := makeAccount()
+			banker := makeUser()
+			held := holdTransfer(t, store, from, to, 10, time.Now().Add(time.Hour))
+
+			result, err := store.DecideTransferReviewTx(context.Background(), DecideTransferReviewTxParams{
+				ReviewID:  held.Review.ID,
+				Status:    tc.status,
+				DecidedBy: banker.Username,
+				Reason:    "checked with the customer",
+				Now:       time.Now(),
+				Audit: &CreateAuditLogParams{
+					Actor:        banker.Username,
+					Action:       "transfer.decide",
+					Outcome:      "success",
+					ResourceType: "transfer_review",
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.status, result.Review.Status)
+			require.Equal(t, banker.Username, result.Review.DecidedBy.String)
+			require.True(t, result.Review.DecidedAt.Valid)
+			require.Equal(t, tc.transferStatus, result.Transfer.Transfer.Status)
+
+			account, err := store.GetAccount(context.Background(), from.ID)
+			require.NoError(t, err)
+			require.Zero(t, account.Held)
+			if tc.posted {
+				require.Equal(t, from.Balance-10, account.Balance)
+				require.Equal(t, int64(-10), result.Transfer.FromEntry.Amount)
+			} else {
+				require.Equal(t, from.Balance, account.Balance)
+			}
+
+			notifications, err := store.ListNotifications(context.Background(), ListNotificationsParams{
+				Username: from.Owner,
+				Limit:    5,
+			})
+			require.NoError(t, err)
+			require.Len(t, notifications, 1)
+			require.Equal(t, "transfer."+tc.status, notifications[0].Kind)
+			require.Equal(t, held.Transfer.ID, notifications[0].TransferID.Int64)
+
+			// A review is decided once.
+			_, err = store.DecideTransferReviewTx(context.Background(), DecideTransferReviewTxParams{
+				ReviewID:  held.Review.ID,
+				Status:    ReviewApproved,
+				DecidedBy: banker.Username,
+				Now:       time.Now(),
+			})
+			require.ErrorIs(t, err, ErrReviewClosed)
+		})
+	}
+}
+
+func TestExpireTransferReviews(t *testing.T) {
+	store := NewStore(testDB, testLedgerKey)
+	from := fundedAccount(t)
+	to := makeAccount()
+	held := holdTransfer(t, store, from, to, 10, time.Now().Add(time.Minute))
+
+	// Bankers can't decide on it once it expired.
+	_, err := store.DecideTransferReviewTx(context.Background(), DecideTransferReviewTxParams{
+		ReviewID:  held.Review.ID,
+		Status:    ReviewApproved,
+		DecidedBy: makeUser().Username,
+		Now:       time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrReviewClosed)
+
+	// Other tests leave expiring reviews behind, so expire them all.
+	later := time.Now().Add(time.Hour)
+	for {
+		n, err := ExpireTransferReviews(context.Background(), store, later, 100, nil)
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+	}
+
+	review, err := store.GetTransferReview(context.Background(), held.Review.ID)
+	require.NoError(t, err)
+	require.Equal(t, ReviewExpired, review.Status)
+	require.False(t, review.DecidedBy.Valid)
+
+	transfer, err := store.GetTransfer(context.Background(), held.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferExpired, transfer.Status)
+
+	account, err := store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.Held)
+	require.Equal(t, from.Balance, account.Balance)
+}
@@ -0,0 +1,20 @@
+package util
+
+// Types of account.
+const (
+	Checking = "checking"
+	Savings  = "savings"
+)
+
+// AccountTypes lists the types of account that can be opened.
+var AccountTypes = []string{Checking, Savings}
+
+// IsSupportedAccountType reports whether accounts of accountType can be opened.
+func IsSupportedAccountType(accountType string) bool {
+	for _, t := range AccountTypes {
+		if t == accountType {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
@@ -8,20 +9,64 @@ import (
 
 // A struct to hold all application configs
 type Config struct {
+	Environment string `mapstructure:"ENVIRONMENT"`
+	LogLevel string `mapstructure:"LOG_LEVEL"`
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+	TraceExporter string `mapstructure:"TRACE_EXPORTER"`
+	TraceEndpoint string `mapstructure:"TRACE_ENDPOINT"`
+	TraceFile string `mapstructure:"TRACE_FILE"`
 	DBDriver      string `mapstructure:"DB_DRIVER"`
 	DBSource      string `mapstructure:"DB_SOURCE"`
+	MigrateOnStart bool `mapstructure:"MIGRATE_ON_START"`
 	HTTPServerAddress string `mapstructure:"HTTP_SERVER_ADDRESS"`
 	GRPCServerAddress string `mapstructure:"GRPC_SERVER_ADDRESS"`
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	DrainPeriod time.Duration `mapstructure:"DRAIN_PERIOD"`
+	GatewayMode string `mapstructure:"GATEWAY_MODE"`
+	// TrustedProxies are the addresses or CIDR ranges of the proxies in front of the servers,
+	// whose X-Forwarded-For is believed. Without any, clients are known by their own address.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+	TLSCertFile string `mapstructure:"TLS_CERT_FILE"`
+	TLSKeyFile string `mapstructure:"TLS_KEY_FILE"`
+	TLSCAFile string `mapstructure:"TLS_CA_FILE"`
+	RateLimits string `mapstructure:"RATE_LIMITS"`
+	TransferLimits string `mapstructure:"TRANSFER_LIMITS"`
+	RiskRules string `mapstructure:"RISK_RULES"`
+	TransferReviewTTL time.Duration `mapstructure:"TRANSFER_REVIEW_TTL"`
+	PasswordHasher string `mapstructure:"PASSWORD_HASHER"`
+	PasswordMinLength int `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMinClasses int `mapstructure:"PASSWORD_MIN_CLASSES"`
+	PasswordCommonList string `mapstructure:"PASSWORD_COMMON_LIST"`
+	TokenMaker string `mapstructure:"TOKEN_MAKER"`
 	TokenSymmetricKey string `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenIssuer string `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience string `mapstructure:"TOKEN_AUDIENCE"`
 	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	// LedgerKey keys the hash chain of the ledger. Only the servers hold it, so that write access
+	// to the database alone isn't enough to rewrite entries and recompute the chain.
+	LedgerKey string `mapstructure:"LEDGER_KEY"`
 }
 
+// Defaults of the settings the servers can't run without.
+const (
+	DefaultShutdownTimeout = 20 * time.Second
+	// DefaultDrainPeriod covers two failed readiness probes five seconds apart.
+	DefaultDrainPeriod = 10 * time.Second
+	// DefaultTransferReviewTTL gives bankers three days to decide on a held transfer.
+	DefaultTransferReviewTTL = 72 * time.Hour
+	// MinLedgerKeySize is the size of a SHA-256 block's worth of HMAC key the chain needs.
+	MinLedgerKeySize = 32
+)
+
 func LoadConfig(path string) (config Config, err error) {
 	viper.SetConfigName("app")
 	viper.SetConfigType("env")
 	viper.AddConfigPath(path)
 	viper.AutomaticEnv()
+	viper.SetDefault("SHUTDOWN_TIMEOUT", DefaultShutdownTimeout)
+	viper.SetDefault("DRAIN_PERIOD", DefaultDrainPeriod)
+	viper.SetDefault("TRANSFER_REVIEW_TTL", DefaultTransferReviewTTL)
 
 	err = viper.ReadInConfig()
 	if err != nil {
@@ -31,5 +76,23 @@ func LoadConfig(path string) (config Config, err error) {
 	if err != nil {
 		return
 	}
+	err = config.validate()
 	return
 }
+
+// validate rejects settings that would make the servers misbehave rather than fail to start.
+func (config Config) validate() error {
+	if config.ShutdownTimeout <= 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must be positive, got %s", config.ShutdownTimeout)
+	}
+	if config.DrainPeriod < 0 {
+		return fmt.Errorf("DRAIN_PERIOD can't be negative, got %s", config.DrainPeriod)
+	}
+	if config.TransferReviewTTL <= 0 {
+		return fmt.Errorf("TRANSFER_REVIEW_TTL must be positive, got %s", config.TransferReviewTTL)
+	}
+	if len(config.LedgerKey) < MinLedgerKeySize {
+		return fmt.Errorf("LEDGER_KEY must be at least %d characters", MinLedgerKeySize)
+	}
+	return nil
+}
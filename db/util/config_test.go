@@ -0,0 +1,60 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// testLedgerKey is a LEDGER_KEY long enough to pass validation.
+const testLedgerKey = "LEDGER_KEY=abcdefghijabcdefghijabcdefghijab\n"
+
+// loadTestConfig loads a config from an app.env holding a valid LEDGER_KEY and settings.
+func loadTestConfig(t *testing.T, settings string) (Config, error) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.env"), []byte(testLedgerKey+settings), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	return LoadConfig(dir)
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	config, err := loadTestConfig(t, "DB_DRIVER=postgres\n")
+	if err != nil {
+		t.Fatalf("Expected nil, but got error: %v", err)
+	}
+	if config.ShutdownTimeout != DefaultShutdownTimeout {
+		t.Errorf("Expected shutdown timeout %s, but got %s", DefaultShutdownTimeout, config.ShutdownTimeout)
+	}
+	if config.DrainPeriod != DefaultDrainPeriod {
+		t.Errorf("Expected drain period %s, but got %s", DefaultDrainPeriod, config.DrainPeriod)
+	}
+	if config.TransferReviewTTL != DefaultTransferReviewTTL {
+		t.Errorf("Expected transfer review TTL %s, but got %s", DefaultTransferReviewTTL, config.TransferReviewTTL)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		settings string
+	}{
+		{"ZeroShutdownTimeout", "SHUTDOWN_TIMEOUT=0s\n"},
+		{"NegativeShutdownTimeout", "SHUTDOWN_TIMEOUT=-1s\n"},
+		{"NegativeDrainPeriod", "DRAIN_PERIOD=-1s\n"},
+		{"ZeroTransferReviewTTL", "TRANSFER_REVIEW_TTL=0s\n"},
+		{"NegativeTransferReviewTTL", "TRANSFER_REVIEW_TTL=-1h\n"},
+		{"ShortLedgerKey", "LEDGER_KEY=short\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := loadTestConfig(t, tc.settings); err == nil {
+				t.Errorf("Expected an error for %q", tc.settings)
+			}
+		})
+	}
+}
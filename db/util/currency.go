@@ -0,0 +1,21 @@
+package util
+
+// Currencies the bank holds accounts in.
+const (
+	USD = "USD"
+	EUR = "EUR"
+	CAD = "CAD"
+)
+
+// SupportedCurrencies lists the currencies accounts can be opened in, in the order they are shown.
+var SupportedCurrencies = []string{USD, EUR, CAD}
+
+// IsSupportedCurrency reports whether accounts can be opened in currency.
+func IsSupportedCurrency(currency string) bool {
+	for _, c := range SupportedCurrencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
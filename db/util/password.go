@@ -1,21 +0,0 @@
-package util
-
-import (
-	"fmt"
-
-	"golang.org/x/crypto/bcrypt"
-)
-
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate hash: %w", err)
-	}
-	return string(hash), nil
-}
-
-func CheckPassword(clear string, hash string) error {
-
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(clear))
-
-}
@@ -0,0 +1,32 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/ashokmouli/simplebank/audit"
+)
+
+// auditClient describes the caller for the audit log.
+func (server *Server) auditClient(ctx context.Context) audit.Client {
+	md := server.extractMetaData(ctx)
+	client := audit.Client{
+		IP:        md.clientIP,
+		UserAgent: md.userAgent,
+	}
+	if rl, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		client.RequestID = rl.requestID
+	}
+	return client
+}
+
+// auditLogin records a login attempt. Failed attempts are recorded under the username that was tried.
+func (server *Server) auditLogin(ctx context.Context, username string, outcome string) {
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        username,
+		Action:       audit.ActionLogin,
+		Outcome:      outcome,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   username,
+		Client:       server.auditClient(ctx),
+	})
+}
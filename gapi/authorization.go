@@ -0,0 +1,119 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ashokmouli/simplebank/apikey"
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/token"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	authorizationHeader     = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
+)
+
+// rpcScopes declares, for every RPC, the scope the caller's token or API key must hold.
+// An empty scope marks a public RPC. RPCs missing from the list are refused.
+var rpcScopes = map[string]string{
+	pb.SimpleBank_CreateUser_FullMethodName:     "",
+	pb.SimpleBank_LoginUser_FullMethodName:      "",
+	pb.SimpleBank_CreateAPIKey_FullMethodName:   token.ScopeAPIKeysManage,
+	pb.SimpleBank_ListAPIKeys_FullMethodName:    token.ScopeAPIKeysManage,
+	pb.SimpleBank_RevokeAPIKey_FullMethodName:   token.ScopeAPIKeysManage,
+	pb.SimpleBank_ListAuditLog_FullMethodName:   token.ScopeAuditRead,
+	pb.SimpleBank_VerifyLedger_FullMethodName:   token.ScopeAuditRead,
+	pb.SimpleBank_CreatePayee_FullMethodName:    token.ScopeTransfersWrite,
+	pb.SimpleBank_ListPayees_FullMethodName:     token.ScopeTransfersWrite,
+	pb.SimpleBank_UpdatePayee_FullMethodName:    token.ScopeTransfersWrite,
+	pb.SimpleBank_DeletePayee_FullMethodName:    token.ScopeTransfersWrite,
+	pb.SimpleBank_GetLimits_FullMethodName:      token.ScopeAccountsRead,
+	pb.SimpleBank_CreateTransfer_FullMethodName: token.ScopeTransfersWrite,
+
+	// Probes call the health service without credentials.
+	healthpb.Health_Check_FullMethodName: "",
+}
+
+type authPayloadKey struct{}
+
+// authorizeUser returns the caller of an RPC that needs one.
+func (server *Server) authorizeUser(ctx context.Context, method string) (*token.Payload, error) {
+	// Calls through the gRPC server have already been authorized by AuthInterceptor.
+	if payload, ok := ctx.Value(authPayloadKey{}).(*token.Payload); ok {
+		return payload, nil
+	}
+	// The in-process HTTP gateway calls handlers directly and skips interceptors, so authorize
+	// and rate limit here.
+	payload, err := server.authorize(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	if err := server.rateLimit(ctx, method, payload.Username); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// authorize checks the credentials in the request metadata, and that they hold the scope method needs.
+func (server *Server) authorize(ctx context.Context, method string) (*token.Payload, error) {
+	scope, ok := rpcScopes[method]
+	if !ok {
+		return nil, apperr.Newf(apperr.CodePermissionDenied, "no authorization rule for %s", method)
+	}
+
+	payload, err := server.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	setLogUsername(ctx, payload.Username)
+	if !payload.HasScope(scope) {
+		return nil, apperr.Newf(apperr.CodePermissionDenied, "token is missing the %s scope", scope)
+	}
+	return payload, nil
+}
+
+// authenticate checks the credentials in the request metadata and returns the caller.
+func (server *Server) authenticate(ctx context.Context) (*token.Payload, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, apperr.New(apperr.CodeUnauthenticated, "missing metadata")
+	}
+
+	// The header should be of the form
+	// authorization: bearer <token>
+	// or, for machine clients,
+	// authorization: apikey <key>
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return nil, apperr.New(apperr.CodeUnauthenticated, "authorization header not found")
+	}
+	fields := strings.Fields(values[0])
+	if len(fields) != 2 {
+		return nil, apperr.New(apperr.CodeUnauthenticated, "invalid authorization header format")
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case authorizationTypeBearer:
+		payload, err := server.maker.VerifyToken(fields[1], token.AccessToken)
+		if err != nil {
+			return nil, apperr.Wrap(err, apperr.CodeUnauthenticated, "invalid access token: "+err.Error())
+		}
+		return payload, nil
+	case authorizationTypeAPIKey:
+		apiKey, err := apikey.Authenticate(ctx, server.store, fields[1])
+		if err != nil {
+			if errors.Is(err, apikey.ErrInvalidKey) || errors.Is(err, apikey.ErrRevokedKey) || errors.Is(err, apikey.ErrExpiredKey) {
+				return nil, apperr.Wrap(err, apperr.CodeUnauthenticated, "invalid api key: "+err.Error())
+			}
+			return nil, apperr.Internal(err)
+		}
+		return apikey.NewPayload(apiKey), nil
+	}
+	return nil, apperr.Newf(apperr.CodeUnauthenticated, "unsupported authorization type: %s", fields[0])
+}
@@ -0,0 +1,91 @@
+package gapi
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// TrustedProxies are the proxies whose X-Forwarded-For the servers believe, as set in
+// TRUSTED_PROXIES. Any other hop could have written whatever it liked in the header.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses addresses and CIDR ranges, such as 10.0.0.0/8 or ::1.
+func ParseTrustedProxies(proxies []string) (TrustedProxies, error) {
+	var trusted TrustedProxies
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		trusted = append(trusted, ipNet)
+	}
+	return trusted, nil
+}
+
+// loopbackProxies stand for the gateway when it dials the gRPC server over loopback.
+var loopbackProxies = TrustedProxies{
+	{IP: net.IPv4(127, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 8*net.IPv4len)},
+	{IP: net.IPv6loopback, Mask: net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)},
+}
+
+func (proxies TrustedProxies) trusts(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client behind remote, the address the request came from.
+// While that is a trusted proxy, the client is the address the proxy appended to hops, the
+// X-Forwarded-For chain, and so on to the left. Hops added by anyone else are never believed.
+func (proxies TrustedProxies) clientIP(remote string, hops []string) string {
+	ip := clientHost(remote)
+	for i := len(hops) - 1; i >= 0 && proxies.trusts(ip); i-- {
+		ip = clientHost(strings.TrimSpace(hops[i]))
+	}
+	return ip
+}
+
+// forwardedHops splits X-Forwarded-For values into the addresses of the hops they list.
+func forwardedHops(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// clientHost drops the port from a peer address, so that every connection from a host shares a bucket.
+func clientHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
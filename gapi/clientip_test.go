@@ -0,0 +1,59 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.1", "::1", ""})
+	require.NoError(t, err)
+	require.Len(t, proxies, 3)
+	require.True(t, proxies.trusts("10.1.2.3"))
+	require.True(t, proxies.trusts("192.0.2.1"))
+	require.False(t, proxies.trusts("192.0.2.2"))
+	require.True(t, proxies.trusts("::1"))
+	require.False(t, proxies.trusts("not an ip"))
+
+	_, err = ParseTrustedProxies([]string{"10.0.0.300"})
+	require.Error(t, err)
+	_, err = ParseTrustedProxies([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+}
+
+func TestExtractMetaDataClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	server := &Server{proxies: proxies}
+
+	testCases := []struct {
+		name      string
+		peer      string
+		forwarded string
+		clientIP  string
+	}{
+		{"Peer", "203.0.113.7", "", "203.0.113.7"},
+		{"ForgedByPeer", "203.0.113.7", "198.51.100.1", "203.0.113.7"},
+		{"TrustedProxy", "10.0.0.1", "198.51.100.1", "198.51.100.1"},
+		{"ForgedBehindTrustedProxy", "10.0.0.1", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+		{"TrustedProxies", "10.0.0.1", "198.51.100.1, 10.0.0.2", "198.51.100.1"},
+		{"InProcessGateway", "", "198.51.100.1", "198.51.100.1"},
+		{"ForgedThroughInProcessGateway", "", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+		{"InProcessGatewayBehindTrustedProxy", "", "198.51.100.1, 10.0.0.1", "198.51.100.1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.peer != "" {
+				ctx = peerContext(tc.peer)
+			}
+			if tc.forwarded != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(gRPCForwaredFor, tc.forwarded))
+			}
+			require.Equal(t, tc.clientIP, server.extractMetaData(ctx).clientIP)
+		})
+	}
+}
@@ -1,8 +1,14 @@
 package gapi
 
 import (
+	"encoding/json"
+	"fmt"
+
 	db "github.com/ashokmouli/simplebank/db/sqlc"
 	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/txlimit"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -14,4 +20,91 @@ func convertUser(user db.User) *pb.User {
 		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
 		CreatedAt: timestamppb.New(user.CreatedAt),
 	}
-}
\ No newline at end of file
+}
+
+func convertPayee(payee db.Payee) *pb.Payee {
+	return &pb.Payee{
+		Id:        payee.ID,
+		Label:     payee.Label,
+		AccountId: payee.AccountID.Int64,
+		Username:  payee.Username.String,
+		Currency:  payee.Currency,
+		CreatedAt: timestamppb.New(payee.CreatedAt),
+	}
+}
+
+func convertTransfer(transfer db.Transfer, currency string) *pb.Transfer {
+	return &pb.Transfer{
+		Id:            transfer.ID,
+		FromAccountId: transfer.FromAccount,
+		ToAccountId:   transfer.ToAccount,
+		Amount:        transfer.Amount,
+		Currency:      currency,
+		Status:        transfer.Status,
+		Type:          transfer.Type,
+		CreatedAt:     timestamppb.New(transfer.CreatedAt),
+	}
+}
+
+func convertLimitWindow(window txlimit.Window) *pb.LimitWindow {
+	return &pb.LimitWindow{
+		Limit:     window.Limit,
+		Used:      window.Used,
+		Remaining: window.Remaining,
+		Scope:     window.Scope,
+	}
+}
+
+func convertAPIKey(apiKey db.APIKey) *pb.APIKey {
+	rsp := &pb.APIKey{
+		Id:        apiKey.ID.String(),
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: timestamppb.New(apiKey.CreatedAt),
+	}
+	if apiKey.ExpiresAt.Valid {
+		rsp.ExpiresAt = timestamppb.New(apiKey.ExpiresAt.Time)
+	}
+	if apiKey.LastUsedAt.Valid {
+		rsp.LastUsedAt = timestamppb.New(apiKey.LastUsedAt.Time)
+	}
+	if apiKey.RevokedAt.Valid {
+		rsp.RevokedAt = timestamppb.New(apiKey.RevokedAt.Time)
+	}
+	return rsp
+}
+
+func convertAuditLog(entry db.AuditLog) (*pb.AuditLogEntry, error) {
+	before, err := convertAuditState(entry.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := convertAuditState(entry.After)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.AuditLogEntry{
+		Id:           entry.ID,
+		Actor:        entry.Actor,
+		Action:       entry.Action,
+		Outcome:      entry.Outcome,
+		ResourceType: entry.ResourceType,
+		ResourceId:   entry.ResourceID,
+		ClientIp:     entry.ClientIp,
+		UserAgent:    entry.UserAgent,
+		RequestId:    entry.RequestID,
+		Before:       before,
+		After:        after,
+		OccurredAt:   timestamppb.New(entry.OccurredAt),
+	}, nil
+}
+
+// convertAuditState turns the JSON object stored for a resource's state into a Struct.
+func convertAuditState(state json.RawMessage) (*structpb.Struct, error) {
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(state, s); err != nil {
+		return nil, fmt.Errorf("cannot decode audit state: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,40 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/status"
+)
+
+// GatewayErrorHandler writes errors from the gateway in the same envelope as the Gin API, so that
+// REST clients see one error format whichever server they talk to.
+func GatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	appErr := apperr.FromStatus(status.Convert(err))
+
+	// Headers set by the handler, such as retry-after, still reach the client.
+	if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+		for key, values := range md.HeaderMD {
+			if name, ok := OutgoingHeaderMatcher(key); ok {
+				for _, value := range values {
+					w.Header().Add(name, value)
+				}
+			}
+		}
+	}
+
+	body, marshalErr := marshaler.Marshal(appErr.Envelope())
+	if marshalErr != nil {
+		log.Error().Err(marshalErr).Msg("cannot marshal error response")
+		http.Error(w, `{"error":{"code":"internal","message":"internal error"}}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", marshaler.ContentType(appErr.Envelope()))
+	w.WriteHeader(appErr.HTTPStatus())
+	if _, err := w.Write(body); err != nil {
+		log.Error().Err(err).Msg("cannot write error response")
+	}
+}
@@ -0,0 +1,32 @@
+package gapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestGatewayErrorHandler(t *testing.T) {
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{
+		HeaderMD: metadata.Pairs(retryAfterHeader, "30"),
+	})
+	err := apperr.InvalidArgument(apperr.Violation("username", "is required"))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/create_user", nil)
+	GatewayErrorHandler(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, rec, req, err)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+	var body apperr.Envelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, err.Envelope(), body)
+}
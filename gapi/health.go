@@ -0,0 +1,128 @@
+package gapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// readinessTimeout bounds the database checks behind a readiness probe.
+const readinessTimeout = 2 * time.Second
+
+// Health answers the liveness and readiness probes, over HTTP on the gateway and over the standard
+// grpc.health.v1 service on the gRPC server.
+type Health struct {
+	store         db.Store
+	schemaVersion uint
+	grpcHealth    *health.Server
+	draining      atomic.Bool
+}
+
+// NewHealth returns a Health that reports ready once the database is reachable and migrated to
+// schemaVersion.
+func NewHealth(store db.Store, schemaVersion uint) *Health {
+	return &Health{
+		store:         store,
+		schemaVersion: schemaVersion,
+		grpcHealth:    health.NewServer(),
+	}
+}
+
+// GRPCServer returns the grpc.health.v1 service to register on the gRPC server.
+func (h *Health) GRPCServer() healthpb.HealthServer {
+	return h.grpcHealth
+}
+
+// CheckReady returns why the service cannot take traffic, or nil if it can.
+func (h *Health) CheckReady(ctx context.Context) error {
+	if h.draining.Load() {
+		return fmt.Errorf("server is shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	if err := h.store.Ping(ctx); err != nil {
+		return fmt.Errorf("cannot reach database: %w", err)
+	}
+	version, dirty, err := h.store.SchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot read schema version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("schema version %d is dirty", version)
+	}
+	if version != h.schemaVersion {
+		return fmt.Errorf("schema version is %d, want %d", version, h.schemaVersion)
+	}
+	return nil
+}
+
+// Watch keeps the gRPC serving status in step with CheckReady until ctx is done.
+func (h *Health) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.updateGRPCStatus(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Health) updateGRPCStatus(ctx context.Context) {
+	if h.draining.Load() {
+		return
+	}
+	status := healthpb.HealthCheckResponse_SERVING
+	if err := h.CheckReady(ctx); err != nil {
+		log.Warn().Err(err).Msg("server is not ready")
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	// The empty service name stands for the server as a whole.
+	h.grpcHealth.SetServingStatus("", status)
+}
+
+// Drain marks the service as shutting down, so that the probes stop sending it new traffic, then
+// waits for period to give the load balancer time to notice before the caller stops its server.
+// Both servers drain at once, so they wait out the same period.
+func (h *Health) Drain(period time.Duration) {
+	h.draining.Store(true)
+	h.grpcHealth.Shutdown()
+	time.Sleep(period)
+}
+
+// HandleLive answers the liveness probe. The process is alive as long as it can serve it.
+func (h *Health) HandleLive(res http.ResponseWriter, req *http.Request) {
+	writeHealth(res, http.StatusOK, nil)
+}
+
+// HandleReady answers the readiness probe.
+func (h *Health) HandleReady(res http.ResponseWriter, req *http.Request) {
+	if err := h.CheckReady(req.Context()); err != nil {
+		writeHealth(res, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeHealth(res, http.StatusOK, nil)
+}
+
+func writeHealth(res http.ResponseWriter, statusCode int, err error) {
+	body := map[string]string{"status": "ok"}
+	if err != nil {
+		body = map[string]string{"status": "unavailable", "error": err.Error()}
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(statusCode)
+	json.NewEncoder(res).Encode(body)
+}
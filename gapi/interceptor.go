@@ -0,0 +1,25 @@
+package gapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// AuthInterceptor enforces the scopes declared in rpcScopes and passes the caller on to the handler.
+func (server *Server) AuthInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if scope, ok := rpcScopes[info.FullMethod]; ok && scope == "" {
+		return handler(ctx, req)
+	}
+
+	payload, err := server.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, authPayloadKey{}, payload), req)
+}
@@ -0,0 +1,155 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	requestIDHeader    = "x-request-id"
+	forwardedForHeader = "x-forwarded-for"
+)
+
+// requestLog collects what the request log line needs from deeper in the call, such as the caller
+// once the request has been authorized.
+type requestLog struct {
+	requestID string
+	username  string
+	route     string
+}
+
+type requestLogKey struct{}
+
+func withRequestLog(ctx context.Context, requestID string) (context.Context, *requestLog) {
+	// The in-process gateway hands the HTTP request context to the handler, so reuse the
+	// HTTP logger's entry instead of starting a second one.
+	if rl, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		return ctx, rl
+	}
+	rl := &requestLog{requestID: requestID}
+	return context.WithValue(ctx, requestLogKey{}, rl), rl
+}
+
+// setLogUsername records the authorized caller on the request log line, if there is one.
+func setLogUsername(ctx context.Context, username string) {
+	if rl, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		rl.username = username
+	}
+}
+
+func newRequestID(id string) string {
+	if id != "" {
+		return id
+	}
+	return uuid.NewString()
+}
+
+// GrpcLogger logs every unary gRPC call.
+func (server *Server) GrpcLogger(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	startTime := time.Now()
+
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(requestIDHeader); len(ids) > 0 {
+			requestID = ids[0]
+		}
+	}
+	ctx, rl := withRequestLog(ctx, newRequestID(requestID))
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, rl.requestID))
+
+	result, err := handler(ctx, req)
+	duration := time.Since(startTime)
+
+	statusCode := status.Code(err)
+	logger := log.WithLevel(grpcLogLevel(statusCode))
+	if err != nil {
+		logger = logger.Err(err)
+	}
+
+	logger.Str("protocol", "grpc").
+		Str("request_id", rl.requestID).
+		Str("method", info.FullMethod).
+		Int("status_code", int(statusCode)).
+		Str("status_text", statusCode.String()).
+		Dur("duration", duration).
+		Str("username", rl.username).
+		Str("client_ip", server.extractMetaData(ctx).clientIP).
+		Msg("received a gRPC request")
+
+	return result, err
+}
+
+func grpcLogLevel(code codes.Code) zerolog.Level {
+	switch code {
+	case codes.OK:
+		return zerolog.InfoLevel
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return zerolog.ErrorLevel
+	}
+	return zerolog.WarnLevel
+}
+
+// responseRecorder remembers the status code written by the wrapped handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+// HttpLogger logs every request served by the HTTP gateway.
+func (server *Server) HttpLogger(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		startTime := time.Now()
+
+		ctx, rl := withRequestLog(req.Context(), newRequestID(req.Header.Get(requestIDHeader)))
+		res.Header().Set(requestIDHeader, rl.requestID)
+
+		rec := &responseRecorder{
+			ResponseWriter: res,
+			statusCode:     http.StatusOK,
+		}
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+		duration := time.Since(startTime)
+
+		logger := log.WithLevel(httpLogLevel(rec.statusCode))
+		logger.Str("protocol", "http").
+			Str("request_id", rl.requestID).
+			Str("method", req.Method).
+			Str("path", req.URL.Path).
+			Str("route", rl.route).
+			Int("status_code", rec.statusCode).
+			Str("status_text", http.StatusText(rec.statusCode)).
+			Dur("duration", duration).
+			Str("username", rl.username).
+			Str("client_ip", server.proxies.clientIP(req.RemoteAddr, forwardedHops(req.Header.Values(forwardedForHeader)))).
+			Msg("received a HTTP request")
+	})
+}
+
+func httpLogLevel(statusCode int) zerolog.Level {
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		return zerolog.ErrorLevel
+	case statusCode >= http.StatusBadRequest:
+		return zerolog.WarnLevel
+	}
+	return zerolog.InfoLevel
+}
@@ -0,0 +1,69 @@
+package gapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = defaultLogger }()
+
+	// The request comes from the test's 192.0.2.1 through a proxy at 10.0.0.2.
+	proxies, err := ParseTrustedProxies([]string{"192.0.2.0/24", "10.0.0.2"})
+	require.NoError(t, err)
+	server := &Server{proxies: proxies}
+	handler := server.HttpLogger(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		setLogUsername(req.Context(), "alice")
+		res.WriteHeader(http.StatusNotFound)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/list_api_keys", nil)
+	req.Header.Set(requestIDHeader, "abc-123")
+	req.Header.Set(forwardedForHeader, "10.0.0.1, 10.0.0.2")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "abc-123", rec.Header().Get(requestIDHeader))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "warn", entry["level"])
+	require.Equal(t, "http", entry["protocol"])
+	require.Equal(t, "abc-123", entry["request_id"])
+	require.Equal(t, "GET", entry["method"])
+	require.Equal(t, "/v1/list_api_keys", entry["path"])
+	require.Equal(t, float64(http.StatusNotFound), entry["status_code"])
+	require.Equal(t, "alice", entry["username"])
+	require.Equal(t, "10.0.0.1", entry["client_ip"])
+}
+
+func TestHttpLoggerGeneratesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = defaultLogger }()
+
+	handler := (&Server{}).HttpLogger(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/login_user", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, rec.Header().Get(requestIDHeader))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "info", entry["level"])
+	require.Equal(t, rec.Header().Get(requestIDHeader), entry["request_id"])
+}
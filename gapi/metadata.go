@@ -2,7 +2,6 @@ package gapi
 
 import (
 	"context"
-	"fmt"
 
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
@@ -19,31 +18,34 @@ type metaData struct {
 	clientIP  string
 }
 
-func extractMetaData(ctx context.Context) *metaData {
+// extractMetaData describes the caller of an RPC. The client IP is only taken from
+// x-forwarded-for when the hop that added it can be trusted: the in-process gateway, or a peer
+// listed in TRUSTED_PROXIES. Anyone else could put any address there.
+func (server *Server) extractMetaData(ctx context.Context) *metaData {
 
 	mtd := &metaData{}
+	var hops []string
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		fmt.Printf("%+v", md)
-
-		// If coming through gateway, parse out the user agent.
-		if userAgents := md.Get(gRPCGWUserAgent); len(userAgents) > 0 {
-			mtd.userAgent = userAgents[0]
-		}
-
 		// If coming through GRPC client, parse out the user agent.
 		if userAgents := md.Get(gRPCUserAgent); len(userAgents) > 0 {
 			mtd.userAgent = userAgents[0]
 		}
-		// Parse out the client ip if coming through gateway.
-		if clientIPs := md.Get(gRPCForwaredFor); len(clientIPs) > 0 {
-			mtd.clientIP = clientIPs[0]
+
+		// If coming through gateway, parse out the user agent. It wins over the gRPC user agent,
+		// which is the gateway's own when the gateway dials the gRPC server.
+		if userAgents := md.Get(gRPCGWUserAgent); len(userAgents) > 0 {
+			mtd.userAgent = userAgents[0]
 		}
+		hops = forwardedHops(md.Get(gRPCForwaredFor))
 	}
 
-	// The client ip is available through peer package if coming through gRPC client.
 	if peer, ok := peer.FromContext(ctx); ok {
-		addr := peer.LocalAddr.String()
-		mtd.clientIP = addr
+		// Over gRPC the peer is the client, unless it is a proxy trusted to say who the client is.
+		mtd.clientIP = server.proxies.clientIP(peer.Addr.String(), hops)
+	} else if len(hops) > 0 {
+		// The in-process gateway has no peer. It appends the address of its HTTP client to
+		// x-forwarded-for, so the last hop is as good as a peer address.
+		mtd.clientIP = server.proxies.clientIP(hops[len(hops)-1], hops[:len(hops)-1])
 	}
 
 	return mtd
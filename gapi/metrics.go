@@ -0,0 +1,79 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ashokmouli/simplebank/metrics"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// unmatchedRoute labels gateway requests that did not match any route, so that arbitrary paths
+// cannot blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+// GrpcMetrics counts and times every unary gRPC call.
+func GrpcMetrics(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	startTime := time.Now()
+	result, err := handler(ctx, req)
+
+	metrics.GRPCRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	metrics.GRPCDuration.WithLabelValues(info.FullMethod).Observe(time.Since(startTime).Seconds())
+	return result, err
+}
+
+// GatewayMetadata runs on the gateway mux, through runtime.WithMetadata, once a request has matched
+// a route. It records the route for the metrics and the request's span, and forwards the request ID
+// so that the gRPC server logs the same ID when the gateway dials it.
+func GatewayMetadata(ctx context.Context, req *http.Request) metadata.MD {
+	rl, ok := ctx.Value(requestLogKey{}).(*requestLog)
+	if !ok {
+		return nil
+	}
+
+	if pattern, ok := runtime.HTTPPathPattern(ctx); ok {
+		rl.route = pattern
+
+		span := trace.SpanFromContext(ctx)
+		span.SetName(req.Method + " " + pattern)
+		span.SetAttributes(semconv.HTTPRoute(pattern))
+		if method, ok := runtime.RPCMethod(ctx); ok {
+			span.SetAttributes(semconv.RPCMethod(method))
+		}
+	}
+	return metadata.Pairs(requestIDHeader, rl.requestID)
+}
+
+// HttpMetrics counts and times every request served by the HTTP gateway, labelled by the route
+// pattern rather than the raw path.
+func HttpMetrics(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		startTime := time.Now()
+
+		ctx, rl := withRequestLog(req.Context(), newRequestID(req.Header.Get(requestIDHeader)))
+		rec := &responseRecorder{
+			ResponseWriter: res,
+			statusCode:     http.StatusOK,
+		}
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+
+		route := rl.route
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.HTTPRequests.WithLabelValues(req.Method, route, strconv.Itoa(rec.statusCode)).Inc()
+		metrics.HTTPDuration.WithLabelValues(req.Method, route).Observe(time.Since(startTime).Seconds())
+	})
+}
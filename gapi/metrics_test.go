@@ -0,0 +1,53 @@
+package gapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashokmouli/simplebank/metrics"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHttpMetrics(t *testing.T) {
+	mux := runtime.NewServeMux(runtime.WithMetadata(GatewayMetadata))
+
+	handler := HttpMetrics(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		// This is what the generated gateway handlers do once a route has matched.
+		ctx, err := runtime.AnnotateIncomingContext(req.Context(), mux, req, "/pb.SimpleBank/LoginUser",
+			runtime.WithHTTPPathPattern("/v1/login_user"))
+		require.NoError(t, err)
+
+		md, ok := metadata.FromIncomingContext(ctx)
+		require.True(t, ok)
+		require.Equal(t, []string{"abc-123"}, md.Get(requestIDHeader))
+		res.WriteHeader(http.StatusForbidden)
+	}))
+
+	counter := metrics.HTTPRequests.WithLabelValues(http.MethodPost, "/v1/login_user", "403")
+	before := testutil.ToFloat64(counter)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/login_user", nil)
+	req.Header.Set(requestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestHttpMetricsUnmatchedRoute(t *testing.T) {
+	handler := HttpMetrics(http.NotFoundHandler())
+
+	counter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")
+	before := testutil.ToFloat64(counter)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/no/such/route/123", nil))
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, before+1, testutil.ToFloat64(counter))
+}
@@ -0,0 +1,116 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/ratelimit"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const retryAfterHeader = "retry-after"
+
+// rpcRateLimits names the rate limit rule of each RPC. RPCs missing from the list share the
+// default rule, and an empty rule means no limit. The names match the Gin routes', so one
+// RATE_LIMITS setting covers both.
+var rpcRateLimits = map[string]string{
+	pb.SimpleBank_CreateUser_FullMethodName:     "create_user",
+	pb.SimpleBank_LoginUser_FullMethodName:      "login_user",
+	pb.SimpleBank_CreateAPIKey_FullMethodName:   "create_api_key",
+	pb.SimpleBank_ListAPIKeys_FullMethodName:    "list_api_keys",
+	pb.SimpleBank_RevokeAPIKey_FullMethodName:   "revoke_api_key",
+	pb.SimpleBank_ListAuditLog_FullMethodName:   "list_audit_log",
+	pb.SimpleBank_VerifyLedger_FullMethodName:   "verify_ledger",
+	pb.SimpleBank_CreatePayee_FullMethodName:    "create_payee",
+	pb.SimpleBank_ListPayees_FullMethodName:     "list_payees",
+	pb.SimpleBank_UpdatePayee_FullMethodName:    "update_payee",
+	pb.SimpleBank_DeletePayee_FullMethodName:    "delete_payee",
+	pb.SimpleBank_GetLimits_FullMethodName:      "get_limits",
+	pb.SimpleBank_CreateTransfer_FullMethodName: "transfer",
+
+	// Probes must never be throttled.
+	healthpb.Health_Check_FullMethodName: "",
+}
+
+type rateLimitedKey struct{}
+
+// RateLimitInterceptor limits how often a caller can use an RPC. It runs after AuthInterceptor so
+// that authenticated callers are counted by username.
+func (server *Server) RateLimitInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	var username string
+	if payload, ok := ctx.Value(authPayloadKey{}).(*token.Payload); ok {
+		username = payload.Username
+	}
+	if err := server.rateLimit(ctx, info.FullMethod, username); err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, rateLimitedKey{}, true), req)
+}
+
+// rateLimitPublic limits a public RPC called through the in-process gateway, which skips the
+// interceptors. Calls the interceptor has already let through are not counted again.
+func (server *Server) rateLimitPublic(ctx context.Context, method string) error {
+	if ctx.Value(rateLimitedKey{}) != nil {
+		return nil
+	}
+	return server.rateLimit(ctx, method, "")
+}
+
+// rateLimit takes a token for the caller, identified by username if there is one and by IP
+// address otherwise.
+func (server *Server) rateLimit(ctx context.Context, method string, username string) error {
+	rule, ok := rpcRateLimits[method]
+	if !ok {
+		rule = ratelimit.DefaultRule
+	}
+	if rule == "" {
+		return nil
+	}
+
+	caller := ratelimit.UserCaller(username)
+	if username == "" {
+		caller = ratelimit.IPCaller(server.extractMetaData(ctx).clientIP)
+	}
+
+	allowed, retryAfter, err := server.limiter.Allow(ctx, rule, caller)
+	if err != nil {
+		// Better to serve unthrottled than to fail every call while the backend is down.
+		log.Warn().Err(err).Str("rule", rule).Msg("cannot check rate limit")
+		return nil
+	}
+	if allowed {
+		return nil
+	}
+
+	// The gateway turns the header into Retry-After; gRPC clients can also read RetryInfo.
+	grpc.SetHeader(ctx, metadata.Pairs(retryAfterHeader, ratelimit.RetryAfterSeconds(retryAfter)))
+	limitErr := apperr.Wrap(ratelimit.ErrLimitExceeded, apperr.CodeRateLimited, ratelimit.ErrLimitExceeded.Error())
+	st, err := limitErr.GRPCStatus().WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return limitErr
+	}
+	return st.Err()
+}
+
+// OutgoingHeaderMatcher lets the gateway send the retry-after header as the standard Retry-After
+// instead of prefixing it like other gRPC metadata.
+func OutgoingHeaderMatcher(key string) (string, bool) {
+	if key == retryAfterHeader {
+		return "Retry-After", true
+	}
+	return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+}
@@ -0,0 +1,107 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/ratelimit"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func newRateLimitedServer(limit ratelimit.Limit) *Server {
+	return &Server{
+		limiter: ratelimit.New(ratelimit.NewMemoryBackend(), map[string]ratelimit.Limit{
+			ratelimit.DefaultRule: limit,
+		}),
+	}
+}
+
+func peerContext(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000},
+	})
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	server := newRateLimitedServer(ratelimit.Limit{Rate: 1.0 / 60, Burst: 1})
+	info := &grpc.UnaryServerInfo{FullMethod: pb.SimpleBank_LoginUser_FullMethodName}
+
+	var handlerCtx context.Context
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerCtx = ctx
+		return "ok", nil
+	}
+
+	_, err := server.RateLimitInterceptor(peerContext("10.0.0.1"), nil, info, handler)
+	require.NoError(t, err)
+
+	// The handler knows the call has been counted and does not count it again.
+	require.NoError(t, server.rateLimitPublic(handlerCtx, pb.SimpleBank_LoginUser_FullMethodName))
+
+	_, err = server.RateLimitInterceptor(peerContext("10.0.0.1"), nil, info, handler)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	var retryInfo *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			retryInfo = d
+		}
+	}
+	require.NotNil(t, retryInfo)
+	require.InDelta(t, time.Minute, retryInfo.RetryDelay.AsDuration(), float64(time.Millisecond))
+
+	// Another address, or an authenticated caller, has a bucket of its own.
+	_, err = server.RateLimitInterceptor(peerContext("10.0.0.2"), nil, info, handler)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(peerContext("10.0.0.1"), authPayloadKey{}, &token.Payload{Username: "alice"})
+	_, err = server.RateLimitInterceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+}
+
+func TestRateLimitPublic(t *testing.T) {
+	server := newRateLimitedServer(ratelimit.Limit{Rate: 1.0 / 60, Burst: 1})
+
+	// Calls from the in-process gateway are counted by the handler itself.
+	require.NoError(t, server.rateLimitPublic(peerContext("10.0.0.1"), pb.SimpleBank_CreateUser_FullMethodName))
+	err := server.rateLimitPublic(peerContext("10.0.0.1"), pb.SimpleBank_CreateUser_FullMethodName)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestOutgoingHeaderMatcher(t *testing.T) {
+	header, ok := OutgoingHeaderMatcher(retryAfterHeader)
+	require.True(t, ok)
+	require.Equal(t, "Retry-After", header)
+
+	header, ok = OutgoingHeaderMatcher(requestIDHeader)
+	require.True(t, ok)
+	require.Equal(t, "Grpc-Metadata-x-request-id", header)
+}
+
+func TestRateLimitForgedForwardedFor(t *testing.T) {
+	server := newRateLimitedServer(ratelimit.Limit{Rate: 1.0 / 60, Burst: 1})
+	info := &grpc.UnaryServerInfo{FullMethod: pb.SimpleBank_LoginUser_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	forwarded := func(ip string) context.Context {
+		return metadata.NewIncomingContext(peerContext("203.0.113.1"), metadata.Pairs(gRPCForwaredFor, ip))
+	}
+
+	// The peer is not a trusted proxy, so what it claims to forward doesn't change its bucket.
+	_, err := server.RateLimitInterceptor(forwarded("198.51.100.1"), nil, info, handler)
+	require.NoError(t, err)
+	_, err = server.RateLimitInterceptor(forwarded("198.51.100.2"), nil, info, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
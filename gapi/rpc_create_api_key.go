@@ -0,0 +1,67 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ashokmouli/simplebank/apikey"
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/google/uuid"
+)
+
+func (server *Server) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	payload, err := server.authorizeUser(ctx, pb.SimpleBank_CreateAPIKey_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCreateAPIKeyRequest(req, payload.Scopes); err != nil {
+		return nil, err
+	}
+
+	key, prefix, hashedSecret, err := apikey.Generate()
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	var expiresAt sql.NullTime
+	if req.GetExpiresInDays() > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, int(req.GetExpiresInDays())), Valid: true}
+	}
+
+	apiKey, err := server.store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		ID:           id,
+		Username:     payload.Username,
+		Name:         req.GetName(),
+		Prefix:       prefix,
+		HashedSecret: hashedSecret,
+		Scopes:       req.GetScopes(),
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		return nil, apperr.FromDB(err, "api key")
+	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionAPIKeyCreate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceAPIKey,
+		ResourceID:   apiKey.ID.String(),
+		Client:       server.auditClient(ctx),
+		After:        convertAPIKey(apiKey),
+	})
+
+	rsp := &pb.CreateAPIKeyResponse{
+		Key:    key,
+		ApiKey: convertAPIKey(apiKey),
+	}
+	return rsp, nil
+}
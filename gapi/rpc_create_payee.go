@@ -0,0 +1,53 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/recipient"
+)
+
+func (server *Server) CreatePayee(ctx context.Context, req *pb.CreatePayeeRequest) (*pb.CreatePayeeResponse, error) {
+	payload, err := server.authorizeUser(ctx, pb.SimpleBank_CreatePayee_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCreatePayeeRequest(req); err != nil {
+		return nil, err
+	}
+	currency, err := recipient.PayeeCurrency(ctx, server.store, req.GetAccountId(), req.GetUsername(), req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	payee, err := server.store.CreatePayee(ctx, db.CreatePayeeParams{
+		Owner:     payload.Username,
+		Label:     req.GetLabel(),
+		AccountID: sql.NullInt64{Int64: req.GetAccountId(), Valid: req.GetAccountId() != 0},
+		Username:  sql.NullString{String: req.GetUsername(), Valid: req.GetUsername() != ""},
+		Currency:  currency,
+	})
+	if err != nil {
+		return nil, apperr.FromDB(err, "payee")
+	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionPayeeCreate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourcePayee,
+		ResourceID:   strconv.FormatInt(payee.ID, 10),
+		Client:       server.auditClient(ctx),
+		After:        convertPayee(payee),
+	})
+
+	rsp := &pb.CreatePayeeResponse{
+		Payee: convertPayee(payee),
+	}
+	return rsp, nil
+}
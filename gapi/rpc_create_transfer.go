@@ -0,0 +1,146 @@
+package gapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/metrics"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/recipient"
+	"github.com/ashokmouli/simplebank/risk"
+	"github.com/ashokmouli/simplebank/txlimit"
+)
+
+func (server *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	payload, err := server.authorizeUser(ctx, pb.SimpleBank_CreateTransfer_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCreateTransferRequest(req); err != nil {
+		return nil, err
+	}
+	to := recipient.Request{
+		AccountID: req.GetToAccountId(),
+		Username:  req.GetToUsername(),
+		Email:     req.GetToEmail(),
+		PayeeID:   req.GetPayeeId(),
+		Currency:  req.GetCurrency(),
+	}
+	if err := to.ExpandPayee(ctx, server.store, payload.Username); err != nil {
+		return nil, err
+	}
+	account, err := recipient.Account(ctx, server.store, "from_account_id", req.GetFromAccountId(), to.Currency)
+	if err != nil {
+		return nil, err
+	}
+	payee, err := to.Resolve(ctx, server.store)
+	if err != nil {
+		return nil, err
+	}
+
+	event := audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionTransferCreate,
+		ResourceType: audit.ResourceTransfer,
+		Client:       server.auditClient(ctx),
+	}
+	if account.Owner != payload.Username {
+		// Record the attempt against the account it tried to take money from.
+		event.Outcome = audit.OutcomeFailure
+		event.ResourceType = audit.ResourceAccount
+		event.ResourceID = strconv.FormatInt(account.ID, 10)
+		audit.Record(ctx, server.store, event)
+		return nil, apperr.New(apperr.CodePermissionDenied, "account does not belong to the authenticated user")
+	}
+
+	limits := server.transferLimits[to.Currency]
+	input := db.TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   payee.AccountID,
+		Amount:        req.GetAmount(),
+		Limits:        &limits,
+	}
+
+	// Suspicious transfers are refused or wait for a banker instead of posting.
+	assessment, err := server.screener.Screen(ctx, risk.Transfer{
+		Username:      payload.Username,
+		FromAccountID: input.FromAccountID,
+		ToAccountID:   input.ToAccountID,
+		ToOwner:       payee.Owner,
+		Amount:        input.Amount,
+		Currency:      to.Currency,
+		ClientIP:      event.Client.IP,
+		At:            time.Now(),
+	}, risk.StoreHistory{Store: server.store})
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	metrics.ObserveScreening(assessment.Verdict.String())
+	switch assessment.Verdict {
+	case risk.Deny:
+		event.Outcome = audit.OutcomeFailure
+		event.ResourceType = audit.ResourceAccount
+		event.ResourceID = strconv.FormatInt(account.ID, 10)
+		event.After = assessment
+		audit.Record(ctx, server.store, event)
+		return nil, apperr.New(apperr.CodePermissionDenied, "the transfer was declined")
+	case risk.Review:
+		// The transfer waits for a banker, holding its amount; the sender is not told which rules
+		// matched. The hold is recorded in the audit log in the same transaction, with the findings.
+		findings, err := json.Marshal(assessment.Findings)
+		if err != nil {
+			return nil, apperr.Internal(err)
+		}
+		input.Review = &db.CreateTransferReviewParams{
+			RequestedBy: payload.Username,
+			ClientIp:    event.Client.IP,
+			Findings:    findings,
+			ExpiresAt:   time.Now().Add(server.config.TransferReviewTTL),
+		}
+		event.Action = audit.ActionTransferHold
+		event.After = assessment
+	}
+
+	// The transfer is recorded in the audit log in the same transaction as the transfer itself.
+	event.Outcome = audit.OutcomeSuccess
+	auditEntry, err := event.Params()
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	input.Audit = &auditEntry
+
+	results, err := server.store.TransferTx(ctx, &input)
+	if err != nil {
+		return nil, transferError(err)
+	}
+	rsp := &pb.CreateTransferResponse{
+		Transfer: convertTransfer(results.Transfer, to.Currency),
+		Balance:  results.FromAccount.Balance,
+	}
+	if results.Review != nil {
+		rsp.ReviewId = results.Review.ID
+		return rsp, nil
+	}
+	metrics.ObserveTransfer(to.Currency, input.Amount)
+	if results.Fee != nil {
+		rsp.Fee = results.Fee.Transfer.Amount
+	}
+	return rsp, nil
+}
+
+// transferError maps the errors the store returns for transfers that the accounts' status,
+// balance or limits don't allow, as the Gin API does.
+func transferError(err error) *apperr.Error {
+	if errors.Is(err, db.ErrAccountNotActive) || errors.Is(err, txlimit.ErrExceeded) ||
+		errors.Is(err, db.ErrFundsHeld) || errors.Is(err, db.ErrSystemAccount) {
+		return apperr.Wrap(err, apperr.CodeFailedPrecondition, err.Error())
+	}
+	return apperr.FromDB(err, "account")
+}
@@ -0,0 +1,140 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/risk"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	from := db.Account{ID: 1, Owner: "bob", Balance: 100, Currency: util.USD, Status: db.AccountActive}
+	payee := db.GetPayeeAccountRow{ID: 2, Owner: "jane", Status: db.AccountActive}
+	saved := db.Payee{ID: 7, Owner: from.Owner, Username: sql.NullString{String: payee.Owner, Valid: true}, Currency: util.USD}
+	req := &pb.CreateTransferRequest{FromAccountId: from.ID, PayeeId: saved.ID, Amount: 10}
+
+	testCases := []struct {
+		name       string
+		caller     string
+		rules      string
+		buildStore func(store *mockdb.MockStore)
+		check      func(t *testing.T, rsp *pb.CreateTransferResponse, err error)
+	}{
+		{
+			name:   "PayeeID",
+			caller: from.Owner,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, arg *db.TransferTxParams) (db.TransferTxResults, error) {
+						require.Equal(t, payee.ID, arg.ToAccountID)
+						require.NotNil(t, arg.Limits)
+						require.Nil(t, arg.Review)
+						return db.TransferTxResults{
+							Transfer:    db.Transfer{ID: 3, FromAccount: from.ID, ToAccount: payee.ID, Amount: 10, Status: db.TransferPosted},
+							FromAccount: db.Account{ID: from.ID, Balance: 89},
+							Fee:         &db.FeeCharge{Transfer: db.Transfer{Amount: 1}},
+						}, nil
+					}).Times(1)
+			},
+			check: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, payee.ID, rsp.GetTransfer().GetToAccountId())
+				require.Equal(t, util.USD, rsp.GetTransfer().GetCurrency())
+				require.Equal(t, int64(1), rsp.GetFee())
+				require.Equal(t, int64(89), rsp.GetBalance())
+				require.Zero(t, rsp.GetReviewId())
+			},
+		},
+		{
+			name:   "Review",
+			caller: from.Owner,
+			rules:  "large=10",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, arg *db.TransferTxParams) (db.TransferTxResults, error) {
+						require.NotNil(t, arg.Review)
+						require.Equal(t, from.Owner, arg.Review.RequestedBy)
+						return db.TransferTxResults{
+							Transfer: db.Transfer{ID: 3, Status: db.TransferPending},
+							Review:   &db.TransferReview{ID: 4},
+						}, nil
+					}).Times(1)
+			},
+			check: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, int64(4), rsp.GetReviewId())
+				require.Equal(t, db.TransferPending, rsp.GetTransfer().GetStatus())
+			},
+		},
+		{
+			name:   "NotOwner",
+			caller: "mallory",
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.Equal(t, apperr.CodePermissionDenied, apperr.From(err).Code)
+			},
+		},
+		{
+			name:   "SystemAccount",
+			caller: from.Owner,
+			buildStore: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Return(db.TransferTxResults{}, fmt.Errorf("account 2: %w", db.ErrSystemAccount)).Times(1)
+			},
+			check: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.Equal(t, apperr.CodeFailedPrecondition, apperr.From(err).Code)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			// The payee is resolved the way the Gin API resolves it.
+			store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(db.GetPayeeParams{ID: saved.ID, Owner: tc.caller})).
+				Return(saved, nil).Times(1)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Return(from, nil).Times(1)
+			store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Eq(db.GetPayeeAccountParams{
+				Username: saved.Username,
+				Currency: util.USD,
+			})).Return(payee, nil).Times(1)
+			tc.buildStore(store)
+
+			rules, err := risk.Parse(tc.rules)
+			require.NoError(t, err)
+			server := &Server{
+				store:    store,
+				config:   util.Config{TransferReviewTTL: time.Hour},
+				screener: risk.New(rules...),
+			}
+			payload, err := token.NewPayload(tc.caller, token.UserScopes, token.AccessToken, time.Minute)
+			require.NoError(t, err)
+			ctx := context.WithValue(context.Background(), authPayloadKey{}, payload)
+
+			rsp, err := server.CreateTransfer(ctx, req)
+			tc.check(t, rsp, err)
+		})
+	}
+}
+
+func TestCreateTransferValidation(t *testing.T) {
+	require.NoError(t, validateCreateTransferRequest(&pb.CreateTransferRequest{FromAccountId: 1, PayeeId: 7, Amount: 10}))
+	err := validateCreateTransferRequest(&pb.CreateTransferRequest{ToEmail: "not-an-email", Amount: -1, Currency: "XYZ"})
+	require.ElementsMatch(t, []string{"from_account_id", "to_email", "amount", "currency"}, violatedFields(t, err))
+}
@@ -3,19 +3,23 @@ package gapi
 import (
 	"context"
 
+	"github.com/ashokmouli/simplebank/apperr"
 	db "github.com/ashokmouli/simplebank/db/sqlc"
-	"github.com/ashokmouli/simplebank/db/util"
 	"github.com/ashokmouli/simplebank/pb"
-	"github.com/lib/pq"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
 
-	hashedPassword, err := util.HashPassword(req.GetPassword())
+	if err := server.rateLimitPublic(ctx, pb.SimpleBank_CreateUser_FullMethodName); err != nil {
+		return nil, err
+	}
+	if err := validateCreateUserRequest(req, server.passwordPolicy); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := server.passwords.Hash(req.GetPassword())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
+		return nil, apperr.Internal(err)
 	}
 
 	user, err := server.store.CreateUser(ctx, db.CreateUserParams{
@@ -26,13 +30,7 @@ func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 	})
 
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				return nil, status.Errorf(codes.AlreadyExists, "username exists: %s", err)
-			}
-		}
-		return nil, status.Errorf(codes.Internal, "failed to create user: %s", err)
+		return nil, apperr.FromDB(err, "user")
 	}
 
 	rsp := &pb.CreateUserResponse {
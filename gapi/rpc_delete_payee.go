@@ -0,0 +1,55 @@
+package gapi
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/val"
+)
+
+func (server *Server) DeletePayee(ctx context.Context, req *pb.DeletePayeeRequest) (*pb.DeletePayeeResponse, error) {
+	payload, err := server.authorizeUser(ctx, pb.SimpleBank_DeletePayee_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := val.ValidateID(req.GetId()); err != nil {
+		return nil, apperr.InvalidArgument(apperr.Violation("id", err.Error()))
+	}
+
+	payee, err := server.store.GetPayee(ctx, db.GetPayeeParams{
+		ID:    req.GetId(),
+		Owner: payload.Username,
+	})
+	if err != nil {
+		return nil, apperr.FromDB(err, "payee")
+	}
+	rows, err := server.store.DeletePayee(ctx, db.DeletePayeeParams{
+		ID:    payee.ID,
+		Owner: payee.Owner,
+	})
+	if err != nil {
+		return nil, apperr.FromDB(err, "payee")
+	}
+	if rows == 0 {
+		return nil, apperr.New(apperr.CodeNotFound, "payee not found")
+	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionPayeeDelete,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourcePayee,
+		ResourceID:   strconv.FormatInt(payee.ID, 10),
+		Client:       server.auditClient(ctx),
+		Before:       convertPayee(payee),
+	})
+
+	rsp := &pb.DeletePayeeResponse{
+		Payee: convertPayee(payee),
+	}
+	return rsp, nil
+}
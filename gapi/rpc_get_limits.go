@@ -0,0 +1,44 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/ashokmouli/simplebank/val"
+)
+
+func (server *Server) GetLimits(ctx context.Context, req *pb.GetLimitsRequest) (*pb.GetLimitsResponse, error) {
+	payload, err := server.authorizeUser(ctx, pb.SimpleBank_GetLimits_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := val.ValidateID(req.GetAccountId()); err != nil {
+		return nil, apperr.InvalidArgument(apperr.Violation("account_id", err.Error()))
+	}
+
+	account, err := server.store.GetAccount(ctx, req.GetAccountId())
+	if err != nil {
+		return nil, apperr.FromDB(err, "account")
+	}
+	// Bankers can look at any account.
+	if account.Owner != payload.Username && !payload.HasScope(token.ScopeAccountsAdmin) {
+		return nil, apperr.New(apperr.CodePermissionDenied, "account does not belong to the authenticated user")
+	}
+
+	allowance, err := server.store.TransferAllowance(ctx, account, server.transferLimits[account.Currency])
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	rsp := &pb.GetLimitsResponse{
+		AccountId: allowance.AccountID,
+		Currency:  allowance.Currency,
+		Single:    convertLimitWindow(allowance.Single),
+		Daily:     convertLimitWindow(allowance.Daily),
+		Monthly:   convertLimitWindow(allowance.Monthly),
+	}
+	return rsp, nil
+}
@@ -0,0 +1,26 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/pb"
+)
+
+func (server *Server) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	payload, err := server.authorizeUser(ctx, pb.SimpleBank_ListAPIKeys_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := server.store.ListAPIKeys(ctx, payload.Username)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	rsp := &pb.ListAPIKeysResponse{}
+	for _, apiKey := range apiKeys {
+		rsp.ApiKeys = append(rsp.ApiKeys, convertAPIKey(apiKey))
+	}
+	return rsp, nil
+}
@@ -0,0 +1,51 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/pb"
+)
+
+func (server *Server) ListAuditLog(ctx context.Context, req *pb.ListAuditLogRequest) (*pb.ListAuditLogResponse, error) {
+	if _, err := server.authorizeUser(ctx, pb.SimpleBank_ListAuditLog_FullMethodName); err != nil {
+		return nil, err
+	}
+
+	if err := validateListAuditLogRequest(req); err != nil {
+		return nil, err
+	}
+
+	arg := db.ListAuditLogParams{
+		Actor:        audit.NullString(req.GetActor()),
+		Action:       audit.NullString(req.GetAction()),
+		ResourceType: audit.NullString(req.GetResourceType()),
+		ResourceID:   audit.NullString(req.GetResourceId()),
+		PageLimit:    req.GetPageSize(),
+		PageOffset:   (req.GetPageId() - 1) * req.GetPageSize(),
+	}
+	if req.Since != nil {
+		arg.Since = sql.NullTime{Time: req.GetSince().AsTime(), Valid: true}
+	}
+	if req.Until != nil {
+		arg.Until = sql.NullTime{Time: req.GetUntil().AsTime(), Valid: true}
+	}
+
+	entries, err := server.store.ListAuditLog(ctx, arg)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	rsp := &pb.ListAuditLogResponse{}
+	for _, entry := range entries {
+		converted, err := convertAuditLog(entry)
+		if err != nil {
+			return nil, apperr.Internal(err)
+		}
+		rsp.Entries = append(rsp.Entries, converted)
+	}
+	return rsp, nil
+}
@@ -0,0 +1,35 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/pb"
+)
+
+func (server *Server) ListPayees(ctx context.Context, req *pb.ListPayeesRequest) (*pb.ListPayeesResponse, error) {
+	payload, err := server.authorizeUser(ctx, pb.SimpleBank_ListPayees_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateListPayeesRequest(req); err != nil {
+		return nil, err
+	}
+
+	payees, err := server.store.ListPayees(ctx, db.ListPayeesParams{
+		Owner:  payload.Username,
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	rsp := &pb.ListPayeesResponse{}
+	for _, payee := range payees {
+		rsp.Payees = append(rsp.Payees, convertPayee(payee))
+	}
+	return rsp, nil
+}
@@ -3,60 +3,100 @@ package gapi
 import (
 	"context"
 	"database/sql"
+	"errors"
 
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
 	db "github.com/ashokmouli/simplebank/db/sqlc"
-	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/metrics"
+	"github.com/ashokmouli/simplebank/password"
 	"github.com/ashokmouli/simplebank/pb"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/ashokmouli/simplebank/token"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
 
+	if err := server.rateLimitPublic(ctx, pb.SimpleBank_LoginUser_FullMethodName); err != nil {
+		return nil, err
+	}
+	if err := validateLoginUserRequest(req); err != nil {
+		return nil, err
+	}
+
 	// Fetch the user object from DB
 	user, err := server.store.GetUser(ctx, req.GetUsername())
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Errorf(codes.NotFound, "user does not exist: %s", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			metrics.ObserveLogin(false)
+			server.auditLogin(ctx, req.GetUsername(), audit.OutcomeFailure)
 		}
-		return nil, status.Errorf(codes.Internal, "internal error: %s", err)
+		return nil, apperr.FromDB(err, "user")
 	}
 
 	// Check the password.
-	err = util.CheckPassword(req.Password, user.HashedPassword)
+	rehash, err := server.passwords.Check(req.GetPassword(), user.HashedPassword)
 	if err != nil {
-		return nil, status.Errorf(codes.PermissionDenied, "password mismatch: %s", err)
+		if errors.Is(err, password.ErrMismatch) {
+			metrics.ObserveLogin(false)
+			server.auditLogin(ctx, req.GetUsername(), audit.OutcomeFailure)
+			return nil, apperr.Wrap(err, apperr.CodePermissionDenied, "password mismatch")
+		}
+		return nil, apperr.Internal(err)
+	}
+	if rehash {
+		server.passwords.Rehash(ctx, server.store, user, req.GetPassword())
+	}
+
+	// Tokens get all the scopes of the user's role unless the caller asks for fewer.
+	scopes := token.RoleScopes(user.Role)
+	if len(req.GetScopes()) > 0 {
+		if err := token.CheckScopes(req.GetScopes(), scopes); err != nil {
+			return nil, apperr.Wrap(err, apperr.CodePermissionDenied, err.Error())
+		}
+		scopes = req.GetScopes()
 	}
 
 	// Create access token.
-	accessToken, accessPayload, err := server.maker.CreateToken(req.GetUsername(), server.config.AccessTokenDuration)
+	accessToken, accessPayload, err := server.maker.CreateToken(req.GetUsername(), scopes, token.AccessToken, server.config.AccessTokenDuration)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "could not create access token error: %s", err)
+		return nil, apperr.Internal(err)
 	}
 
 	// Create refresh token.
-	refresh_token, refreshPayload, err := server.maker.CreateToken(req.Username, server.config.RefreshTokenDuration)
+	refresh_token, refreshPayload, err := server.maker.CreateToken(req.Username, scopes, token.RefreshToken, server.config.RefreshTokenDuration)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "could not create refresh token error: %s", err)
+		return nil, apperr.Internal(err)
 	}
 
-	metaData := extractMetaData(ctx)
+	metaData := server.extractMetaData(ctx)
 
 	// Create a session record.
 	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
 		ID:           refreshPayload.ID,
 		Username:     req.GetUsername(),
 		IsBlocked:    false,
-		ClientIp:     sql.NullString{String: metaData.userAgent, Valid: true},
-		UserAgent:    sql.NullString{String: metaData.clientIP, Valid: true},
+		ClientIp:     sql.NullString{String: metaData.clientIP, Valid: true},
+		UserAgent:    sql.NullString{String: metaData.userAgent, Valid: true},
 		RefreshToken: refresh_token,
 		ExpiresAt:    refreshPayload.ExpiredAt,
 	})
 
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "could not create session error: %s", err)
+		return nil, apperr.Internal(err)
 	}
 
+	metrics.ObserveLogin(true)
+	server.auditLogin(ctx, req.GetUsername(), audit.OutcomeSuccess)
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        user.Username,
+		Action:       audit.ActionSessionCreate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceSession,
+		ResourceID:   session.ID.String(),
+		Client:       server.auditClient(ctx),
+		After:        map[string]interface{}{"expires_at": session.ExpiresAt, "scopes": scopes},
+	})
+
 	// Marshal back the response.
 
 	rsp := &pb.LoginUserResponse{
@@ -68,4 +108,4 @@ func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (
 		User: convertUser(user),
 	}
 	return rsp, nil
-}
\ No newline at end of file
+}
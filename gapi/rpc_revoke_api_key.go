@@ -0,0 +1,52 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/google/uuid"
+)
+
+func (server *Server) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	payload, err := server.authorizeUser(ctx, pb.SimpleBank_RevokeAPIKey_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateRevokeAPIKeyRequest(req); err != nil {
+		return nil, err
+	}
+	id := uuid.MustParse(req.GetId())
+
+	// Users can only revoke their own keys, so a key owned by someone else looks the same as a missing one.
+	apiKey, err := server.store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		ID:       id,
+		Username: payload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.Wrap(err, apperr.CodeNotFound, "api key not found or already revoked")
+		}
+		return nil, apperr.FromDB(err, "api key")
+	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionAPIKeyRevoke,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceAPIKey,
+		ResourceID:   apiKey.ID.String(),
+		Client:       server.auditClient(ctx),
+		Before:       map[string]interface{}{"revoked_at": nil},
+		After:        map[string]interface{}{"revoked_at": apiKey.RevokedAt.Time},
+	})
+
+	rsp := &pb.RevokeAPIKeyResponse{
+		ApiKey: convertAPIKey(apiKey),
+	}
+	return rsp, nil
+}
@@ -0,0 +1,63 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/audit"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/recipient"
+)
+
+func (server *Server) UpdatePayee(ctx context.Context, req *pb.UpdatePayeeRequest) (*pb.UpdatePayeeResponse, error) {
+	payload, err := server.authorizeUser(ctx, pb.SimpleBank_UpdatePayee_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateUpdatePayeeRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Users can only see their own payees, so someone else's looks the same as a missing one.
+	payee, err := server.store.GetPayee(ctx, db.GetPayeeParams{
+		ID:    req.GetId(),
+		Owner: payload.Username,
+	})
+	if err != nil {
+		return nil, apperr.FromDB(err, "payee")
+	}
+	if req.GetCurrency() != "" && req.GetCurrency() != payee.Currency {
+		if _, err := recipient.PayeeCurrency(ctx, server.store, payee.AccountID.Int64, payee.Username.String, req.GetCurrency()); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := server.store.UpdatePayee(ctx, db.UpdatePayeeParams{
+		ID:       payee.ID,
+		Owner:    payee.Owner,
+		Label:    sql.NullString{String: req.GetLabel(), Valid: req.GetLabel() != ""},
+		Currency: sql.NullString{String: req.GetCurrency(), Valid: req.GetCurrency() != ""},
+	})
+	if err != nil {
+		return nil, apperr.FromDB(err, "payee")
+	}
+	audit.Record(ctx, server.store, audit.Event{
+		Actor:        payload.Username,
+		Action:       audit.ActionPayeeUpdate,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourcePayee,
+		ResourceID:   strconv.FormatInt(payee.ID, 10),
+		Client:       server.auditClient(ctx),
+		Before:       convertPayee(payee),
+		After:        convertPayee(updated),
+	})
+
+	rsp := &pb.UpdatePayeeResponse{
+		Payee: convertPayee(updated),
+	}
+	return rsp, nil
+}
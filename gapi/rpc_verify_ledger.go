@@ -0,0 +1,37 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/ledger"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/val"
+)
+
+func (server *Server) VerifyLedger(ctx context.Context, req *pb.VerifyLedgerRequest) (*pb.VerifyLedgerResponse, error) {
+	if _, err := server.authorizeUser(ctx, pb.SimpleBank_VerifyLedger_FullMethodName); err != nil {
+		return nil, err
+	}
+
+	if err := val.ValidateID(req.GetAccountId()); err != nil {
+		return nil, apperr.InvalidArgument(apperr.Violation("account_id", err.Error()))
+	}
+
+	report, err := ledger.Verify(ctx, server.store, []byte(server.config.LedgerKey), req.GetAccountId())
+	if err != nil {
+		return nil, apperr.FromDB(err, "account")
+	}
+
+	rsp := &pb.VerifyLedgerResponse{
+		AccountId:     report.AccountID,
+		Entries:       report.Entries,
+		LegacyEntries: report.LegacyEntries,
+		Ok:            report.OK(),
+	}
+	if !report.OK() {
+		rsp.BrokenEntryId = report.BrokenLink.EntryID
+		rsp.BrokenReason = report.BrokenLink.Reason
+	}
+	return rsp, nil
+}
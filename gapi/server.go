@@ -5,31 +5,83 @@ import (
 
 	db "github.com/ashokmouli/simplebank/db/sqlc"
 	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/password"
 	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/ratelimit"
+	"github.com/ashokmouli/simplebank/risk"
 	"github.com/ashokmouli/simplebank/token"
+	"github.com/ashokmouli/simplebank/txlimit"
 )
 
 type Server struct {
 	pb.UnimplementedSimpleBankServer
-	store  db.Store
-	maker  token.Maker
-	config util.Config
+	store          db.Store
+	maker          token.Maker
+	config         util.Config
+	limiter        *ratelimit.Limiter
+	passwords      *password.Hashing
+	passwordPolicy *password.Policy
+	// transferLimits are the default limits of every user, by currency.
+	transferLimits map[string]txlimit.Limits
+	// screener runs the risk rules over transfers before they post.
+	screener *risk.Screener
+	// proxies are the hops whose x-forwarded-for is believed.
+	proxies TrustedProxies
 }
 
 // Server serves gRPC requests for our banking service
 func NewServer(store db.Store, config util.Config) (*Server, error) {
 
 	// Create a token maker
-	maker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	maker, err := token.NewMaker(config.TokenMaker, config.TokenSymmetricKey, config.TokenIssuer, config.TokenAudience)
 	if err != nil {
 		err := fmt.Errorf("could not create a new token interface, %w", err)
 		return nil, err
 	}
 
+	limits, err := ratelimit.ParseLimits(config.RateLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	passwords, err := password.NewHashing(config.PasswordHasher)
+	if err != nil {
+		return nil, err
+	}
+	passwordPolicy, err := password.NewPolicy(config.PasswordMinLength, config.PasswordMinClasses, config.PasswordCommonList)
+	if err != nil {
+		return nil, err
+	}
+
+	transferLimits, err := txlimit.Parse(config.TransferLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	riskRules, err := risk.Parse(config.RiskRules)
+	if err != nil {
+		return nil, err
+	}
+
+	proxies, err := ParseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	// A dialing gateway reaches the gRPC server over loopback and forwards its clients' addresses.
+	if config.GatewayMode == "dial" {
+		proxies = append(proxies, loopbackProxies...)
+	}
+
 	server := &Server{
-		store:  store,
-		config: config,
-		maker:  maker,
+		store:          store,
+		config:         config,
+		maker:          maker,
+		limiter:        ratelimit.New(ratelimit.NewMemoryBackend(), limits),
+		passwords:      passwords,
+		passwordPolicy: passwordPolicy,
+		transferLimits: transferLimits,
+		screener:       risk.New(riskRules...),
+		proxies:        proxies,
 	}
 
 	return server, nil
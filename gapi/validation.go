@@ -0,0 +1,144 @@
+package gapi
+
+import (
+	"github.com/ashokmouli/simplebank/apperr"
+	"github.com/ashokmouli/simplebank/password"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/ashokmouli/simplebank/val"
+	"github.com/google/uuid"
+)
+
+// Every RPC checks its request here before touching the store. The rules come from the val
+// package, which the Gin API checks as well.
+
+// violations collects the fields of a request that break the rules.
+type violations []apperr.FieldViolation
+
+func (v *violations) check(field string, err error) {
+	if err != nil {
+		*v = append(*v, apperr.Violation(field, err.Error()))
+	}
+}
+
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return apperr.InvalidArgument(v...)
+}
+
+func validateCreateUserRequest(req *pb.CreateUserRequest, policy *password.Policy) error {
+	var v violations
+	v.check("username", val.ValidateUsername(req.GetUsername()))
+	v.check("full_name", val.ValidateFullName(req.GetFullName()))
+	v.check("email", val.ValidateEmail(req.GetEmail()))
+	v.check("password", policy.Validate(req.GetPassword(), req.GetUsername()))
+	return v.err()
+}
+
+func validateLoginUserRequest(req *pb.LoginUserRequest) error {
+	var v violations
+	v.check("username", val.ValidateUsername(req.GetUsername()))
+	v.check("password", val.ValidateLoginPassword(req.GetPassword()))
+	if len(req.GetScopes()) > 0 {
+		v.check("scopes", token.CheckScopes(req.GetScopes(), token.BankerScopes))
+	}
+	return v.err()
+}
+
+func validateCreateAPIKeyRequest(req *pb.CreateAPIKeyRequest, held []string) error {
+	var v violations
+	v.check("name", val.ValidateString(req.GetName(), 1, 100))
+	if len(req.GetScopes()) == 0 {
+		v = append(v, apperr.Violation("scopes", "at least one scope is required"))
+	} else {
+		v.check("scopes", token.CheckAPIKeyScopes(req.GetScopes(), held))
+	}
+	if req.GetExpiresInDays() < 0 {
+		v = append(v, apperr.Violation("expires_in_days", "must not be negative"))
+	}
+	return v.err()
+}
+
+func validateRevokeAPIKeyRequest(req *pb.RevokeAPIKeyRequest) error {
+	if _, err := uuid.Parse(req.GetId()); err != nil {
+		return apperr.InvalidArgument(apperr.Violation("id", "must be a UUID"))
+	}
+	return nil
+}
+
+func validateListAuditLogRequest(req *pb.ListAuditLogRequest) error {
+	var v violations
+	if req.GetPageId() < 1 {
+		v = append(v, apperr.Violation("page_id", "must be at least 1"))
+	}
+	if req.GetPageSize() < 5 || req.GetPageSize() > 100 {
+		v = append(v, apperr.Violation("page_size", "must be between 5 and 100"))
+	}
+	return v.err()
+}
+
+func validateCreatePayeeRequest(req *pb.CreatePayeeRequest) error {
+	var v violations
+	v.check("label", val.ValidatePayeeLabel(req.GetLabel()))
+	if (req.GetAccountId() == 0) == (req.GetUsername() == "") {
+		v = append(v, apperr.Violation("account_id", "exactly one of account_id and username is required"))
+	} else if req.GetAccountId() != 0 {
+		v.check("account_id", val.ValidateID(req.GetAccountId()))
+	} else {
+		v.check("username", val.ValidateUsername(req.GetUsername()))
+		if req.GetCurrency() == "" {
+			v = append(v, apperr.Violation("currency", "is required with username"))
+		}
+	}
+	if req.GetCurrency() != "" {
+		v.check("currency", val.ValidateCurrency(req.GetCurrency()))
+	}
+	return v.err()
+}
+
+func validateCreateTransferRequest(req *pb.CreateTransferRequest) error {
+	var v violations
+	v.check("from_account_id", val.ValidateID(req.GetFromAccountId()))
+	if req.GetToAccountId() != 0 {
+		v.check("to_account_id", val.ValidateID(req.GetToAccountId()))
+	}
+	if req.GetToUsername() != "" {
+		v.check("to_username", val.ValidateUsername(req.GetToUsername()))
+	}
+	if req.GetToEmail() != "" {
+		v.check("to_email", val.ValidateEmail(req.GetToEmail()))
+	}
+	if req.GetPayeeId() != 0 {
+		v.check("payee_id", val.ValidateID(req.GetPayeeId()))
+	}
+	v.check("amount", val.ValidateAmount(req.GetAmount()))
+	if req.GetCurrency() != "" {
+		v.check("currency", val.ValidateCurrency(req.GetCurrency()))
+	}
+	return v.err()
+}
+
+func validateListPayeesRequest(req *pb.ListPayeesRequest) error {
+	var v violations
+	if req.GetPageId() < 1 {
+		v = append(v, apperr.Violation("page_id", "must be at least 1"))
+	}
+	if req.GetPageSize() < 5 || req.GetPageSize() > 50 {
+		v = append(v, apperr.Violation("page_size", "must be between 5 and 50"))
+	}
+	return v.err()
+}
+
+func validateUpdatePayeeRequest(req *pb.UpdatePayeeRequest) error {
+	var v violations
+	v.check("id", val.ValidateID(req.GetId()))
+	if req.GetLabel() != "" {
+		v.check("label", val.ValidatePayeeLabel(req.GetLabel()))
+	}
+	if req.GetCurrency() != "" {
+		v.check("currency", val.ValidateCurrency(req.GetCurrency()))
+	}
+	return v.err()
+}
@@ -0,0 +1,92 @@
+package gapi
+
+import (
+	"testing"
+
+	"github.com/ashokmouli/simplebank/password"
+	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/ratelimit"
+	"github.com/ashokmouli/simplebank/token"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// violatedFields returns the fields named by the BadRequest detail of err.
+func violatedFields(t *testing.T, err error) []string {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range badRequest.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+	return fields
+}
+
+func TestCreateUserValidation(t *testing.T) {
+	// No store: an invalid request must be turned away before reaching it.
+	server := newRateLimitedServer(ratelimit.Limit{Rate: 100, Burst: 100})
+	policy, err := password.NewPolicy(10, 3, "")
+	require.NoError(t, err)
+	server.passwordPolicy = policy
+
+	_, err = server.CreateUser(peerContext("10.0.0.1"), &pb.CreateUserRequest{
+		Username: "",
+		FullName: "Bob",
+		Email:    "not-an-email",
+		Password: "password123",
+	})
+	require.ElementsMatch(t, []string{"username", "email", "password"}, violatedFields(t, err))
+}
+
+func TestLoginUserValidation(t *testing.T) {
+	server := newRateLimitedServer(ratelimit.Limit{Rate: 100, Burst: 100})
+
+	_, err := server.LoginUser(peerContext("10.0.0.1"), &pb.LoginUserRequest{
+		Username: "bob smith",
+		Scopes:   []string{"everything"},
+	})
+	require.ElementsMatch(t, []string{"username", "password", "scopes"}, violatedFields(t, err))
+}
+
+func TestRevokeAPIKeyValidation(t *testing.T) {
+	require.NoError(t, validateRevokeAPIKeyRequest(&pb.RevokeAPIKeyRequest{Id: "5b1e8b4e-3d4f-4c8e-9a51-0d6a3f1c2e7b"}))
+	require.Equal(t, []string{"id"}, violatedFields(t, validateRevokeAPIKeyRequest(&pb.RevokeAPIKeyRequest{Id: "42"})))
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	req := &pb.CreateAPIKeyRequest{Name: "batch", Scopes: []string{token.ScopeTransfersWrite}}
+	require.NoError(t, validateCreateAPIKeyRequest(req, token.UserScopes))
+	// A token narrowed to managing keys can't mint a key that does more.
+	err := validateCreateAPIKeyRequest(req, []string{token.ScopeAPIKeysManage})
+	require.Equal(t, []string{"scopes"}, violatedFields(t, err))
+}
+
+func TestListAuditLogValidation(t *testing.T) {
+	require.NoError(t, validateListAuditLogRequest(&pb.ListAuditLogRequest{PageId: 1, PageSize: 5}))
+	require.ElementsMatch(t, []string{"page_id", "page_size"}, violatedFields(t, validateListAuditLogRequest(&pb.ListAuditLogRequest{PageSize: 500})))
+}
+
+func TestCreatePayeeValidation(t *testing.T) {
+	require.NoError(t, validateCreatePayeeRequest(&pb.CreatePayeeRequest{Label: "Mom", AccountId: 7}))
+	require.NoError(t, validateCreatePayeeRequest(&pb.CreatePayeeRequest{Label: "Mom", Username: "mom", Currency: "EUR"}))
+
+	err := validateCreatePayeeRequest(&pb.CreatePayeeRequest{Username: "mom"})
+	require.ElementsMatch(t, []string{"label", "currency"}, violatedFields(t, err))
+	err = validateCreatePayeeRequest(&pb.CreatePayeeRequest{Label: "Mom", AccountId: 7, Username: "mom", Currency: "XYZ"})
+	require.ElementsMatch(t, []string{"account_id", "currency"}, violatedFields(t, err))
+}
+
+func TestUpdatePayeeValidation(t *testing.T) {
+	// Empty fields are left unchanged.
+	require.NoError(t, validateUpdatePayeeRequest(&pb.UpdatePayeeRequest{Id: 1}))
+	err := validateUpdatePayeeRequest(&pb.UpdatePayeeRequest{Label: " Mom", Currency: "XYZ"})
+	require.ElementsMatch(t, []string{"id", "label", "currency"}, violatedFields(t, err))
+}
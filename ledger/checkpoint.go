@@ -0,0 +1,71 @@
+package ledger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"time"
+)
+
+// checkpointDomain keeps checkpoint MACs apart from entry hashes made with the same key.
+const checkpointDomain = "simplebank/checkpoint/v1"
+
+// ErrCheckpointMAC is returned for a checkpoint that was not signed with the ledger key, or was
+// changed since.
+var ErrCheckpointMAC = errors.New("checkpoint MAC does not match, it was changed or signed with another key")
+
+// Checkpoint is the heads of every chain at one time, signed with the ledger key. Kept outside
+// the database, it anchors the chains: an entry hashed into a checkpoint can't later be dropped
+// from the end of its chain without the checkpoint telling.
+type Checkpoint struct {
+	CreatedAt time.Time `json:"created_at"`
+	Heads     []Head    `json:"heads"`
+	MAC       []byte    `json:"mac"`
+}
+
+// NewCheckpoint signs heads with key.
+func NewCheckpoint(key []byte, heads []Head, createdAt time.Time) Checkpoint {
+	heads = append([]Head(nil), heads...)
+	sort.Slice(heads, func(i, j int) bool { return heads[i].AccountID < heads[j].AccountID })
+	c := Checkpoint{CreatedAt: createdAt.UTC(), Heads: heads}
+	c.MAC = c.mac(key)
+	return c
+}
+
+// Check returns ErrCheckpointMAC unless the checkpoint was signed with key.
+func (c Checkpoint) Check(key []byte) error {
+	if !hmac.Equal(c.MAC, c.mac(key)) {
+		return ErrCheckpointMAC
+	}
+	return nil
+}
+
+// HeadsByAccount returns the heads of the checkpoint by account ID.
+func (c Checkpoint) HeadsByAccount() map[int64]Head {
+	heads := make(map[int64]Head, len(c.Heads))
+	for _, head := range c.Heads {
+		heads[head.AccountID] = head
+	}
+	return heads
+}
+
+func (c Checkpoint) mac(key []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(checkpointDomain))
+	var buf [8]byte
+	writeInt := func(v int64) {
+		binary.BigEndian.PutUint64(buf[:], uint64(v))
+		h.Write(buf[:])
+	}
+	writeInt(c.CreatedAt.UnixNano())
+	writeInt(int64(len(c.Heads)))
+	for _, head := range c.Heads {
+		writeInt(head.AccountID)
+		writeInt(head.EntryID)
+		writeInt(int64(len(head.Hash)))
+		h.Write(head.Hash)
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,103 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	rows := chain(10, 20)
+	heads := []Head{
+		{AccountID: 9, EntryID: 5, Hash: []byte("nine")},
+		{AccountID: accountID, EntryID: rows[1].ID, Hash: rows[1].Hash},
+	}
+	checkpoint := NewCheckpoint(testKey, heads, time.Now())
+	require.NoError(t, checkpoint.Check(testKey))
+	require.Equal(t, int64(accountID), checkpoint.Heads[0].AccountID)
+	require.ErrorIs(t, checkpoint.Check([]byte("another key entirely, also 32 b.")), ErrCheckpointMAC)
+
+	tampered := checkpoint
+	tampered.Heads = append([]Head(nil), checkpoint.Heads...)
+	tampered.Heads[0].EntryID = 1
+	require.ErrorIs(t, tampered.Check(testKey), ErrCheckpointMAC)
+}
+
+// mockChain returns a store holding the chain of accountID made of rows, and the given balance.
+func mockChain(t *testing.T, rows []db.ListLedgerEntriesRow, balance int64) *mockdb.MockStore {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ReadTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(context.Context, db.Querier) error) error {
+			return fn(ctx, store)
+		}).AnyTimes()
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(db.Account{ID: accountID, Balance: balance}, nil).AnyTimes()
+	store.EXPECT().ListLedgerEntries(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+	return store
+}
+
+func TestVerifySince(t *testing.T) {
+	rows := chain(10, 20, -5)
+	head := Head{AccountID: accountID, EntryID: rows[2].ID, Hash: rows[2].Hash}
+
+	testCases := []struct {
+		name    string
+		rows    []db.ListLedgerEntriesRow
+		balance int64
+		brokeAt int64
+		reason  string
+	}{
+		{"ok", rows, 25, 0, ""},
+		{"Appended", chain(10, 20, -5, 1), 26, 0, ""},
+		// The balance is set back, so that without the checkpoint the chain would check out.
+		{"TailDropped", rows[:2], 30, 3, "missing"},
+		{"Rewritten", chain(10, 20, -4), 26, 3, "checkpoint"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mockChain(t, tc.rows, tc.balance)
+			report, err := VerifySince(context.Background(), store, testKey, accountID, &head)
+			require.NoError(t, err)
+			if tc.brokeAt == 0 {
+				require.True(t, report.OK(), report.String())
+				return
+			}
+			require.False(t, report.OK())
+			require.Equal(t, tc.brokeAt, report.BrokenLink.EntryID)
+			require.Contains(t, report.BrokenLink.Reason, tc.reason)
+		})
+	}
+}
+
+func TestRunCommandCheckpoint(t *testing.T) {
+	rows := chain(10, 20, -5)
+	store := mockChain(t, rows, 25)
+	store.EXPECT().ListAccountIDs(gomock.Any(), gomock.Any()).Return([]int64{accountID}, nil).AnyTimes()
+
+	var out bytes.Buffer
+	require.NoError(t, RunCommand(context.Background(), store, testKey, []string{"checkpoint"}, &out))
+	file := filepath.Join(t.TempDir(), "checkpoint.json")
+	require.NoError(t, os.WriteFile(file, out.Bytes(), 0o600))
+
+	out.Reset()
+	require.NoError(t, RunCommand(context.Background(), store, testKey, []string{"-checkpoint", file}, &out))
+	require.Contains(t, out.String(), "ok, 3 entries checked")
+
+	// The last entry is dropped and the balance set back.
+	store = mockChain(t, rows[:2], 30)
+	err := RunCommand(context.Background(), store, testKey, []string{"-checkpoint", file, "7"}, &out)
+	require.EqualError(t, err, "1 broken ledger chains")
+
+	err = RunCommand(context.Background(), store, []byte("another key entirely, also 32 b."), []string{"-checkpoint", file}, &out)
+	require.ErrorIs(t, err, ErrCheckpointMAC)
+}
@@ -0,0 +1,125 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+)
+
+// Usage describes the arguments RunCommand accepts.
+const Usage = "verify-ledger [-checkpoint FILE] [ACCOUNT_ID...] | verify-ledger checkpoint"
+
+// RunCommand verifies the chains of the given accounts, or of every account when none is given,
+// and writes a line per account to out. It fails if any chain is broken. With -checkpoint, the
+// chains must also still hold the heads recorded in the checkpoint FILE.
+//
+// "verify-ledger checkpoint" verifies every chain and writes a checkpoint of their heads to out
+// instead, to be kept where the database's users can't write.
+func RunCommand(ctx context.Context, store db.Store, key []byte, args []string, out io.Writer) error {
+	if len(args) > 0 && args[0] == "checkpoint" {
+		if len(args) > 1 {
+			return fmt.Errorf("unexpected arguments %q, usage: %s", args[1:], Usage)
+		}
+		return writeCheckpoint(ctx, store, key, out)
+	}
+
+	flags := flag.NewFlagSet("verify-ledger", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	checkpointFile := flags.String("checkpoint", "", "checkpoint the chains must still hold")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w, usage: %s", err, Usage)
+	}
+	args = flags.Args()
+
+	var heads map[int64]Head
+	if *checkpointFile != "" {
+		checkpoint, err := readCheckpoint(*checkpointFile, key)
+		if err != nil {
+			return err
+		}
+		heads = checkpoint.HeadsByAccount()
+	}
+
+	if len(args) == 0 {
+		broken, err := VerifyAll(ctx, store, key, heads, func(report Report) {
+			fmt.Fprintln(out, report)
+		})
+		if err != nil {
+			return err
+		}
+		if broken > 0 {
+			return fmt.Errorf("%d broken ledger chains", broken)
+		}
+		return nil
+	}
+
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid account id %q, usage: %s", arg, Usage)
+		}
+		ids = append(ids, id)
+	}
+	broken := 0
+	for _, id := range ids {
+		var head *Head
+		if h, ok := heads[id]; ok {
+			head = &h
+		}
+		report, err := VerifySince(ctx, store, key, id, head)
+		if err != nil {
+			return fmt.Errorf("cannot verify account %d: %w", id, err)
+		}
+		if !report.OK() {
+			broken++
+		}
+		fmt.Fprintln(out, report)
+	}
+	if broken > 0 {
+		return fmt.Errorf("%d broken ledger chains", broken)
+	}
+	return nil
+}
+
+// writeCheckpoint checkpoints the heads of every chain, as long as all of them check out.
+func writeCheckpoint(ctx context.Context, store db.Store, key []byte, out io.Writer) error {
+	var heads []Head
+	broken, err := VerifyAll(ctx, store, key, nil, func(report Report) {
+		if report.OK() && report.Head != nil {
+			heads = append(heads, *report.Head)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if broken > 0 {
+		return fmt.Errorf("%d broken ledger chains, not checkpointing them", broken)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewCheckpoint(key, heads, time.Now()))
+}
+
+func readCheckpoint(name string, key []byte) (Checkpoint, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("cannot read checkpoint: %w", err)
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return Checkpoint{}, fmt.Errorf("cannot parse checkpoint %s: %w", name, err)
+	}
+	if err := checkpoint.Check(key); err != nil {
+		return Checkpoint{}, fmt.Errorf("checkpoint %s: %w", name, err)
+	}
+	return checkpoint, nil
+}
@@ -0,0 +1,218 @@
+// Package ledger checks the hash chain that links the entries of every account, to prove that
+// entries, transfers and balances were not edited after the fact. The chain is keyed with
+// LEDGER_KEY, and checkpoints of its heads, kept outside the database, catch entries dropped
+// from the end of a chain.
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"fmt"
+
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+)
+
+// pageSize is how many entries or accounts are read at a time.
+const pageSize = 500
+
+// BrokenLink is the first entry of a chain that does not check out.
+type BrokenLink struct {
+	EntryID int64  `json:"entry_id"`
+	Reason  string `json:"reason"`
+}
+
+// Head is the last entry of a chain.
+type Head struct {
+	AccountID int64  `json:"account_id"`
+	EntryID   int64  `json:"entry_id"`
+	Hash      []byte `json:"hash"`
+}
+
+// Report is the result of verifying the chain of one account.
+type Report struct {
+	AccountID int64 `json:"account_id"`
+	// Entries is how many chained entries were checked.
+	Entries int64 `json:"entries"`
+	// LegacyEntries were made before the chain existed and can't be checked.
+	LegacyEntries int64       `json:"legacy_entries"`
+	BrokenLink    *BrokenLink `json:"broken_link,omitempty"`
+	// Head is the last entry checked, if any.
+	Head *Head `json:"head,omitempty"`
+}
+
+// OK reports whether the whole chain checked out.
+func (r Report) OK() bool {
+	return r.BrokenLink == nil
+}
+
+func (r Report) String() string {
+	if r.OK() {
+		return fmt.Sprintf("account %d: ok, %d entries checked, %d legacy entries", r.AccountID, r.Entries, r.LegacyEntries)
+	}
+	return fmt.Sprintf("account %d: broken at entry %d: %s", r.AccountID, r.BrokenLink.EntryID, r.BrokenLink.Reason)
+}
+
+// Verify walks the chain of an account in one snapshot of the database, and stops at the first
+// broken link. Once the entries check out, the account's balance must be the last entry's.
+func Verify(ctx context.Context, store db.Store, key []byte, accountID int64) (Report, error) {
+	return VerifySince(ctx, store, key, accountID, nil)
+}
+
+// VerifySince verifies the chain of an account like Verify, and also that it still holds head,
+// the head of the chain when it was checkpointed. Without it, dropping the last entries of a chain
+// and setting the balance back would go unnoticed.
+func VerifySince(ctx context.Context, store db.Store, key []byte, accountID int64, head *Head) (Report, error) {
+	var report Report
+	err := store.ReadTx(ctx, func(ctx context.Context, q db.Querier) error {
+		var err error
+		report, err = verifyChain(ctx, q, key, accountID, head)
+		return err
+	})
+	return report, err
+}
+
+// VerifyAll verifies every account in turn and hands each report to fn. heads are the
+// checkpointed heads of the chains, if any; a checkpointed account that no longer exists is
+// reported broken. It returns how many chains are broken.
+func VerifyAll(ctx context.Context, store db.Store, key []byte, heads map[int64]Head, fn func(Report)) (int, error) {
+	broken := 0
+	seen := make(map[int64]bool, len(heads))
+	var afterID int64
+	for {
+		ids, err := store.ListAccountIDs(ctx, db.ListAccountIDsParams{AfterID: afterID, PageLimit: pageSize})
+		if err != nil {
+			return broken, fmt.Errorf("cannot list accounts: %w", err)
+		}
+		for _, id := range ids {
+			var head *Head
+			if h, ok := heads[id]; ok {
+				head = &h
+				seen[id] = true
+			}
+			report, err := VerifySince(ctx, store, key, id, head)
+			if err != nil {
+				return broken, fmt.Errorf("cannot verify account %d: %w", id, err)
+			}
+			if !report.OK() {
+				broken++
+			}
+			fn(report)
+		}
+		if len(ids) < pageSize {
+			break
+		}
+		afterID = ids[len(ids)-1]
+	}
+
+	for id, head := range heads {
+		if seen[id] {
+			continue
+		}
+		broken++
+		fn(Report{
+			AccountID:  id,
+			BrokenLink: &BrokenLink{EntryID: head.EntryID, Reason: "account was removed after the checkpoint"},
+		})
+	}
+	return broken, nil
+}
+
+func verifyChain(ctx context.Context, q db.Querier, key []byte, accountID int64, head *Head) (Report, error) {
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{AccountID: accountID}
+	prev := db.GenesisHash
+	var balance, lastID int64
+	var afterID int64
+	headSeen := false
+	for {
+		rows, err := q.ListLedgerEntries(ctx, db.ListLedgerEntriesParams{
+			AccountID: accountID,
+			AfterID:   afterID,
+			PageLimit: pageSize,
+		})
+		if err != nil {
+			return Report{}, err
+		}
+		for _, row := range rows {
+			// The chain starts after the entries made before it existed.
+			if row.Hash == nil && report.Entries == 0 {
+				report.LegacyEntries++
+				continue
+			}
+			if reason := checkLink(row, key, prev, balance, report.Entries == 0); reason != "" {
+				report.BrokenLink = &BrokenLink{EntryID: row.ID, Reason: reason}
+				return report, nil
+			}
+			if head != nil && row.ID == head.EntryID {
+				if !bytes.Equal(row.Hash, head.Hash) {
+					report.BrokenLink = &BrokenLink{EntryID: row.ID, Reason: "hash does not match the checkpoint, the chain was rewritten"}
+					return report, nil
+				}
+				headSeen = true
+			}
+			report.Entries++
+			prev = row.Hash
+			balance = row.Balance.Int64
+			lastID = row.ID
+		}
+		if len(rows) < pageSize {
+			break
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+
+	if head != nil && !headSeen {
+		report.BrokenLink = &BrokenLink{
+			EntryID: head.EntryID,
+			Reason:  "checkpointed entry is missing, entries were removed from the chain",
+		}
+		return report, nil
+	}
+	if report.Entries > 0 {
+		report.Head = &Head{AccountID: accountID, EntryID: lastID, Hash: prev}
+	}
+	// A balance changed outside the ledger doesn't match the last entry.
+	if report.Entries > 0 && account.Balance != balance {
+		report.BrokenLink = &BrokenLink{
+			EntryID: lastID,
+			Reason:  fmt.Sprintf("account balance %d does not match the balance %d after the last entry", account.Balance, balance),
+		}
+	}
+	return report, nil
+}
+
+// checkLink returns why row does not follow the entry whose hash is prev and that left the account
+// with balance, or "" if it does.
+func checkLink(row db.ListLedgerEntriesRow, key []byte, prev []byte, balance int64, first bool) string {
+	if row.Hash == nil {
+		return "entry has no hash"
+	}
+	if !bytes.Equal(row.PrevHash, prev) {
+		return "prev_hash does not match the previous entry, which was changed or removed"
+	}
+	if !row.Balance.Valid {
+		return "entry has no balance"
+	}
+	if !first && row.Balance.Int64 != balance+row.Amount {
+		return fmt.Sprintf("balance %d does not follow from the previous balance %d and amount %d", row.Balance.Int64, balance, row.Amount)
+	}
+	entry := db.ChainedEntry{
+		AccountID:           row.AccountID,
+		Amount:              row.Amount,
+		Balance:             row.Balance.Int64,
+		CreatedAt:           row.CreatedAt,
+		TransferID:          row.TransferID.Int64,
+		TransferFromAccount: row.TransferFromAccount,
+		TransferToAccount:   row.TransferToAccount,
+		TransferAmount:      row.TransferAmount,
+	}
+	if !hmac.Equal(row.Hash, entry.Hash(key, prev)) {
+		return "hash does not match the entry or its transfer, which was changed or hashed without the ledger key"
+	}
+	return ""
+}
@@ -0,0 +1,247 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const accountID = 7
+
+var testKey = []byte("abcdefghijabcdefghijabcdefghijab")
+
+// chain returns entries of accountID with the given amounts, linked the way TransferTx links them,
+// starting from a balance of 0.
+func chain(amounts ...int64) []db.ListLedgerEntriesRow {
+	rows := make([]db.ListLedgerEntriesRow, 0, len(amounts))
+	prev := db.GenesisHash
+	var balance int64
+	for i, amount := range amounts {
+		balance += amount
+		row := db.ListLedgerEntriesRow{
+			ID:                  int64(i + 1),
+			AccountID:           accountID,
+			Amount:              amount,
+			TransferID:          sql.NullInt64{Int64: int64(100 + i), Valid: true},
+			Balance:             sql.NullInt64{Int64: balance, Valid: true},
+			PrevHash:            prev,
+			CreatedAt:           time.Date(2026, 1, 1, 0, i, 0, 0, time.UTC),
+			TransferFromAccount: 1,
+			TransferToAccount:   accountID,
+			TransferAmount:      amount,
+		}
+		row.Hash = db.ChainedEntry{
+			AccountID:           row.AccountID,
+			Amount:              row.Amount,
+			Balance:             row.Balance.Int64,
+			CreatedAt:           row.CreatedAt,
+			TransferID:          row.TransferID.Int64,
+			TransferFromAccount: row.TransferFromAccount,
+			TransferToAccount:   row.TransferToAccount,
+			TransferAmount:      row.TransferAmount,
+		}.Hash(testKey, prev)
+		prev = row.Hash
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestVerify(t *testing.T) {
+	testCases := []struct {
+		name    string
+		key     []byte
+		balance int64
+		rows    func() []db.ListLedgerEntriesRow
+		entries int64
+		legacy  int64
+		brokeAt int64
+		reason  string
+	}{
+		{
+			name:    "ok",
+			balance: 25,
+			rows:    func() []db.ListLedgerEntriesRow { return chain(10, 20, -5) },
+			entries: 3,
+		},
+		{
+			name:    "NoEntries",
+			balance: 0,
+			rows:    func() []db.ListLedgerEntriesRow { return nil },
+		},
+		{
+			name:    "LegacyEntriesFirst",
+			balance: 30,
+			rows: func() []db.ListLedgerEntriesRow {
+				legacy := db.ListLedgerEntriesRow{ID: 0, AccountID: accountID, Amount: 99}
+				return append([]db.ListLedgerEntriesRow{legacy}, chain(10, 20)...)
+			},
+			entries: 2,
+			legacy:  1,
+		},
+		{
+			name:    "EditedAmount",
+			balance: 25,
+			rows: func() []db.ListLedgerEntriesRow {
+				rows := chain(10, 20, -5)
+				rows[1].Amount = 200
+				return rows
+			},
+			entries: 1,
+			brokeAt: 2,
+			reason:  "balance",
+		},
+		{
+			name:    "EditedTransfer",
+			balance: 25,
+			rows: func() []db.ListLedgerEntriesRow {
+				rows := chain(10, 20, -5)
+				rows[2].TransferFromAccount = 3
+				return rows
+			},
+			entries: 2,
+			brokeAt: 3,
+			reason:  "hash",
+		},
+		{
+			name:    "RemovedEntry",
+			balance: 25,
+			rows: func() []db.ListLedgerEntriesRow {
+				rows := chain(10, 20, -5)
+				return append(rows[:1], rows[2:]...)
+			},
+			entries: 1,
+			brokeAt: 3,
+			reason:  "prev_hash",
+		},
+		{
+			name:    "OtherKey",
+			key:     []byte("another key entirely, also 32 b."),
+			balance: 25,
+			rows:    func() []db.ListLedgerEntriesRow { return chain(10, 20, -5) },
+			brokeAt: 1,
+			reason:  "ledger key",
+		},
+		{
+			name:    "HashRemoved",
+			balance: 25,
+			rows: func() []db.ListLedgerEntriesRow {
+				rows := chain(10, 20, -5)
+				rows[2].Hash = nil
+				return rows
+			},
+			entries: 2,
+			brokeAt: 3,
+			reason:  "no hash",
+		},
+		{
+			name:    "BalanceOverwritten",
+			balance: 1000,
+			rows:    func() []db.ListLedgerEntriesRow { return chain(10, 20, -5) },
+			entries: 3,
+			brokeAt: 3,
+			reason:  "account balance",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().ReadTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, fn func(context.Context, db.Querier) error) error {
+					return fn(ctx, store)
+				}).Times(1)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(accountID))).
+				Return(db.Account{ID: accountID, Balance: tc.balance}, nil).Times(1)
+			store.EXPECT().ListLedgerEntries(gomock.Any(), gomock.Any()).Return(tc.rows(), nil).Times(1)
+
+			key := testKey
+			if tc.key != nil {
+				key = tc.key
+			}
+			report, err := Verify(context.Background(), store, key, accountID)
+			require.NoError(t, err)
+			require.Equal(t, int64(accountID), report.AccountID)
+			require.Equal(t, tc.entries, report.Entries)
+			require.Equal(t, tc.legacy, report.LegacyEntries)
+			if tc.brokeAt == 0 {
+				require.True(t, report.OK(), report.String())
+				return
+			}
+			require.False(t, report.OK())
+			require.Equal(t, tc.brokeAt, report.BrokenLink.EntryID)
+			require.Contains(t, report.BrokenLink.Reason, tc.reason)
+		})
+	}
+}
+
+func TestVerifyPages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	amounts := make([]int64, pageSize+1)
+	for i := range amounts {
+		amounts[i] = 1
+	}
+	rows := chain(amounts...)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ReadTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(context.Context, db.Querier) error) error {
+			return fn(ctx, store)
+		})
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(db.Account{ID: accountID, Balance: pageSize + 1}, nil)
+	store.EXPECT().ListLedgerEntries(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, arg db.ListLedgerEntriesParams) ([]db.ListLedgerEntriesRow, error) {
+			if arg.AfterID == 0 {
+				return rows[:pageSize], nil
+			}
+			require.Equal(t, int64(pageSize), arg.AfterID)
+			return rows[pageSize:], nil
+		}).Times(2)
+
+	report, err := Verify(context.Background(), store, testKey, accountID)
+	require.NoError(t, err)
+	require.True(t, report.OK(), report.String())
+	require.Equal(t, int64(pageSize+1), report.Entries)
+	require.Equal(t, rows[pageSize].ID, report.Head.EntryID)
+}
+
+func TestRunCommand(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListAccountIDs(gomock.Any(), gomock.Any()).Return([]int64{accountID, accountID}, nil)
+	store.EXPECT().ReadTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(context.Context, db.Querier) error) error {
+			return fn(ctx, store)
+		}).Times(2)
+	// The first pass finds the chain intact, the second finds the balance overwritten.
+	gomock.InOrder(
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(db.Account{ID: accountID, Balance: 30}, nil),
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(db.Account{ID: accountID, Balance: 31}, nil),
+	)
+	store.EXPECT().ListLedgerEntries(gomock.Any(), gomock.Any()).Return(chain(10, 20), nil).Times(2)
+
+	var out bytes.Buffer
+	err := RunCommand(context.Background(), store, testKey, nil, &out)
+	require.EqualError(t, err, "1 broken ledger chains")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], "ok, 2 entries checked")
+	require.Contains(t, lines[1], "broken at entry 2")
+
+	err = RunCommand(context.Background(), store, testKey, []string{"abc"}, &out)
+	require.ErrorContains(t, err, "invalid account id")
+}
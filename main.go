@@ -2,47 +2,203 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
-	"log"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/ashokmouli/simplebank/api"
+	"github.com/ashokmouli/simplebank/audit"
+	"github.com/ashokmouli/simplebank/certs"
+	"github.com/ashokmouli/simplebank/db/migration"
 	db "github.com/ashokmouli/simplebank/db/sqlc"
 	"github.com/ashokmouli/simplebank/db/util"
 	"github.com/ashokmouli/simplebank/gapi"
+	"github.com/ashokmouli/simplebank/ledger"
+	"github.com/ashokmouli/simplebank/metrics"
 	"github.com/ashokmouli/simplebank/pb"
+	"github.com/ashokmouli/simplebank/tracing"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
+// healthCheckInterval is how often the gRPC health status is refreshed.
+const healthCheckInterval = 10 * time.Second
+
+// reviewExpiryInterval is how often held transfers nobody reviewed in time are released, at most
+// reviewExpiryBatch at a time.
+const (
+	reviewExpiryInterval = time.Minute
+	reviewExpiryBatch    = 100
+)
+
+// interestInterval is how often the interest of the previous day is accrued and, once a month is
+// over, posted, interestBatch accounts at a time.
+const (
+	interestInterval = time.Hour
+	interestBatch    = 100
+)
+
+// Values of GATEWAY_MODE. In-process, the gateway calls the handlers directly and skips the gRPC
+// interceptors; in dial mode it goes through the gRPC server, so the interceptors run for HTTP traffic too.
+const (
+	gatewayInProcess = "inprocess"
+	gatewayDial      = "dial"
+)
+
+// interruptSignals are the signals that start a graceful shutdown. Kubernetes sends SIGTERM.
+var interruptSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+}
+
 func main() {
 
 	config, err := util.LoadConfig("./")
 	if err != nil {
-		log.Fatal("could not read config file", err)
+		log.Fatal().Err(err).Msg("could not read config file")
+	}
+	configureLogger(config)
+
+	// "main migrate ..." manages the schema instead of starting the servers.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(config, os.Args[2:])
+		return
+	}
+
+	// "main verify-ledger ..." checks the hash chains of the accounts' entries and exits.
+	if len(os.Args) > 1 && os.Args[1] == "verify-ledger" {
+		runVerifyLedgerCommand(config, os.Args[2:])
+		return
+	}
+
+	if config.MigrateOnStart {
+		if err := migration.Up(config.DBSource); err != nil {
+			log.Fatal().Err(err).Msg("cannot migrate db")
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot set up tracing")
 	}
+
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
-		log.Fatal("cannot connect to db: ", err)
+		log.Fatal().Err(err).Msg("cannot connect to db")
 	}
-	store := db.NewStore(conn)
-	go runGatewayServer(store, config)
-	runGrpcServer(store, config)
-}
+	metrics.RegisterDB(conn, "simple_bank")
+
+	store := db.NewStore(conn, []byte(config.LedgerKey))
 
-func runGatewayServer(store db.Store, config util.Config) {
+	schemaVersion, err := migration.LatestVersion(migration.FS)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot read migrations")
+	}
+	health := gapi.NewHealth(store, schemaVersion)
 
+	// The gRPC server and the in-process gateway share one server, and so its rate limit buckets.
 	server, err := gapi.NewServer(store, config)
 	if err != nil {
-		log.Fatal("could not start the server: ", err)
+		log.Fatal().Err(err).Msg("could not create the server")
+	}
+
+	// The servers stop when a signal arrives or when any one of them fails.
+	ctx, stop := signal.NotifyContext(context.Background(), interruptSignals...)
+	defer stop()
+	waitGroup, ctx := errgroup.WithContext(ctx)
+
+	// Both listeners use TLS when a certificate is configured, and pick up rotated files.
+	var reloader *certs.Reloader
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		reloader, err = certs.NewReloader(config.TLSCertFile, config.TLSKeyFile, config.TLSCAFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot load TLS certificates")
+		}
+		waitGroup.Go(func() error {
+			return reloader.Watch(ctx)
+		})
+	} else if config.TLSCAFile != "" {
+		log.Fatal().Msg("TLS_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE")
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	waitGroup.Go(func() error {
+		return runGrpcServer(ctx, server, health, reloader, config)
+	})
+	waitGroup.Go(func() error {
+		return runGatewayServer(ctx, server, health, reloader, config)
+	})
+	waitGroup.Go(func() error {
+		return health.Watch(ctx, healthCheckInterval)
+	})
+	waitGroup.Go(func() error {
+		return runReviewExpiry(ctx, store, reviewExpiryInterval)
+	})
+	waitGroup.Go(func() error {
+		return runInterest(ctx, store, interestInterval)
+	})
+
+	err = waitGroup.Wait()
+
+	// Close the DB pool only once the servers have drained, since in-flight requests still use it.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
 	defer cancel()
+	if terr := shutdownTracing(shutdownCtx); terr != nil {
+		log.Error().Err(terr).Msg("cannot flush traces")
+	}
+	if cerr := conn.Close(); cerr != nil {
+		log.Error().Err(cerr).Msg("cannot close db")
+	}
+
+	if err != nil {
+		log.Fatal().Err(err).Msg("server stopped with an error")
+	}
+	log.Info().Msg("server stopped")
+}
+
+func runMigrateCommand(config util.Config, args []string) {
+	m, err := migration.New(config.DBSource)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create migrator")
+	}
+
+	err = migration.RunCommand(m, args, os.Stdout)
+	m.Close()
+	if err != nil {
+		log.Fatal().Err(err).Msg("migration failed")
+	}
+}
+
+func runVerifyLedgerCommand(config util.Config, args []string) {
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot connect to db")
+	}
+
+	err = ledger.RunCommand(context.Background(), db.NewStore(conn, []byte(config.LedgerKey)), []byte(config.LedgerKey), args, os.Stdout)
+	conn.Close()
+	if err != nil {
+		log.Fatal().Err(err).Msg("ledger verification failed")
+	}
+}
+
+func runGatewayServer(ctx context.Context, server *gapi.Server, health *gapi.Health, reloader *certs.Reloader, config util.Config) error {
 
 	// jsonOption settings below preserve the field names in proto as is. Without these options, field names are
 	// camelCased.
@@ -55,49 +211,239 @@ func runGatewayServer(store db.Store, config util.Config) {
 		},
 	})
 
-	grpcMux := runtime.NewServeMux(jsonOption)
+	// GatewayMetadata labels the gateway metrics with the matched route and forwards the request ID.
+	grpcMux := runtime.NewServeMux(
+		jsonOption,
+		runtime.WithMetadata(gapi.GatewayMetadata),
+		runtime.WithOutgoingHeaderMatcher(gapi.OutgoingHeaderMatcher),
+		runtime.WithErrorHandler(gapi.GatewayErrorHandler),
+	)
 
-	err = pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
-	if err != nil {
-		log.Fatalf("cannot register handler server :%s", err)
+	switch config.GatewayMode {
+	case "", gatewayInProcess:
+		err := pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
+		if err != nil {
+			return fmt.Errorf("cannot register handler server: %w", err)
+		}
+	case gatewayDial:
+		conn, err := dialGrpcServer(reloader, config)
+		if err != nil {
+			return err
+		}
+		// Closed on return, once the HTTP server has drained.
+		defer conn.Close()
+		err = pb.RegisterSimpleBankHandler(ctx, grpcMux, conn)
+		if err != nil {
+			return fmt.Errorf("cannot register handler: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported gateway mode %q", config.GatewayMode)
 	}
 
 	// Create a new http mux and have it handle grpc mux.
 	mux := http.NewServeMux()
-	mux.Handle("/", grpcMux)
+	// otelhttp starts the request's span from the incoming trace headers. The in-process gateway
+	// passes the request context on to the handlers, and in dial mode the client stats handler
+	// propagates it to the gRPC server, so either way the handlers' queries join the same trace.
+	mux.Handle("/", otelhttp.NewHandler(server.HttpLogger(gapi.HttpMetrics(grpcMux)), "gateway"))
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", health.HandleLive)
+	mux.HandleFunc("/readyz", health.HandleReady)
 
 	listener, err := net.Listen("tcp", config.HTTPServerAddress)
 	if err != nil {
-		log.Fatalf("cannot create listener: %s", err)
+		return fmt.Errorf("cannot create listener: %w", err)
 	}
-	log.Printf("Starting HTTP gateway server at: %s", listener.Addr().String())
-	err = http.Serve(listener, mux)
-	if err != nil {
-		log.Fatalf("cannot start HTTP gateway server: %s", err)
+	// The gateway serves public clients, so it never asks for client certificates.
+	if reloader != nil {
+		listener = tls.NewListener(listener, reloader.ServerConfig(false))
+	}
+	httpServer := &http.Server{Handler: mux}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info().Msgf("Starting HTTP gateway server at: %s", listener.Addr().String())
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("HTTP gateway server failed: %w", err)
+	case <-ctx.Done():
 	}
+
+	// Fail the readiness probe for the drain period first, so that the load balancer stops sending
+	// requests, then stop accepting connections and wait for in-flight requests, up to the timeout.
+	log.Info().Msg("draining HTTP gateway server")
+	health.Drain(config.DrainPeriod)
+	log.Info().Msg("gracefully shutting down HTTP gateway server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Warn().Err(err).Msg("HTTP gateway server did not drain in time")
+		return httpServer.Close()
+	}
+	log.Info().Msg("HTTP gateway server stopped")
+	return nil
 }
 
-func runGrpcServer(store db.Store, config util.Config) {
+// dialGrpcServer connects the gateway to the gRPC server. With TLS, the gateway presents the
+// server's own certificate, which the gRPC server accepts when it requires client certificates.
+func dialGrpcServer(reloader *certs.Reloader, config util.Config) (*grpc.ClientConn, error) {
+	host, port, err := net.SplitHostPort(config.GRPCServerAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gRPC server address: %w", err)
+	}
+	// The server listens on every interface; reach it over loopback.
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
 
-	server, err := gapi.NewServer(store, config)
+	creds := insecure.NewCredentials()
+	if reloader != nil {
+		creds = credentials.NewTLS(reloader.ClientConfig(host))
+	}
+	conn, err := grpc.Dial(net.JoinHostPort(host, port),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
 	if err != nil {
-		log.Fatal("could not start the server: ", err)
+		return nil, fmt.Errorf("cannot dial gRPC server: %w", err)
 	}
+	return conn, nil
+}
 
-	grpcServer := grpc.NewServer()
+func runGrpcServer(ctx context.Context, server *gapi.Server, health *gapi.Health, reloader *certs.Reloader, config util.Config) error {
+
+	// The logger and metrics run before auth so that they also see calls the auth interceptor rejects.
+	// Rate limiting runs after auth so that it can count authenticated callers by username.
+	options := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(server.GrpcLogger, gapi.GrpcMetrics, server.AuthInterceptor, server.RateLimitInterceptor),
+	}
+	// With a CA configured, only internal callers holding a certificate it signed get in.
+	if reloader != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(reloader.ServerConfig(reloader.MutualTLS()))))
+	}
+	grpcServer := grpc.NewServer(options...)
 	pb.RegisterSimpleBankServer(grpcServer, server)
+	healthpb.RegisterHealthServer(grpcServer, health.GRPCServer())
 
 	// Make services on this server visible for clients to explore.
 	reflection.Register(grpcServer)
 
 	listener, err := net.Listen("tcp", config.GRPCServerAddress)
 	if err != nil {
-		log.Fatal("cannot create listener")
+		return fmt.Errorf("cannot create listener: %w", err)
 	}
-	log.Printf("Starting grpc server at: %s", listener.Addr().String())
-	err = grpcServer.Serve(listener)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info().Msgf("Starting grpc server at: %s", listener.Addr().String())
+		serveErr <- grpcServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("gRPC server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Report NOT_SERVING for the drain period first, then let GracefulStop wait for pending RPCs to
+	// finish; cut them off if they outlast the shutdown timeout.
+	log.Info().Msg("draining gRPC server")
+	health.Drain(config.DrainPeriod)
+	log.Info().Msg("gracefully shutting down gRPC server")
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		log.Info().Msg("gRPC server stopped")
+	case <-time.After(config.ShutdownTimeout):
+		log.Warn().Msg("gRPC server did not drain in time")
+		grpcServer.Stop()
+	}
+	return nil
+}
+
+// runReviewExpiry releases the holds of transfers whose review expired, until ctx is done.
+func runReviewExpiry(ctx context.Context, store db.Store, interval time.Duration) error {
+	entry, err := audit.Event{
+		Actor:        audit.ActorSystem,
+		Action:       audit.ActionTransferExpire,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceTransferReview,
+	}.Params()
 	if err != nil {
-		log.Fatal("cannot start gRPC server")
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		expired, err := db.ExpireTransferReviews(ctx, store, time.Now(), reviewExpiryBatch, &entry)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("cannot expire transfer reviews")
+		}
+		if expired > 0 {
+			log.Info().Int("expired", expired).Msg("expired transfer reviews")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// runInterest accrues the interest of each day once it is over, catching up on the days missed
+// while it wasn't running, and posts the interest of each month once it is over, until ctx is
+// done. Both are safe to repeat, so every tick does them again.
+func runInterest(ctx context.Context, store db.Store, interval time.Duration) error {
+	entry, err := audit.Event{
+		Actor:        audit.ActorSystem,
+		Action:       audit.ActionInterestPost,
+		Outcome:      audit.OutcomeSuccess,
+		ResourceType: audit.ResourceInterestPosting,
+	}.Params()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		accrued, err := db.AccrueInterestUntil(ctx, store, now.AddDate(0, 0, -1))
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("cannot accrue interest")
+		}
+		if accrued > 0 {
+			log.Info().Int64("accruals", accrued).Msg("accrued interest")
+		}
+
+		lastMonth := db.InterestMonth(now).AddDate(0, -1, 0)
+		for {
+			posted, err := db.PostInterest(ctx, store, lastMonth, interestBatch, &entry)
+			if err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Msg("cannot post interest")
+			}
+			if posted > 0 {
+				log.Info().Int("accounts", posted).Str("month", lastMonth.Format("2006-01")).Msg("posted interest")
+			}
+			if err != nil || posted < interestBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
 	}
 }
 
@@ -105,10 +451,28 @@ func runGrpcServer(store db.Store, config util.Config) {
 func _ /* runGinServer */ (store db.Store, config util.Config) {
 	server, err := api.NewServer(store, config)
 	if err != nil {
-		log.Fatal("could not start the server: ", err)
+		log.Fatal().Err(err).Msg("could not start the server")
 	}
 	err = server.StartServer(config.HTTPServerAddress)
 	if err != nil {
-		log.Fatal("could not start the server", err)
+		log.Fatal().Err(err).Msg("could not start the server")
+	}
+}
+
+// configureLogger sets the global log level and format. Logs are JSON unless LOG_FORMAT asks for
+// console output, which is the default in development.
+func configureLogger(config util.Config) {
+	level, err := zerolog.ParseLevel(config.LogLevel)
+	if err != nil || config.LogLevel == "" {
+		level = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(level)
+
+	format := config.LogFormat
+	if format == "" && config.Environment == "development" {
+		format = "console"
+	}
+	if format == "console" {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	}
 }
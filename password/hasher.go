@@ -0,0 +1,197 @@
+// Package password hashes and checks user passwords, and holds the policy new passwords must meet.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hash algorithms, as named in the PASSWORD_HASHER setting.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatch      = errors.New("password does not match")
+	ErrUnknownFormat = errors.New("unknown password hash format")
+)
+
+// Hasher hashes passwords with one algorithm. Hashes encode their parameters, so they can be
+// checked after the parameters change.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Check returns ErrMismatch when password does not match the hash.
+	Check(password string, hash string) error
+	// Handles reports whether hash was made by this algorithm.
+	Handles(hash string) bool
+	// Outdated reports whether hash was made with parameters other than the current ones.
+	Outdated(hash string) bool
+}
+
+// Argon2Params are the Argon2id cost parameters.
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the OWASP recommendation for Argon2id.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2Hasher hashes passwords with Argon2id, encoded as
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+type Argon2Hasher struct {
+	Params Argon2Params
+}
+
+const argon2Prefix = "$argon2id$"
+
+func (h Argon2Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("cannot generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Params.Iterations, h.Params.Memory, h.Params.Parallelism, h.Params.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+		h.Params.Memory, h.Params.Iterations, h.Params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2Hasher) Check(password string, hash string) error {
+	params, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (h Argon2Hasher) Handles(hash string) bool {
+	return strings.HasPrefix(hash, argon2Prefix)
+}
+
+func (h Argon2Hasher) Outdated(hash string) bool {
+	params, salt, _, err := decodeArgon2(hash)
+	if err != nil {
+		return true
+	}
+	params.SaltLength = uint32(len(salt))
+	return params != h.Params
+}
+
+func decodeArgon2(hash string) (params Argon2Params, salt []byte, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrUnknownFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownFormat
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrUnknownFormat
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, ErrUnknownFormat
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, ErrUnknownFormat
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// BcryptHasher hashes passwords with bcrypt. The cost is encoded in the hash.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate hash: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (h BcryptHasher) Check(password string, hash string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	return err
+}
+
+func (h BcryptHasher) Handles(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (h BcryptHasher) Outdated(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+// Hashing hashes new passwords with the current hasher, and checks passwords against hashes made
+// by any of the hashers it knows.
+type Hashing struct {
+	current Hasher
+	known   []Hasher
+}
+
+// NewHashing returns a Hashing that hashes new passwords with algorithm and its default parameters.
+func NewHashing(algorithm string) (*Hashing, error) {
+	argon2id := Argon2Hasher{Params: DefaultArgon2Params}
+	bcryptHasher := BcryptHasher{Cost: bcrypt.DefaultCost}
+
+	hashing := &Hashing{known: []Hasher{argon2id, bcryptHasher}}
+	switch algorithm {
+	case "", AlgorithmArgon2id:
+		hashing.current = argon2id
+	case AlgorithmBcrypt:
+		hashing.current = bcryptHasher
+	default:
+		return nil, fmt.Errorf("unknown password hasher %q", algorithm)
+	}
+	return hashing, nil
+}
+
+// Hash hashes a new password.
+func (h *Hashing) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Check verifies password against hash, and reports whether the hash should be replaced by a new
+// one because it was made by another algorithm or with outdated parameters.
+func (h *Hashing) Check(password string, hash string) (rehash bool, err error) {
+	for _, hasher := range h.known {
+		if !hasher.Handles(hash) {
+			continue
+		}
+		if err := hasher.Check(password, hash); err != nil {
+			return false, err
+		}
+		return hasher != h.current || hasher.Outdated(hash), nil
+	}
+	return false, ErrUnknownFormat
+}
@@ -0,0 +1,96 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams keep the tests fast; the cost does not change what is checked.
+var testParams = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2Hasher(t *testing.T) {
+	hasher := Argon2Hasher{Params: testParams}
+
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	require.True(t, hasher.Handles(hash))
+	require.False(t, hasher.Outdated(hash))
+
+	require.NoError(t, hasher.Check("correct horse", hash))
+	require.ErrorIs(t, hasher.Check("wrong horse", hash), ErrMismatch)
+
+	// Salted: the same password hashes differently every time.
+	other, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, other)
+
+	// Hashes keep their parameters, so they still check after the parameters change.
+	stronger := Argon2Hasher{Params: testParams}
+	stronger.Params.Iterations = 2
+	require.NoError(t, stronger.Check("correct horse", hash))
+	require.True(t, stronger.Outdated(hash))
+
+	require.ErrorIs(t, hasher.Check("correct horse", "$argon2id$v=19$garbage"), ErrUnknownFormat)
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher := BcryptHasher{Cost: bcrypt.MinCost}
+
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.True(t, hasher.Handles(hash))
+	require.False(t, hasher.Outdated(hash))
+	require.NoError(t, hasher.Check("correct horse", hash))
+	require.ErrorIs(t, hasher.Check("wrong horse", hash), ErrMismatch)
+
+	require.True(t, BcryptHasher{Cost: bcrypt.DefaultCost}.Outdated(hash))
+}
+
+func TestHashingRehash(t *testing.T) {
+	hashing := &Hashing{
+		current: Argon2Hasher{Params: testParams},
+		known:   []Hasher{Argon2Hasher{Params: testParams}, BcryptHasher{Cost: bcrypt.MinCost}},
+	}
+
+	current, err := hashing.Hash("correct horse")
+	require.NoError(t, err)
+	rehash, err := hashing.Check("correct horse", current)
+	require.NoError(t, err)
+	require.False(t, rehash)
+
+	// Hashes from another algorithm still check, and are flagged for replacement.
+	legacy, err := BcryptHasher{Cost: bcrypt.MinCost}.Hash("correct horse")
+	require.NoError(t, err)
+	rehash, err = hashing.Check("correct horse", legacy)
+	require.NoError(t, err)
+	require.True(t, rehash)
+
+	// Hashes with outdated parameters too.
+	weak, err := Argon2Hasher{Params: Argon2Params{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}}.Hash("correct horse")
+	require.NoError(t, err)
+	rehash, err = hashing.Check("correct horse", weak)
+	require.NoError(t, err)
+	require.True(t, rehash)
+
+	_, err = hashing.Check("correct horse", legacy[:10]+"x")
+	require.Error(t, err)
+	_, err = hashing.Check("correct horse", "plaintext")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestNewHashing(t *testing.T) {
+	hashing, err := NewHashing("")
+	require.NoError(t, err)
+	require.Equal(t, Argon2Hasher{Params: DefaultArgon2Params}, hashing.current)
+
+	hashing, err = NewHashing(AlgorithmBcrypt)
+	require.NoError(t, err)
+	require.Equal(t, BcryptHasher{Cost: bcrypt.DefaultCost}, hashing.current)
+
+	_, err = NewHashing("md5")
+	require.Error(t, err)
+}
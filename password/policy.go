@@ -0,0 +1,121 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// MaxLength is the longest password accepted, in bytes. bcrypt ignores everything past 72 bytes,
+// so allowing more would let hashes made with it match passwords that differ after that point.
+const MaxLength = 72
+
+// DefaultMinLength is the minimum length of a password when none is configured.
+const DefaultMinLength = 10
+
+//go:embed common.txt
+var defaultCommonPasswords string
+
+// Policy is what a new password has to meet.
+type Policy struct {
+	MinLength int
+	// MinClasses is how many of lowercase letters, uppercase letters, digits and symbols the
+	// password must mix.
+	MinClasses int
+	common     map[string]bool
+}
+
+// NewPolicy returns a policy that rejects the passwords listed in commonFile, one per line, or a
+// short built-in list of the most common ones when commonFile is empty. A minLength of 0 stands
+// for DefaultMinLength.
+func NewPolicy(minLength int, minClasses int, commonFile string) (*Policy, error) {
+	if minLength == 0 {
+		minLength = DefaultMinLength
+	}
+	if minLength < 1 || minLength > MaxLength {
+		return nil, fmt.Errorf("password minimum length must be between 1 and %d", MaxLength)
+	}
+	if minClasses < 0 || minClasses > 4 {
+		return nil, fmt.Errorf("password character classes must be between 0 and 4")
+	}
+
+	var list io.Reader = strings.NewReader(defaultCommonPasswords)
+	if commonFile != "" {
+		file, err := os.Open(commonFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open common password list: %w", err)
+		}
+		defer file.Close()
+		list = file
+	}
+	common, err := readCommonPasswords(list)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read common password list: %w", err)
+	}
+
+	return &Policy{MinLength: minLength, MinClasses: minClasses, common: common}, nil
+}
+
+func readCommonPasswords(r io.Reader) (map[string]bool, error) {
+	common := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			common[strings.ToLower(line)] = true
+		}
+	}
+	return common, scanner.Err()
+}
+
+// Validate checks a new password for username. The error describes what is wrong with it and is
+// meant for the user.
+func (p *Policy) Validate(password string, username string) error {
+	if password == "" {
+		return fmt.Errorf("is required")
+	}
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("must contain at least %d characters", p.MinLength)
+	}
+	if len(password) > MaxLength {
+		return fmt.Errorf("must be at most %d bytes", MaxLength)
+	}
+	if classes := characterClasses(password); classes < p.MinClasses {
+		return fmt.Errorf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses)
+	}
+	lower := strings.ToLower(password)
+	if username != "" && strings.Contains(lower, strings.ToLower(username)) {
+		return fmt.Errorf("must not contain the username")
+	}
+	if p.common[lower] {
+		return fmt.Errorf("is too common")
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
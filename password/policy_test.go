@@ -0,0 +1,68 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	policy, err := NewPolicy(10, 3, "")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"ok", "Tr0ub4dor&3", true},
+		{"Empty", "", false},
+		{"TooShort", "Ab1!", false},
+		{"TooLong", "Aa1!" + strings.Repeat("x", MaxLength), false},
+		{"TwoClasses", "abcdefghij12", false},
+		{"ContainsUsername", "xBob_Smith1!", false},
+		{"Common", "Password123!", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Validate(tc.password, "bob_smith")
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyCommonListFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "common.txt")
+	require.NoError(t, os.WriteFile(file, []byte("Tr0ub4dor&3\n\n"), 0o600))
+
+	policy, err := NewPolicy(8, 0, file)
+	require.NoError(t, err)
+	require.Error(t, policy.Validate("tr0ub4dor&3", "bob"))
+	// The file replaces the built-in list.
+	require.NoError(t, policy.Validate("Password123!", "bob"))
+
+	_, err = NewPolicy(8, 0, filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
+
+func TestNewPolicyLimits(t *testing.T) {
+	// An unset minimum length falls back to the default.
+	policy, err := NewPolicy(0, 0, "")
+	require.NoError(t, err)
+	require.Equal(t, DefaultMinLength, policy.MinLength)
+
+	_, err = NewPolicy(-1, 0, "")
+	require.Error(t, err)
+	_, err = NewPolicy(MaxLength+1, 0, "")
+	require.Error(t, err)
+	_, err = NewPolicy(8, 5, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,30 @@
+package password
+
+import (
+	"context"
+
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/rs/zerolog/log"
+)
+
+// HashWriter stores password hashes; db.Store is one.
+type HashWriter interface {
+	UpdateUserHashedPassword(ctx context.Context, arg db.UpdateUserHashedPasswordParams) (int64, error)
+}
+
+// Rehash replaces the hash of user's password, made by another algorithm or with outdated
+// parameters, now that the password is known after a login. A failure is only logged so that it
+// never fails the login; the hash is replaced on the next one.
+func (h *Hashing) Rehash(ctx context.Context, w HashWriter, user db.User, clear string) {
+	hash, err := h.Hash(clear)
+	if err == nil {
+		_, err = w.UpdateUserHashedPassword(ctx, db.UpdateUserHashedPasswordParams{
+			HashedPassword:    hash,
+			Username:          user.Username,
+			OldHashedPassword: user.HashedPassword,
+		})
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("username", user.Username).Msg("cannot rehash password")
+	}
+}
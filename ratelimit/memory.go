@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idleTimeout is how long a bucket is kept after its last use, at least. Buckets that take
+// longer to refill are kept until they have, so that dropping one changes nothing for the caller.
+const idleTimeout = 10 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+	// keep is how long the bucket is kept idle: idleTimeout, or the time it takes to refill.
+	keep time.Duration
+}
+
+// keepIdle returns how long an idle bucket of limit is kept: until an empty one has refilled,
+// and at least idleTimeout.
+func keepIdle(limit Limit) time.Duration {
+	if limit.Rate <= 0 {
+		return math.MaxInt64
+	}
+	refill := float64(limit.Burst) / limit.Rate * float64(time.Second)
+	if refill >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if keep := time.Duration(math.Ceil(refill)); keep > idleTimeout {
+		return keep
+	}
+	return idleTimeout
+}
+
+// MemoryBackend keeps the buckets in process memory.
+type MemoryBackend struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryBackend returns an empty in-memory backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryBackend) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{
+			limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst),
+			keep:    keepIdle(limit),
+		}
+		m.buckets[key] = b
+	}
+	b.lastUsed = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, 0, nil
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// Give the token back; the caller is refused rather than queued.
+		reservation.CancelAt(now)
+		return false, delay, nil
+	}
+	return true, 0, nil
+}
+
+// sweep drops the buckets that were idle long enough to refill, at most once per idleTimeout.
+func (m *MemoryBackend) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < idleTimeout {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.lastUsed) >= b.keep {
+			delete(m.buckets, key)
+		}
+	}
+}
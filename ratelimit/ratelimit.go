@@ -0,0 +1,147 @@
+// Package ratelimit throttles callers with token buckets, one per rule and caller.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket that refills at Rate tokens per second and holds at most Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Backend stores the buckets. The in-memory backend limits each replica on its own; a shared
+// store can implement Backend to apply the limits across replicas.
+type Backend interface {
+	// Take removes a token from the bucket for key. If the bucket is empty it returns false and
+	// how long until a token is available.
+	Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// ErrLimitExceeded is returned to callers that have used up their bucket.
+var ErrLimitExceeded = errors.New("rate limit exceeded")
+
+// DefaultRule applies to routes and RPCs that do not name a rule of their own.
+const DefaultRule = "default"
+
+// DefaultLimits are the limits used unless RATE_LIMITS overrides them. Logging in and signing up
+// are limited per IP address, so they get tight limits against password guessing and spam. Payee
+// lookups are limited so that they can't be used to find out who has an account.
+var DefaultLimits = map[string]Limit{
+	DefaultRule:    {Rate: 10, Burst: 20},
+	"login_user":   {Rate: 5.0 / 60, Burst: 5},
+	"create_user":  {Rate: 10.0 / 3600, Burst: 10},
+	"transfer":     {Rate: 1, Burst: 10},
+	"lookup_payee": {Rate: 20.0 / 60, Burst: 10},
+}
+
+// Limiter applies the limit of a rule to a caller.
+type Limiter struct {
+	backend Backend
+	limits  map[string]Limit
+}
+
+// New returns a Limiter that keeps its buckets in backend.
+func New(backend Backend, limits map[string]Limit) *Limiter {
+	return &Limiter{
+		backend: backend,
+		limits:  limits,
+	}
+}
+
+// Allow takes a token from the caller's bucket for rule. Callers are identified by username once
+// authenticated and by IP address otherwise. When the bucket is empty it returns false and how long
+// the caller should wait before retrying.
+func (l *Limiter) Allow(ctx context.Context, rule string, caller string) (bool, time.Duration, error) {
+	limit, ok := l.limits[rule]
+	if !ok {
+		rule = DefaultRule
+		limit, ok = l.limits[DefaultRule]
+	}
+	if !ok {
+		return true, 0, nil
+	}
+	return l.backend.Take(ctx, rule+"|"+caller, limit)
+}
+
+// UserCaller identifies an authenticated caller to Allow.
+func UserCaller(username string) string {
+	return "user:" + username
+}
+
+// IPCaller identifies an anonymous caller to Allow.
+func IPCaller(ip string) string {
+	return "ip:" + ip
+}
+
+// RetryAfterSeconds formats a wait for the Retry-After header, which counts whole seconds.
+func RetryAfterSeconds(retryAfter time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds()))))
+}
+
+// ParseLimits reads limits written as "rule=N/unit" or "rule=N/unit:burst", separated by commas,
+// where unit is s, m or h. The burst defaults to N. The result starts from DefaultLimits.
+func ParseLimits(spec string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(DefaultLimits))
+	for rule, limit := range DefaultLimits {
+		limits[rule] = limit
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, value, found := strings.Cut(entry, "=")
+		if !found || rule == "" {
+			return nil, fmt.Errorf("invalid rate limit %q", entry)
+		}
+		limit, err := parseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit %q: %w", entry, err)
+		}
+		limits[strings.TrimSpace(rule)] = limit
+	}
+	return limits, nil
+}
+
+func parseLimit(value string) (Limit, error) {
+	value, burstValue, hasBurst := strings.Cut(value, ":")
+	countValue, unit, found := strings.Cut(value, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("missing unit")
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(countValue))
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("count must be a positive number")
+	}
+
+	var period time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("unit must be s, m or h")
+	}
+
+	burst := count
+	if hasBurst {
+		burst, err = strconv.Atoi(strings.TrimSpace(burstValue))
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("burst must be a positive number")
+		}
+	}
+	return Limit{Rate: float64(count) / period.Seconds(), Burst: burst}, nil
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBackend() (*MemoryBackend, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	backend := NewMemoryBackend()
+	backend.now = func() time.Time { return now }
+	return backend, &now
+}
+
+func TestLimiterAllow(t *testing.T) {
+	backend, now := newTestBackend()
+	limiter := New(backend, map[string]Limit{
+		DefaultRule:  {Rate: 100, Burst: 100},
+		"login_user": {Rate: 1, Burst: 2},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := limiter.Allow(ctx, "login_user", IPCaller("10.0.0.1"))
+		require.NoError(t, err)
+		require.True(t, allowed)
+	}
+
+	allowed, retryAfter, err := limiter.Allow(ctx, "login_user", IPCaller("10.0.0.1"))
+	require.NoError(t, err)
+	require.False(t, allowed)
+	require.Equal(t, time.Second, retryAfter)
+
+	// Other callers and other rules have buckets of their own.
+	allowed, _, err = limiter.Allow(ctx, "login_user", IPCaller("10.0.0.2"))
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	allowed, _, err = limiter.Allow(ctx, "list_api_keys", IPCaller("10.0.0.1"))
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	// A refused call does not use up a token, so one second later the caller gets in again.
+	*now = now.Add(time.Second)
+	allowed, _, err = limiter.Allow(ctx, "login_user", IPCaller("10.0.0.1"))
+	require.NoError(t, err)
+	require.True(t, allowed)
+}
+
+func TestMemoryBackendSweep(t *testing.T) {
+	backend, now := newTestBackend()
+	limit := Limit{Rate: 1, Burst: 1}
+
+	_, _, err := backend.Take(context.Background(), "a", limit)
+	require.NoError(t, err)
+	*now = now.Add(idleTimeout)
+	_, _, err = backend.Take(context.Background(), "b", limit)
+	require.NoError(t, err)
+
+	require.NotContains(t, backend.buckets, "a")
+	require.Contains(t, backend.buckets, "b")
+}
+
+func TestMemoryBackendSweepSlowLimit(t *testing.T) {
+	backend, now := newTestBackend()
+	limiter := New(backend, DefaultLimits)
+	ctx := context.Background()
+	caller := IPCaller("10.0.0.1")
+
+	signUp := func() bool {
+		allowed, _, err := limiter.Allow(ctx, "create_user", caller)
+		require.NoError(t, err)
+		return allowed
+	}
+	for i := 0; i < DefaultLimits["create_user"].Burst; i++ {
+		require.True(t, signUp())
+	}
+	require.False(t, signUp())
+
+	// Idle past idleTimeout, the empty bucket is kept: it has only refilled a few tokens.
+	*now = now.Add(2 * idleTimeout)
+	for signUp() {
+	}
+	*now = now.Add(2 * idleTimeout)
+	_, _, err := backend.Take(ctx, "other", Limit{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+	allowed := 0
+	for signUp() {
+		allowed++
+	}
+	require.Equal(t, 3, allowed)
+
+	// Once it has had time to refill, it is dropped.
+	*now = now.Add(time.Hour)
+	_, _, err = backend.Take(ctx, "other", Limit{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+	require.Len(t, backend.buckets, 1)
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("login_user=3/m, transfer=20/s:40")
+	require.NoError(t, err)
+	require.Equal(t, Limit{Rate: 3.0 / 60, Burst: 3}, limits["login_user"])
+	require.Equal(t, Limit{Rate: 20, Burst: 40}, limits["transfer"])
+	require.Equal(t, DefaultLimits[DefaultRule], limits[DefaultRule])
+
+	limits, err = ParseLimits("")
+	require.NoError(t, err)
+	require.Equal(t, DefaultLimits, limits)
+
+	for _, spec := range []string{"login_user", "=3/m", "login_user=3", "login_user=0/m", "login_user=3/d", "login_user=3/m:x"} {
+		_, err := ParseLimits(spec)
+		require.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,63 @@
+// Package recipient finds the accounts that receive transfers sent to a payee. The Gin API and the
+// gRPC server both resolve payees through it, so that they accept the same payees and answer the
+// same way about the ones they refuse.
+//
+// Whether a user or an account exists, which currency an account holds and whether it is frozen
+// or closed are not for the sender to learn: every payee that can't receive transfers is refused
+// with the same error, which only repeats what the request said.
+package recipient
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+)
+
+// PayeeAccount returns the account that receives the transfers sent to the user addressed by
+// username or email: their default account in currency. field names the request field that
+// addressed the user, for violations.
+func PayeeAccount(ctx context.Context, q db.Querier, field, username, email, currency string) (db.GetPayeeAccountRow, error) {
+	payee, err := q.GetPayeeAccount(ctx, db.GetPayeeAccountParams{
+		Username: sql.NullString{String: username, Valid: username != ""},
+		Email:    sql.NullString{String: email, Valid: email != ""},
+		Currency: currency,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return payee, apperr.Internal(err)
+	}
+	if err != nil || payee.Status != db.AccountActive {
+		return db.GetPayeeAccountRow{}, apperr.InvalidArgument(apperr.Violation(field, "has no "+currency+" account to receive transfers"))
+	}
+	return payee, nil
+}
+
+// PayeeCurrency checks that a payee saved by account ID or by username can receive transfers in
+// currency, and returns the currency transfers to it default to. A payee saved by account uses the
+// account's currency; one saved by username needs a currency.
+func PayeeCurrency(ctx context.Context, q db.Querier, accountID int64, username, currency string) (string, error) {
+	if accountID == 0 {
+		if currency == "" {
+			return "", apperr.InvalidArgument(apperr.Violation("currency", "is required with username"))
+		}
+		if _, err := PayeeAccount(ctx, q, "username", username, "", currency); err != nil {
+			return "", err
+		}
+		return currency, nil
+	}
+
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", apperr.Internal(err)
+	}
+	if err != nil || account.Status != db.AccountActive || (currency != "" && currency != account.Currency) {
+		description := "is not an account that can receive transfers"
+		if currency != "" {
+			description = "is not an account that can receive " + currency + " transfers"
+		}
+		return "", apperr.InvalidArgument(apperr.Violation("account_id", description))
+	}
+	return account.Currency, nil
+}
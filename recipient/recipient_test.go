@@ -0,0 +1,144 @@
+package recipient
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	mockdb "github.com/ashokmouli/simplebank/db/mock"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayeeAccount(t *testing.T) {
+	active := db.GetPayeeAccountRow{ID: 3, Owner: "jane", Status: db.AccountActive}
+	frozen := active
+	frozen.Status = db.AccountFrozen
+
+	testCases := []struct {
+		name string
+		row  db.GetPayeeAccountRow
+		err  error
+		code apperr.Code
+	}{
+		{"OK", active, nil, ""},
+		{"NotFound", db.GetPayeeAccountRow{}, sql.ErrNoRows, apperr.CodeInvalidArgument},
+		{"Frozen", frozen, nil, apperr.CodeInvalidArgument},
+		{"InternalError", db.GetPayeeAccountRow{}, sql.ErrConnDone, apperr.CodeInternal},
+	}
+	var refused error
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Eq(db.GetPayeeAccountParams{
+				Email:    sql.NullString{String: "jane@example.com", Valid: true},
+				Currency: util.USD,
+			})).Return(tc.row, tc.err).Times(1)
+
+			payee, err := PayeeAccount(context.Background(), store, "to_email", "", "jane@example.com", util.USD)
+			if tc.code == "" {
+				require.NoError(t, err)
+				require.Equal(t, active, payee)
+				return
+			}
+			require.Equal(t, tc.code, apperr.From(err).Code)
+			if tc.code == apperr.CodeInvalidArgument {
+				// Missing and frozen accounts can't be told apart.
+				if refused != nil {
+					require.Equal(t, refused, err)
+				}
+				refused = err
+			}
+		})
+	}
+}
+
+func TestPayeeCurrency(t *testing.T) {
+	account := db.Account{ID: 5, Currency: util.EUR, Status: db.AccountActive}
+	closed := account
+	closed.Status = db.AccountClosed
+
+	testCases := []struct {
+		name     string
+		currency string
+		account  db.Account
+		err      error
+		want     string
+		refused  bool
+	}{
+		{"AccountCurrency", "", account, nil, util.EUR, false},
+		{"SameCurrency", util.EUR, account, nil, util.EUR, false},
+		{"Mismatch", util.USD, account, nil, "", true},
+		{"NotFound", util.USD, db.Account{}, sql.ErrNoRows, "", true},
+		{"Closed", util.USD, closed, nil, "", true},
+	}
+	var refused error
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(tc.account, tc.err).Times(1)
+
+			currency, err := PayeeCurrency(context.Background(), store, account.ID, "", tc.currency)
+			if !tc.refused {
+				require.NoError(t, err)
+				require.Equal(t, tc.want, currency)
+				return
+			}
+			var appErr *apperr.Error
+			require.True(t, errors.As(err, &appErr))
+			require.Equal(t, apperr.CodeInvalidArgument, appErr.Code)
+			// Missing, closed and mismatched accounts can't be told apart.
+			if refused != nil {
+				require.Equal(t, refused, err)
+			}
+			refused = err
+		})
+	}
+}
+
+func TestPayeeCurrencyByUsername(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	_, err := PayeeCurrency(context.Background(), store, 0, "jane", "")
+	require.Equal(t, apperr.CodeInvalidArgument, apperr.From(err).Code)
+
+	store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Any()).
+		Return(db.GetPayeeAccountRow{ID: 3, Status: db.AccountActive}, nil).Times(1)
+	currency, err := PayeeCurrency(context.Background(), store, 0, "jane", util.CAD)
+	require.NoError(t, err)
+	require.Equal(t, util.CAD, currency)
+}
+
+func TestRequestExpandPayee(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	both := Request{AccountID: 3, Username: "jane", Currency: util.USD}
+	require.Equal(t, apperr.CodeInvalidArgument, apperr.From(both.ExpandPayee(context.Background(), store, "bob")).Code)
+	noCurrency := Request{AccountID: 3}
+	require.Equal(t, apperr.CodeInvalidArgument, apperr.From(noCurrency.ExpandPayee(context.Background(), store, "bob")).Code)
+
+	// A payee saved by username stands for it, in its currency, and violations name payee_id.
+	store.EXPECT().GetPayee(gomock.Any(), gomock.Eq(db.GetPayeeParams{ID: 7, Owner: "bob"})).
+		Return(db.Payee{ID: 7, Username: sql.NullString{String: "jane", Valid: true}, Currency: util.EUR}, nil).Times(1)
+	store.EXPECT().GetPayeeAccount(gomock.Any(), gomock.Any()).Return(db.GetPayeeAccountRow{}, sql.ErrNoRows).Times(1)
+	req := Request{PayeeID: 7}
+	require.NoError(t, req.ExpandPayee(context.Background(), store, "bob"))
+	require.Equal(t, "jane", req.Username)
+	require.Equal(t, util.EUR, req.Currency)
+	_, err := req.Resolve(context.Background(), store)
+	require.Equal(t, "payee_id", apperr.From(err).Violations[0].Field)
+}
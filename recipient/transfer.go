@@ -0,0 +1,112 @@
+package recipient
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ashokmouli/simplebank/apperr"
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+)
+
+// Request names who a transfer goes to, in one of the ways both servers accept: exactly one of
+// AccountID, Username, Email and PayeeID. A username or email sends to the user's default account
+// in Currency. A saved payee stands for the account or username it was saved with, and transfers
+// to it default to its currency.
+type Request struct {
+	AccountID int64
+	Username  string
+	Email     string
+	PayeeID   int64
+	Currency  string
+	// viaPayee is set once a payee stands in for the recipient, so that violations name payee_id.
+	viaPayee bool
+}
+
+// Recipient is the account a transfer goes to.
+type Recipient struct {
+	AccountID int64
+	Owner     string
+}
+
+// ExpandPayee checks that the request names one recipient and replaces a payee of owner by what
+// it stands for. The currency of the transfer is known afterwards.
+func (r *Request) ExpandPayee(ctx context.Context, q db.Querier, owner string) error {
+	if count(r.AccountID != 0, r.Username != "", r.Email != "", r.PayeeID != 0) != 1 {
+		return apperr.InvalidArgument(apperr.Violation("to_account_id", "exactly one of to_account_id, to_username, to_email and payee_id is required"))
+	}
+	if r.PayeeID != 0 {
+		payee, err := q.GetPayee(ctx, db.GetPayeeParams{
+			ID:    r.PayeeID,
+			Owner: owner,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return apperr.InvalidArgument(apperr.Violation("payee_id", "payee does not exist"))
+			}
+			return apperr.Internal(err)
+		}
+		r.AccountID = payee.AccountID.Int64
+		r.Username = payee.Username.String
+		if r.Currency == "" {
+			r.Currency = payee.Currency
+		}
+		r.viaPayee = true
+	}
+	if r.Currency == "" {
+		return apperr.InvalidArgument(apperr.Violation("currency", "is required"))
+	}
+	return nil
+}
+
+// Resolve finds the account the transfer goes to, once ExpandPayee has run.
+func (r *Request) Resolve(ctx context.Context, q db.Querier) (Recipient, error) {
+	switch {
+	case r.Username != "":
+		payee, err := PayeeAccount(ctx, q, r.field("to_username"), r.Username, "", r.Currency)
+		return Recipient{AccountID: payee.ID, Owner: payee.Owner}, err
+	case r.Email != "":
+		payee, err := PayeeAccount(ctx, q, "to_email", "", r.Email, r.Currency)
+		return Recipient{AccountID: payee.ID, Owner: payee.Owner}, err
+	}
+	account, err := Account(ctx, q, r.field("to_account_id"), r.AccountID, r.Currency)
+	return Recipient{AccountID: account.ID, Owner: account.Owner}, err
+}
+
+func (r *Request) field(name string) string {
+	if r.viaPayee {
+		return "payee_id"
+	}
+	return name
+}
+
+// Account checks that an account named by ID, the sender's or the recipient's, is active and in
+// currency. field names the request field that holds the ID, for violations. Unlike payees, these
+// accounts are told apart: the sender has the account number in hand.
+func Account(ctx context.Context, q db.Querier, field string, accountID int64, currency string) (db.Account, error) {
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account, apperr.InvalidArgument(apperr.Violation(field, "account does not exist"))
+		}
+		return account, apperr.Internal(err)
+	}
+	if account.Currency != currency {
+		return account, apperr.InvalidArgument(apperr.Violation("currency", "does not match the currency of "+field))
+	}
+	if account.Status != db.AccountActive {
+		return account, apperr.Newf(apperr.CodeFailedPrecondition, "account %d is %s", accountID, account.Status)
+	}
+	return account, nil
+}
+
+// count counts the ways a request names its recipient.
+func count(set ...bool) int {
+	n := 0
+	for _, ok := range set {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,36 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	db "github.com/ashokmouli/simplebank/db/sqlc"
+)
+
+// StoreHistory answers the questions of the rules from the database.
+type StoreHistory struct {
+	Store db.Querier
+}
+
+func (h StoreHistory) CountTransfersSince(ctx context.Context, username string, since time.Time) (int64, error) {
+	return h.Store.CountUserTransfersSince(ctx, db.CountUserTransfersSinceParams{
+		Owner: username,
+		Since: since,
+	})
+}
+
+func (h StoreHistory) CountTransfersTo(ctx context.Context, username string, toAccountID int64) (int64, error) {
+	return h.Store.CountUserTransfersTo(ctx, db.CountUserTransfersToParams{
+		Owner:     username,
+		ToAccount: toAccountID,
+	})
+}
+
+// SessionIPs relies on the sessions, which record the trusted address of every login.
+func (h StoreHistory) SessionIPs(ctx context.Context, username string, ip string) (time.Time, int64, error) {
+	row, err := h.Store.CountSessionIPs(ctx, db.CountSessionIPsParams{
+		ClientIp: ip,
+		Username: username,
+	})
+	return row.FirstSeen, row.Others, err
+}
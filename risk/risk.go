@@ -0,0 +1,325 @@
+// Package risk screens transfers for fraud before they post. Rules look for one kind of suspicious
+// transfer each; the worst verdict of the rules that match decides whether the transfer goes
+// through, waits for a banker or is refused.
+package risk
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verdict is what happens to a transfer.
+type Verdict int
+
+// Verdicts, from the mildest to the worst.
+const (
+	Allow Verdict = iota
+	Review
+	Deny
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Allow:
+		return "allow"
+	case Review:
+		return "review"
+	case Deny:
+		return "deny"
+	}
+	return "verdict(" + strconv.Itoa(int(v)) + ")"
+}
+
+// MarshalText writes the verdict by name, in JSON and logs.
+func (v Verdict) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText reads a verdict written by name.
+func (v *Verdict) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "allow":
+		*v = Allow
+	case "review":
+		*v = Review
+	case "deny":
+		*v = Deny
+	default:
+		return fmt.Errorf("unknown verdict %q", text)
+	}
+	return nil
+}
+
+// Transfer is a transfer about to post.
+type Transfer struct {
+	Username      string
+	FromAccountID int64
+	ToAccountID   int64
+	// ToOwner is the owner of the account the transfer goes to.
+	ToOwner  string
+	Amount   int64
+	Currency string
+	// ClientIP is where the request came from, if known.
+	ClientIP string
+	At       time.Time
+}
+
+// History answers questions about a user's past transfers.
+type History interface {
+	// CountTransfersSince counts the transfers that left the user's accounts since a time.
+	CountTransfersSince(ctx context.Context, username string, since time.Time) (int64, error)
+	// CountTransfersTo counts the transfers the user made to an account.
+	CountTransfersTo(ctx context.Context, username string, toAccountID int64) (int64, error)
+	// SessionIPs tells when the user first logged in from an IP address, or now if they never
+	// did, and how many of their sessions came from other addresses.
+	SessionIPs(ctx context.Context, username string, ip string) (firstSeen time.Time, others int64, err error)
+}
+
+// A Check looks for one kind of suspicious transfer. It explains what it found, or returns ""
+// when the transfer doesn't match.
+type Check interface {
+	Name() string
+	Check(ctx context.Context, t Transfer, h History) (string, error)
+}
+
+// Rule gives the verdict of a check to the transfers it matches.
+type Rule struct {
+	Check   Check
+	Verdict Verdict
+}
+
+// Finding is a rule a transfer matched.
+type Finding struct {
+	Rule    string  `json:"rule"`
+	Verdict Verdict `json:"verdict"`
+	Reason  string  `json:"reason"`
+}
+
+// Assessment is the outcome of screening a transfer.
+type Assessment struct {
+	Verdict  Verdict   `json:"verdict"`
+	Findings []Finding `json:"findings"`
+}
+
+// Screener runs rules over transfers.
+type Screener struct {
+	rules []Rule
+}
+
+// New returns a screener that runs rules in order. Without rules, every transfer is allowed.
+func New(rules ...Rule) *Screener {
+	return &Screener{rules: rules}
+}
+
+// Screen runs the rules over t and returns the worst verdict of those it matches. Once a rule
+// denies the transfer, the others are not run.
+func (s *Screener) Screen(ctx context.Context, t Transfer, h History) (Assessment, error) {
+	assessment := Assessment{Verdict: Allow}
+	for _, rule := range s.rules {
+		reason, err := rule.Check.Check(ctx, t, h)
+		if err != nil {
+			return Assessment{}, fmt.Errorf("risk rule %s: %w", rule.Check.Name(), err)
+		}
+		if reason == "" {
+			continue
+		}
+		assessment.Findings = append(assessment.Findings, Finding{
+			Rule:    rule.Check.Name(),
+			Verdict: rule.Verdict,
+			Reason:  reason,
+		})
+		if rule.Verdict > assessment.Verdict {
+			assessment.Verdict = rule.Verdict
+		}
+		if assessment.Verdict == Deny {
+			break
+		}
+	}
+	return assessment, nil
+}
+
+// Velocity matches a user's transfer when they already made Count transfers within Window.
+type Velocity struct {
+	Count  int64
+	Window time.Duration
+}
+
+func (v Velocity) Name() string { return "velocity" }
+
+func (v Velocity) Check(ctx context.Context, t Transfer, h History) (string, error) {
+	count, err := h.CountTransfersSince(ctx, t.Username, t.At.Add(-v.Window))
+	if err != nil {
+		return "", err
+	}
+	if count < v.Count {
+		return "", nil
+	}
+	return fmt.Sprintf("%d transfers in the last %s", count+1, v.Window), nil
+}
+
+// NewPayee matches transfers of at least Amount to an account the user never sent money to.
+// Transfers between a user's own accounts don't match.
+type NewPayee struct {
+	Amount int64
+}
+
+func (p NewPayee) Name() string { return "new_payee" }
+
+func (p NewPayee) Check(ctx context.Context, t Transfer, h History) (string, error) {
+	if t.Amount < p.Amount || t.ToOwner == t.Username {
+		return "", nil
+	}
+	count, err := h.CountTransfersTo(ctx, t.Username, t.ToAccountID)
+	if err != nil || count > 0 {
+		return "", err
+	}
+	return fmt.Sprintf("first transfer to account %d is %d %s", t.ToAccountID, t.Amount, t.Currency), nil
+}
+
+// LargeAmount matches transfers of at least Amount.
+type LargeAmount struct {
+	Amount int64
+}
+
+func (l LargeAmount) Name() string { return "large" }
+
+func (l LargeAmount) Check(ctx context.Context, t Transfer, h History) (string, error) {
+	if t.Amount < l.Amount {
+		return "", nil
+	}
+	return fmt.Sprintf("%d %s is at least %d", t.Amount, t.Currency, l.Amount), nil
+}
+
+// QuietHours matches transfers made from the hour From up to the hour To, in UTC. The hours can
+// wrap around midnight.
+type QuietHours struct {
+	From int
+	To   int
+}
+
+func (q QuietHours) Name() string { return "quiet_hours" }
+
+func (q QuietHours) Check(ctx context.Context, t Transfer, h History) (string, error) {
+	hour := t.At.UTC().Hour()
+	inside := hour >= q.From && hour < q.To
+	if q.From > q.To {
+		inside = hour >= q.From || hour < q.To
+	}
+	if !inside {
+		return "", nil
+	}
+	return fmt.Sprintf("made at %02d:00 UTC, between %02d:00 and %02d:00", hour, q.From, q.To), nil
+}
+
+// NewIP matches transfers from an IP address the user never logged in from, or first did less
+// than Age ago, once they have logged in from others. Sessions record the address the servers
+// trust at login, only taken from X-Forwarded-For behind trusted proxies, so a client can't claim
+// an address it used before.
+type NewIP struct {
+	Age time.Duration
+}
+
+// DefaultNewIPAge is how long an address stays new when new_ip doesn't say.
+const DefaultNewIPAge = 24 * time.Hour
+
+func (NewIP) Name() string { return "new_ip" }
+
+func (n NewIP) Check(ctx context.Context, t Transfer, h History) (string, error) {
+	if t.ClientIP == "" {
+		return "", nil
+	}
+	firstSeen, others, err := h.SessionIPs(ctx, t.Username, t.ClientIP)
+	if err != nil || others == 0 || t.At.Sub(firstSeen) >= n.Age {
+		return "", err
+	}
+	return fmt.Sprintf("%s is not among the addresses the user logged in from more than %s ago", t.ClientIP, n.Age), nil
+}
+
+// Parse reads rules written as "name[=params][:verdict]", separated by commas. The verdict
+// defaults to review. The rules are:
+//
+//	velocity=5/10m     5 transfers within 10 minutes
+//	new_payee=100000   an amount of at least 100000 to a new payee
+//	large=1000000      an amount of at least 1000000
+//	quiet_hours=1-5    transfers from 01:00 to 05:00 UTC
+//	new_ip=24h         transfers from an address first logged in from less than 24 hours ago;
+//	                   the age defaults to DefaultNewIPAge
+func Parse(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, err := parseRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid risk rule %q: %w", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(entry string) (Rule, error) {
+	rule := Rule{Verdict: Review}
+	if head, verdict, found := strings.Cut(entry, ":"); found {
+		if err := rule.Verdict.UnmarshalText([]byte(verdict)); err != nil {
+			return rule, err
+		}
+		if rule.Verdict == Allow {
+			return rule, fmt.Errorf("a rule that allows does nothing")
+		}
+		entry = head
+	}
+
+	name, params, _ := strings.Cut(entry, "=")
+	switch name {
+	case "velocity":
+		count, window, found := strings.Cut(params, "/")
+		n, err := strconv.ParseInt(count, 10, 64)
+		if !found || err != nil || n < 1 {
+			return rule, fmt.Errorf("want velocity=count/window")
+		}
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			return rule, fmt.Errorf("want velocity=count/window")
+		}
+		rule.Check = Velocity{Count: n, Window: d}
+	case "new_payee":
+		amount, err := strconv.ParseInt(params, 10, 64)
+		if err != nil || amount < 0 {
+			return rule, fmt.Errorf("want new_payee=amount")
+		}
+		rule.Check = NewPayee{Amount: amount}
+	case "large":
+		amount, err := strconv.ParseInt(params, 10, 64)
+		if err != nil || amount < 1 {
+			return rule, fmt.Errorf("want large=amount")
+		}
+		rule.Check = LargeAmount{Amount: amount}
+	case "quiet_hours":
+		from, to, found := strings.Cut(params, "-")
+		f, err1 := strconv.Atoi(from)
+		t, err2 := strconv.Atoi(to)
+		if !found || err1 != nil || err2 != nil || f < 0 || f > 23 || t < 0 || t > 23 || f == t {
+			return rule, fmt.Errorf("want quiet_hours=from-to, in hours of the day")
+		}
+		rule.Check = QuietHours{From: f, To: t}
+	case "new_ip":
+		age := DefaultNewIPAge
+		if params != "" {
+			d, err := time.ParseDuration(params)
+			if err != nil || d <= 0 {
+				return rule, fmt.Errorf("want new_ip or new_ip=age")
+			}
+			age = d
+		}
+		rule.Check = NewIP{Age: age}
+	default:
+		return rule, fmt.Errorf("unknown rule %q", name)
+	}
+	return rule, nil
+}
@@ -0,0 +1,158 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHistory struct {
+	recent    int64
+	to        int64
+	firstSeen time.Time
+	others    int64
+	err       error
+}
+
+func (h fakeHistory) CountTransfersSince(ctx context.Context, username string, since time.Time) (int64, error) {
+	return h.recent, h.err
+}
+
+func (h fakeHistory) CountTransfersTo(ctx context.Context, username string, toAccountID int64) (int64, error) {
+	return h.to, h.err
+}
+
+func (h fakeHistory) SessionIPs(ctx context.Context, username string, ip string) (time.Time, int64, error) {
+	return h.firstSeen, h.others, h.err
+}
+
+func newTransfer(hour int) Transfer {
+	return Transfer{
+		Username:      "alice",
+		FromAccountID: 1,
+		ToAccountID:   2,
+		ToOwner:       "bob",
+		Amount:        500,
+		Currency:      "USD",
+		ClientIP:      "10.0.0.1",
+		At:            time.Date(2024, 3, 1, hour, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestChecks(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name    string
+		check   Check
+		t       Transfer
+		history fakeHistory
+		match   bool
+	}{
+		{"VelocityUnder", Velocity{Count: 3, Window: time.Minute}, newTransfer(12), fakeHistory{recent: 2}, false},
+		{"VelocityOver", Velocity{Count: 3, Window: time.Minute}, newTransfer(12), fakeHistory{recent: 3}, true},
+		{"NewPayeeSmall", NewPayee{Amount: 1000}, newTransfer(12), fakeHistory{}, false},
+		{"NewPayeeLarge", NewPayee{Amount: 100}, newTransfer(12), fakeHistory{}, true},
+		{"KnownPayee", NewPayee{Amount: 100}, newTransfer(12), fakeHistory{to: 1}, false},
+		{"OwnAccount", NewPayee{Amount: 100}, func() Transfer { t := newTransfer(12); t.ToOwner = t.Username; return t }(), fakeHistory{}, false},
+		{"Large", LargeAmount{Amount: 500}, newTransfer(12), fakeHistory{}, true},
+		{"NotLarge", LargeAmount{Amount: 501}, newTransfer(12), fakeHistory{}, false},
+		{"QuietHour", QuietHours{From: 1, To: 5}, newTransfer(3), fakeHistory{}, true},
+		{"DayHour", QuietHours{From: 1, To: 5}, newTransfer(5), fakeHistory{}, false},
+		{"QuietHourWrapping", QuietHours{From: 22, To: 4}, newTransfer(23), fakeHistory{}, true},
+		{"DayHourWrapping", QuietHours{From: 22, To: 4}, newTransfer(12), fakeHistory{}, false},
+		{"NewIP", NewIP{Age: time.Hour}, newTransfer(12), fakeHistory{firstSeen: newTransfer(12).At, others: 4}, true},
+		{"RecentIP", NewIP{Age: time.Hour}, newTransfer(12), fakeHistory{firstSeen: newTransfer(12).At.Add(-time.Minute), others: 4}, true},
+		{"KnownIP", NewIP{Age: time.Hour}, newTransfer(12), fakeHistory{firstSeen: newTransfer(12).At.Add(-time.Hour), others: 4}, false},
+		{"OnlyIP", NewIP{Age: time.Hour}, newTransfer(12), fakeHistory{firstSeen: newTransfer(12).At}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, err := tc.check.Check(ctx, tc.t, tc.history)
+			require.NoError(t, err)
+			require.Equal(t, tc.match, reason != "", reason)
+		})
+	}
+}
+
+func TestScreen(t *testing.T) {
+	ctx := context.Background()
+	screener := New(
+		Rule{Check: QuietHours{From: 1, To: 5}, Verdict: Review},
+		Rule{Check: Velocity{Count: 3, Window: time.Minute}, Verdict: Deny},
+		Rule{Check: NewIP{Age: time.Hour}, Verdict: Review},
+	)
+
+	assessment, err := screener.Screen(ctx, newTransfer(12), fakeHistory{})
+	require.NoError(t, err)
+	require.Equal(t, Allow, assessment.Verdict)
+	require.Empty(t, assessment.Findings)
+
+	assessment, err = screener.Screen(ctx, newTransfer(3), fakeHistory{})
+	require.NoError(t, err)
+	require.Equal(t, Review, assessment.Verdict)
+	require.Len(t, assessment.Findings, 1)
+	require.Equal(t, "quiet_hours", assessment.Findings[0].Rule)
+
+	// A denial stops the screening.
+	assessment, err = screener.Screen(ctx, newTransfer(3), fakeHistory{recent: 5, others: 1})
+	require.NoError(t, err)
+	require.Equal(t, Deny, assessment.Verdict)
+	require.Len(t, assessment.Findings, 2)
+
+	_, err = screener.Screen(ctx, newTransfer(12), fakeHistory{err: errors.New("boom")})
+	require.Error(t, err)
+
+	assessment, err = New().Screen(ctx, newTransfer(3), nil)
+	require.NoError(t, err)
+	require.Equal(t, Allow, assessment.Verdict)
+}
+
+func TestAssessmentJSON(t *testing.T) {
+	data, err := json.Marshal(Assessment{Verdict: Review, Findings: []Finding{{Rule: "new_ip", Verdict: Review, Reason: "x"}}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"verdict":"review","findings":[{"rule":"new_ip","verdict":"review","reason":"x"}]}`, string(data))
+
+	var assessment Assessment
+	require.NoError(t, json.Unmarshal(data, &assessment))
+	require.Equal(t, Review, assessment.Verdict)
+}
+
+func TestParse(t *testing.T) {
+	rules, err := Parse("velocity=5/10m:deny, new_payee=100000, large=1000000, quiet_hours=1-5:review,new_ip,new_ip=1h:deny")
+	require.NoError(t, err)
+	require.Equal(t, []Rule{
+		{Check: Velocity{Count: 5, Window: 10 * time.Minute}, Verdict: Deny},
+		{Check: NewPayee{Amount: 100000}, Verdict: Review},
+		{Check: LargeAmount{Amount: 1000000}, Verdict: Review},
+		{Check: QuietHours{From: 1, To: 5}, Verdict: Review},
+		{Check: NewIP{Age: DefaultNewIPAge}, Verdict: Review},
+		{Check: NewIP{Age: time.Hour}, Verdict: Deny},
+	}, rules)
+
+	rules, err = Parse("")
+	require.NoError(t, err)
+	require.Empty(t, rules)
+
+	for _, spec := range []string{
+		"velocity=5",
+		"velocity=0/10m",
+		"velocity=5/soon",
+		"new_payee=lots",
+		"large=0",
+		"quiet_hours=1-24",
+		"quiet_hours=3-3",
+		"new_ip=1",
+		"new_ip=-1h",
+		"new_ip:allow",
+		"new_ip:maybe",
+		"weekends",
+	} {
+		_, err := Parse(spec)
+		require.Error(t, err, spec)
+	}
+}
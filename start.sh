@@ -1,9 +1,6 @@
 #!/bin/sh
 set -e
 
-echo "running db migration"
-source /app/app.env
-/app/migrate -path /app/migration -database "$DB_SOURCE" -verbose up
-
+# The app applies the embedded migrations itself when MIGRATE_ON_START is set.
 echo "starting the app"
 exec "$@"
\ No newline at end of file
@@ -3,80 +3,104 @@ package token
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type JWTMaker struct {
 	secretKey string
+	issuer    string
+	audience  string
 }
 
 const minSecretKeySize = 30
 
 var ErrInvalidToken = errors.New("token is invalid")
 
-func NewJWTMaker(secret string) (Maker, error) {
+func NewJWTMaker(secret string, issuer string, audience string) (Maker, error) {
 	if len(secret) < minSecretKeySize {
-		return nil, fmt.Errorf("keylength should be at least 30 characters")
+		return nil, fmt.Errorf("keylength should be at least %d characters", minSecretKeySize)
 	}
 	return &JWTMaker{
 		secretKey: secret,
+		issuer:    issuer,
+		audience:  audience,
 	}, nil
 }
 
-// Wraps a Payload object and provides a claims interface.
+// Maps a Payload onto the registered JWT claims. The username travels as the subject,
+// the payload ID as the JWT ID, the scopes as a space separated "scope" claim (RFC 8693)
+// and the token type as a private claim.
 type RegClaims struct {
-	Payload
+	jwt.RegisteredClaims
+	Scope     string    `json:"scope,omitempty"`
+	TokenType TokenType `json:"token_type"`
 }
 
-func (c RegClaims) GetExpirationTime() (*jwt.NumericDate, error) {
-	return jwt.NewNumericDate(c.Payload.ExpiredAt), nil
-}
-
-// GetNotBefore implements the Claims interface.
-func (c RegClaims) GetNotBefore() (*jwt.NumericDate, error) {
-	return jwt.NewNumericDate(time.Now()), nil
-}
-
-// GetIssuedAt implements the Claims interface.
-func (c RegClaims) GetIssuedAt() (*jwt.NumericDate, error) {
-	return jwt.NewNumericDate(c.Payload.IssuedAt), nil
-}
-
-// GetIssuer implements the Claims interface.
-func (c RegClaims) GetIssuer() (string, error) {
-	return "none", nil
-}
-
-// GetAudience implements the Claims interface.
-func (c RegClaims) GetAudience() (jwt.ClaimStrings, error) {
-	return []string{"none"}, nil
+func newRegClaims(payload *Payload) *RegClaims {
+	return &RegClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        payload.ID.String(),
+			Issuer:    payload.Issuer,
+			Subject:   payload.Username,
+			Audience:  jwt.ClaimStrings{payload.Audience},
+			ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
+			NotBefore: jwt.NewNumericDate(payload.IssuedAt),
+			IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+		},
+		Scope:     strings.Join(payload.Scopes, " "),
+		TokenType: payload.TokenType,
+	}
 }
 
-// GetSubject implements the Claims interface.
-func (c RegClaims) GetSubject() (string, error) {
-	return "none", nil
+// Converts the claims back into a Payload.
+func (c *RegClaims) payload() (*Payload, error) {
+	id, err := uuid.Parse(c.ID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if len(c.Audience) != 1 || c.ExpiresAt == nil || c.IssuedAt == nil {
+		return nil, ErrInvalidToken
+	}
+	return &Payload{
+		ID:        id,
+		Username:  c.Subject,
+		Issuer:    c.Issuer,
+		Audience:  c.Audience[0],
+		TokenType: c.TokenType,
+		Scopes:    strings.Fields(c.Scope),
+		IssuedAt:  c.IssuedAt.Time,
+		ExpiredAt: c.ExpiresAt.Time,
+	}, nil
 }
 
-func (j *JWTMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(username, duration)
+func (j *JWTMaker) CreateToken(username string, scopes []string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, scopes, tokenType, duration)
 	if err != nil {
 		return "", nil, err
 	}
+	payload.Issuer = j.issuer
+	payload.Audience = j.audience
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, RegClaims{Payload: *payload})
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, newRegClaims(payload))
 	token, err := jwtToken.SignedString([]byte(j.secretKey))
 	return token, payload, err
 
 }
-func (j *JWTMaker) VerifyToken(token string) (*Payload, error) {
-	
-	p := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+func (j *JWTMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
 
-	registeredClaims := RegClaims{}
+	p := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithAudience(j.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 
-	jwtToken, err := p.ParseWithClaims(token, registeredClaims, func(token *jwt.Token) (interface{}, error) {
+	jwtToken, err := p.ParseWithClaims(token, &RegClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// These checks are not required as the WithValidMethods will validate the signing method.
 		// However, we will keep it for a second level of check.
 		_, ok := token.Method.(*jwt.SigningMethodHMAC)
@@ -86,12 +110,25 @@ func (j *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return []byte(j.secretKey), nil
 	})
 	if err != nil {
-		return nil, err
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
 	}
-	
-	registerdClaims, ok := jwtToken.Claims.(*RegClaims)
+
+	registeredClaims, ok := jwtToken.Claims.(*RegClaims)
 	if !ok {
 		return nil, ErrInvalidToken
 	}
-	return &registerdClaims.Payload, nil
+	payload, err := registeredClaims.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	// The parser has already checked expiry, issuer and audience; this also checks the subject and token type.
+	err = payload.verify(j.issuer, j.audience, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
 }
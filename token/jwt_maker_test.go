@@ -0,0 +1,103 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTOkToken(t *testing.T) {
+	username := util.RandomOwner()
+	issuedAt := time.Now()
+	duration := time.Hour
+	expiredAt := issuedAt.Add(duration)
+	maker, err := NewJWTMaker(util.RandomString(32), "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	token, payload, err := maker.CreateToken(username, UserScopes, RefreshToken, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	got, err := maker.VerifyToken(token, RefreshToken)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, got.ID)
+	require.Equal(t, username, got.Username)
+	require.Equal(t, "simplebank", got.Issuer)
+	require.Equal(t, "simplebank-api", got.Audience)
+	require.Equal(t, RefreshToken, got.TokenType)
+	require.Equal(t, UserScopes, got.Scopes)
+	require.WithinDuration(t, issuedAt, got.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, got.ExpiredAt, time.Second)
+}
+
+func TestJWTRegisteredClaims(t *testing.T) {
+	username := util.RandomOwner()
+	maker, err := NewJWTMaker(util.RandomString(32), "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(username, []string{ScopeAccountsRead, ScopeTransfersWrite}, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	// Decode the claims without verifying the signature and check the standard claims are on the wire.
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	require.Equal(t, "simplebank", claims["iss"])
+	require.Equal(t, username, claims["sub"])
+	require.Equal(t, []interface{}{"simplebank-api"}, claims["aud"])
+	require.Equal(t, string(AccessToken), claims["token_type"])
+	require.Equal(t, "accounts:read transfers:write", claims["scope"])
+}
+
+func TestJWTExpiredToken(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32), "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(util.RandomOwner(), nil, AccessToken, -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token, AccessToken)
+	require.Empty(t, payload)
+	require.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestJWTWrongTokenType(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32), "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(util.RandomOwner(), nil, RefreshToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token, AccessToken)
+	require.Empty(t, payload)
+	require.ErrorIs(t, err, ErrInvalidTokenType)
+}
+
+func TestJWTWrongIssuer(t *testing.T) {
+	key := util.RandomString(32)
+	maker, err := NewJWTMaker(key, "someone-else", "simplebank-api")
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(util.RandomOwner(), nil, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	verifier, err := NewJWTMaker(key, "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	payload, err := verifier.VerifyToken(token, AccessToken)
+	require.Empty(t, payload)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWTNoneAlgorithm(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32), "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	_, payload, err := maker.CreateToken(util.RandomOwner(), nil, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, newRegClaims(payload))
+	token, err := jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token, AccessToken)
+	require.Empty(t, payload)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
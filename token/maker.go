@@ -1,8 +1,29 @@
 package token
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// Supported token formats, selected with the TOKEN_MAKER config.
+const (
+	MakerPaseto = "paseto"
+	MakerJWT    = "jwt"
+)
 
 type Maker interface {
-	CreateToken(username string, duration time.Duration) (string, *Payload, error)
-	VerifyToken(token string) (*Payload, error)
-}
\ No newline at end of file
+	CreateToken(username string, scopes []string, tokenType TokenType, duration time.Duration) (string, *Payload, error)
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
+}
+
+// NewMaker creates a token maker of the given kind. Tokens it creates carry issuer and
+// audience, and only tokens with the same issuer and audience pass verification.
+func NewMaker(kind string, key string, issuer string, audience string) (Maker, error) {
+	switch kind {
+	case MakerPaseto:
+		return NewPasetoMaker(key, issuer, audience)
+	case MakerJWT:
+		return NewJWTMaker(key, issuer, audience)
+	}
+	return nil, fmt.Errorf("unsupported token maker: %q", kind)
+}
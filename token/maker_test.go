@@ -0,0 +1,24 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMaker(t *testing.T) {
+	key := util.RandomString(32)
+
+	maker, err := NewMaker(MakerPaseto, key, "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	require.IsType(t, PasetoMaker{}, maker)
+
+	maker, err = NewMaker(MakerJWT, key, "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	require.IsType(t, &JWTMaker{}, maker)
+
+	maker, err = NewMaker("unknown", key, "simplebank", "simplebank-api")
+	require.Error(t, err)
+	require.Nil(t, maker)
+}
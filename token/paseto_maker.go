@@ -11,23 +11,30 @@ import (
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey []byte
+	issuer       string
+	audience     string
 }
 
-func NewPasetoMaker(key string) (Maker, error) {
+func NewPasetoMaker(key string, issuer string, audience string) (Maker, error) {
 	if len(key) != chacha20poly1305.KeySize {
 		return nil, fmt.Errorf("invalid key size. must be exactly %d", chacha20poly1305.KeySize)
 	}
 	return PasetoMaker{
 		paseto:       paseto.NewV2(),
 		symmetricKey: []byte(key),
+		issuer:       issuer,
+		audience:     audience,
 	}, nil
 }
 
-func (m PasetoMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(username, duration)
+func (m PasetoMaker) CreateToken(username string, scopes []string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, scopes, tokenType, duration)
 	if err != nil {
 		return "", nil, err
 	}
+	payload.Issuer = m.issuer
+	payload.Audience = m.audience
+
 	token, err := m.paseto.Encrypt(m.symmetricKey, payload, nil)
 	if err != nil {
 		return "", nil, err
@@ -35,18 +42,19 @@ func (m PasetoMaker) CreateToken(username string, duration time.Duration) (strin
 	return token, payload, nil
 }
 
-func (m PasetoMaker) VerifyToken(token string) (*Payload, error) {
+func (m PasetoMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
 
 	payload := &Payload{}
 	err := m.paseto.Decrypt(token, m.symmetricKey, payload, nil)
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidToken
 	}
 
-	// It appears Paseto tokens are not validated. We have to manually verify the token has not expired.
-    err = payload.Validate()
+	// It appears Paseto tokens are not validated. We have to manually verify the token has not expired,
+	// and that it was issued by us, for us, as the expected type of token.
+	err = payload.verify(m.issuer, m.audience, tokenType)
 	if err != nil {
 		return nil, err
 	}
-	return payload, err
-}
\ No newline at end of file
+	return payload, nil
+}
@@ -15,32 +15,61 @@ func TestPasetoOkToken(t *testing.T) {
 	issuedAt := time.Now()
 	duration := time.Hour
 	expiredAt := issuedAt.Add(duration)
-	maker, err := NewPasetoMaker(util.RandomString(32))
+	maker, err := NewPasetoMaker(util.RandomString(32), "simplebank", "simplebank-api")
 	require.NoError(t, err)
-	token, payload, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, UserScopes, AccessToken, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 	require.NotEmpty(t, payload)
 
-	payload, err = maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, AccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 	require.Equal(t, payload.Username, username)
+	require.Equal(t, payload.Issuer, "simplebank")
+	require.Equal(t, payload.Audience, "simplebank-api")
+	require.Equal(t, payload.TokenType, AccessToken)
+	require.Equal(t, payload.Scopes, UserScopes)
+	require.True(t, payload.HasScope(ScopeAccountsRead))
 	require.WithinDuration(t, payload.IssuedAt, issuedAt, time.Second)
 	require.WithinDuration(t, payload.ExpiredAt, expiredAt, time.Minute)
 }
 
 func TestPasetoExpiredToken(t *testing.T) {
-	maker, err := NewPasetoMaker(util.RandomString(32))
+	maker, err := NewPasetoMaker(util.RandomString(32), "simplebank", "simplebank-api")
 	require.NoError(t, err)
-	token, payload, err := maker.CreateToken(util.RandomOwner(), -time.Minute)
+	token, payload, err := maker.CreateToken(util.RandomOwner(), nil, AccessToken, -time.Minute)
 	require.NotEmpty(t, token)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
-	payload, err = maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, AccessToken)
 	require.Empty(t, payload)
-	// require.True(t, errors.Is(err, paseto.ErrTokenExpired))
-	require.Error(t, err)
+	require.ErrorIs(t, err, ErrExpiredToken)
 
-}
\ No newline at end of file
+}
+
+func TestPasetoWrongTokenType(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32), "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(util.RandomOwner(), nil, RefreshToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token, AccessToken)
+	require.Empty(t, payload)
+	require.ErrorIs(t, err, ErrInvalidTokenType)
+}
+
+func TestPasetoWrongAudience(t *testing.T) {
+	key := util.RandomString(32)
+	maker, err := NewPasetoMaker(key, "simplebank", "simplebank-api")
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(util.RandomOwner(), nil, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	other, err := NewPasetoMaker(key, "simplebank", "another-api")
+	require.NoError(t, err)
+	payload, err := other.VerifyToken(token, AccessToken)
+	require.Empty(t, payload)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -8,16 +8,33 @@ import (
 	"github.com/google/uuid"
 )
 
-var ErrExpiredToken = errors.New("token has expired")
+var (
+	ErrExpiredToken     = errors.New("token has expired")
+	ErrInvalidTokenType = errors.New("token type is invalid")
+)
+
+// TokenType tells access tokens apart from refresh tokens, so that one can't be used in place of the other.
+type TokenType string
+
+const (
+	AccessToken  TokenType = "access"
+	RefreshToken TokenType = "refresh"
+	// Marks payloads built from an API key rather than decoded from a token.
+	APIKeyToken TokenType = "api_key"
+)
 
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Issuer    string    `json:"issuer"`
+	Audience  string    `json:"audience"`
+	TokenType TokenType `json:"token_type"`
+	Scopes    []string  `json:"scopes"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, scopes []string, tokenType TokenType, duration time.Duration) (*Payload, error) {
 	uuid, err := uuid.NewRandom()
 	if err != nil {
 		return nil, fmt.Errorf("uuid creation failed: %w", err)
@@ -25,6 +42,8 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	return &Payload{
 		ID:        uuid,
 		Username:  username,
+		TokenType: tokenType,
+		Scopes:    scopes,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}, nil
@@ -36,3 +55,22 @@ func (p *Payload) Validate() error {
 	}
 	return nil
 }
+
+// HasScope reports whether the token was granted scope.
+func (p *Payload) HasScope(scope string) bool {
+	return HasScope(p.Scopes, scope)
+}
+
+// verify checks that the payload is still valid and was issued by issuer, for audience, as a token of tokenType.
+func (p *Payload) verify(issuer string, audience string, tokenType TokenType) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.Username == "" || p.Issuer != issuer || p.Audience != audience {
+		return ErrInvalidToken
+	}
+	if p.TokenType != tokenType {
+		return ErrInvalidTokenType
+	}
+	return nil
+}
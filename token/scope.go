@@ -0,0 +1,95 @@
+package token
+
+import "fmt"
+
+// Scopes limit what a token or API key can be used for.
+const (
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeUsersRead      = "users:read"
+	ScopeAPIKeysManage  = "api_keys:manage"
+
+	// Banker scopes
+	ScopeAuditRead        = "audit:read"
+	ScopeAccountsAdmin    = "accounts:admin"
+	ScopeTransfersApprove = "transfers:approve"
+)
+
+// Roles of users. Depositors hold accounts; bankers also run the bank.
+const (
+	RoleDepositor = "depositor"
+	RoleBanker    = "banker"
+)
+
+// UserScopes are the scopes a depositor can hold. Tokens issued at login get all of them unless the
+// user asks for fewer.
+var UserScopes = []string{
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+	ScopeTransfersWrite,
+	ScopeUsersRead,
+	ScopeAPIKeysManage,
+}
+
+// BankerScopes are the scopes a banker can hold: all user scopes plus the ones to run the bank.
+var BankerScopes = []string{
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+	ScopeTransfersWrite,
+	ScopeUsersRead,
+	ScopeAPIKeysManage,
+	ScopeAuditRead,
+	ScopeAccountsAdmin,
+	ScopeTransfersApprove,
+}
+
+// RoleScopes returns the scopes a user with role can hold.
+func RoleScopes(role string) []string {
+	if role == RoleBanker {
+		return BankerScopes
+	}
+	return UserScopes
+}
+
+// APIKeyScopes are the scopes that can be granted to an API key. Keys can't manage other keys.
+var APIKeyScopes = []string{
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+	ScopeTransfersWrite,
+	ScopeUsersRead,
+}
+
+// HasScope reports whether scope is present in scopes.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckScopes returns an error unless every requested scope is one of the allowed scopes.
+func CheckScopes(requested []string, allowed []string) error {
+	for _, scope := range requested {
+		if !HasScope(allowed, scope) {
+			return fmt.Errorf("scope not allowed: %s", scope)
+		}
+	}
+	return nil
+}
+
+// CheckAPIKeyScopes returns an error unless every requested scope can be granted to an API key by
+// a caller holding held. A key can't do more than the token that created it.
+func CheckAPIKeyScopes(requested []string, held []string) error {
+	if err := CheckScopes(requested, APIKeyScopes); err != nil {
+		return err
+	}
+	for _, scope := range requested {
+		if !HasScope(held, scope) {
+			return fmt.Errorf("scope not held by the caller: %s", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckScopes(t *testing.T) {
+	require.NoError(t, CheckScopes([]string{ScopeAccountsRead}, APIKeyScopes))
+	require.NoError(t, CheckScopes(nil, APIKeyScopes))
+	require.Error(t, CheckScopes([]string{ScopeAPIKeysManage}, APIKeyScopes))
+	require.Error(t, CheckScopes([]string{"everything"}, UserScopes))
+}
+
+func TestRoleScopes(t *testing.T) {
+	require.Equal(t, UserScopes, RoleScopes(RoleDepositor))
+	require.Equal(t, UserScopes, RoleScopes(""))
+	require.True(t, HasScope(RoleScopes(RoleBanker), ScopeAuditRead))
+	require.False(t, HasScope(RoleScopes(RoleDepositor), ScopeAuditRead))
+	require.NoError(t, CheckScopes(UserScopes, BankerScopes))
+}
+
+func TestCheckAPIKeyScopes(t *testing.T) {
+	require.NoError(t, CheckAPIKeyScopes([]string{ScopeTransfersWrite}, UserScopes))
+	require.Error(t, CheckAPIKeyScopes([]string{ScopeAPIKeysManage}, UserScopes))
+	require.EqualError(t, CheckAPIKeyScopes([]string{ScopeTransfersWrite}, []string{ScopeAPIKeysManage}),
+		"scope not held by the caller: "+ScopeTransfersWrite)
+}
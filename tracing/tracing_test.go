@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetupFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), util.Config{
+		TraceExporter: ExporterFile,
+		TraceFile:     path,
+	})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"Name":"test-span"`)
+}
+
+func TestSetupNoExporter(t *testing.T) {
+	shutdown, err := Setup(context.Background(), util.Config{TraceExporter: ExporterNone})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+}
+
+func TestSetupInvalidExporter(t *testing.T) {
+	_, err := Setup(context.Background(), util.Config{TraceExporter: "zipkin"})
+	require.Error(t, err)
+
+	_, err = Setup(context.Background(), util.Config{TraceExporter: ExporterFile})
+	require.Error(t, err)
+}
@@ -0,0 +1,146 @@
+// Package txlimit caps how much money can leave accounts: per transfer, over a rolling day and
+// over a calendar month, per currency.
+package txlimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrExceeded is returned for a transfer that would go over a limit.
+var ErrExceeded = errors.New("transfer limit exceeded")
+
+// Scopes a limit applies to. User limits cover all of a user's accounts in a currency.
+const (
+	ScopeUser    = "user"
+	ScopeAccount = "account"
+)
+
+// Limits caps the money that leaves a user's accounts, or one account, in one currency. Zero means
+// no limit.
+type Limits struct {
+	Single  int64 `json:"single"`
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// Used is the money that already left in the daily and monthly windows.
+type Used struct {
+	Daily   int64
+	Monthly int64
+}
+
+// DayStart returns the start of the rolling daily window at now.
+func DayStart(now time.Time) time.Time {
+	return now.Add(-24 * time.Hour)
+}
+
+// MonthStart returns the start of the calendar month of now, in UTC.
+func MonthStart(now time.Time) time.Time {
+	year, month, _ := now.UTC().Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Window is the tightest limit in one window.
+type Window struct {
+	// Limit is zero when nothing limits the window.
+	Limit     int64 `json:"limit"`
+	Used      int64 `json:"used"`
+	Remaining int64 `json:"remaining"`
+	// Scope says whose limit binds, the user's or the account's.
+	Scope string `json:"scope,omitempty"`
+}
+
+func newWindow(limit int64, used int64, scope string) Window {
+	if limit == 0 {
+		return Window{}
+	}
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Window{Limit: limit, Used: used, Remaining: remaining, Scope: scope}
+}
+
+// tightest returns the window that leaves the least.
+func tightest(a Window, b Window) Window {
+	if a.Limit == 0 || (b.Limit != 0 && b.Remaining < a.Remaining) {
+		return b
+	}
+	return a
+}
+
+func (w Window) allows(amount int64) bool {
+	return w.Limit == 0 || amount <= w.Remaining
+}
+
+// Allowance is what can still leave an account, under its own limits and its owner's.
+type Allowance struct {
+	AccountID int64  `json:"account_id"`
+	Currency  string `json:"currency"`
+	Single    Window `json:"single"`
+	Daily     Window `json:"daily"`
+	Monthly   Window `json:"monthly"`
+}
+
+// NewAllowance combines the limits of a user and of one of their accounts with what already left
+// them.
+func NewAllowance(user Limits, userUsed Used, account Limits, accountUsed Used) Allowance {
+	return Allowance{
+		Single:  tightest(newWindow(user.Single, 0, ScopeUser), newWindow(account.Single, 0, ScopeAccount)),
+		Daily:   tightest(newWindow(user.Daily, userUsed.Daily, ScopeUser), newWindow(account.Daily, accountUsed.Daily, ScopeAccount)),
+		Monthly: tightest(newWindow(user.Monthly, userUsed.Monthly, ScopeUser), newWindow(account.Monthly, accountUsed.Monthly, ScopeAccount)),
+	}
+}
+
+// Check returns an error wrapping ErrExceeded when amount can't leave the account.
+func (a Allowance) Check(amount int64) error {
+	windows := []struct {
+		name   string
+		window Window
+	}{
+		{"single transfer", a.Single},
+		{"daily", a.Daily},
+		{"monthly", a.Monthly},
+	}
+	for _, w := range windows {
+		if !w.window.allows(amount) {
+			return fmt.Errorf("the %s %s limit of %d %s leaves %d: %w",
+				w.window.Scope, w.name, w.window.Limit, a.Currency, w.window.Remaining, ErrExceeded)
+		}
+	}
+	return nil
+}
+
+// Parse reads default user limits written as "CUR=single/daily/monthly", separated by commas.
+// Currencies left out, and limits of 0, are not limited.
+func Parse(spec string) (map[string]Limits, error) {
+	limits := make(map[string]Limits)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		currency, value, found := strings.Cut(entry, "=")
+		if !found || strings.TrimSpace(currency) == "" {
+			return nil, fmt.Errorf("invalid transfer limit %q", entry)
+		}
+		parts := strings.Split(value, "/")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid transfer limit %q: want single/daily/monthly", entry)
+		}
+		var amounts [3]int64
+		for i, part := range parts {
+			amount, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil || amount < 0 {
+				return nil, fmt.Errorf("invalid transfer limit %q: limits must be numbers of at least 0", entry)
+			}
+			amounts[i] = amount
+		}
+		limits[strings.TrimSpace(currency)] = Limits{Single: amounts[0], Daily: amounts[1], Monthly: amounts[2]}
+	}
+	return limits, nil
+}
@@ -0,0 +1,62 @@
+package txlimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowance(t *testing.T) {
+	user := Limits{Single: 500, Daily: 1000, Monthly: 5000}
+
+	// Without account limits the user's apply.
+	a := NewAllowance(user, Used{Daily: 800, Monthly: 4000}, Limits{}, Used{})
+	require.Equal(t, Window{Limit: 500, Remaining: 500, Scope: ScopeUser}, a.Single)
+	require.Equal(t, Window{Limit: 1000, Used: 800, Remaining: 200, Scope: ScopeUser}, a.Daily)
+	require.NoError(t, a.Check(200))
+	require.ErrorIs(t, a.Check(201), ErrExceeded)
+
+	// The tighter limit binds, whoever it belongs to.
+	a = NewAllowance(user, Used{Daily: 100, Monthly: 4900}, Limits{Daily: 300}, Used{Daily: 100, Monthly: 100})
+	require.Equal(t, ScopeAccount, a.Daily.Scope)
+	require.Equal(t, int64(200), a.Daily.Remaining)
+	require.Equal(t, ScopeUser, a.Monthly.Scope)
+	require.Equal(t, int64(100), a.Monthly.Remaining)
+	err := a.Check(150)
+	require.ErrorIs(t, err, ErrExceeded)
+	require.Contains(t, err.Error(), "user monthly limit")
+
+	// Going over after a limit was lowered leaves nothing rather than a negative allowance.
+	a = NewAllowance(Limits{Daily: 100}, Used{Daily: 300}, Limits{}, Used{})
+	require.Zero(t, a.Daily.Remaining)
+
+	// Nothing limits a user without limits.
+	a = NewAllowance(Limits{}, Used{Daily: 1e9}, Limits{}, Used{})
+	require.Equal(t, Window{}, a.Daily)
+	require.NoError(t, a.Check(1e12))
+}
+
+func TestWindows(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	require.Equal(t, now.Add(-24*time.Hour), DayStart(now))
+	require.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), MonthStart(now))
+}
+
+func TestParse(t *testing.T) {
+	limits, err := Parse("USD=1000/5000/20000, EUR = 0/3000/0")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Limits{
+		"USD": {Single: 1000, Daily: 5000, Monthly: 20000},
+		"EUR": {Daily: 3000},
+	}, limits)
+
+	limits, err = Parse("")
+	require.NoError(t, err)
+	require.Empty(t, limits)
+
+	for _, spec := range []string{"USD", "=1/2/3", "USD=1/2", "USD=1/2/x", "USD=-1/2/3"} {
+		_, err := Parse(spec)
+		require.Error(t, err, spec)
+	}
+}
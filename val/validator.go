@@ -0,0 +1,141 @@
+// Package val holds the rules for the fields clients send. The Gin API and the gRPC server both
+// check requests against them, so the two cannot drift apart.
+package val
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/ashokmouli/simplebank/db/util"
+	"github.com/ashokmouli/simplebank/password"
+)
+
+var (
+	isValidUsername = regexp.MustCompile(`^[a-zA-Z0-9_]+$`).MatchString
+	isValidFullName = regexp.MustCompile(`^[\p{L}\p{M}' .-]+$`).MatchString
+)
+
+// ValidateString checks that value is between minLength and maxLength characters long.
+func ValidateString(value string, minLength int, maxLength int) error {
+	n := len([]rune(value))
+	if n == 0 {
+		return fmt.Errorf("is required")
+	}
+	if n < minLength || n > maxLength {
+		return fmt.Errorf("must contain from %d to %d characters", minLength, maxLength)
+	}
+	return nil
+}
+
+// ValidateUsername checks a username: 3 to 100 letters, digits or underscores.
+func ValidateUsername(value string) error {
+	if err := ValidateString(value, 3, 100); err != nil {
+		return err
+	}
+	if !isValidUsername(value) {
+		return fmt.Errorf("must contain only letters, digits or underscore")
+	}
+	return nil
+}
+
+// ValidateFullName checks a person's name: letters, spaces and the punctuation found in names.
+func ValidateFullName(value string) error {
+	if err := ValidateString(value, 1, 100); err != nil {
+		return err
+	}
+	if !isValidFullName(value) {
+		return fmt.Errorf("must contain only letters, spaces, apostrophes, periods or hyphens")
+	}
+	return nil
+}
+
+// ValidateEmail checks that value is a bare email address.
+func ValidateEmail(value string) error {
+	if err := ValidateString(value, 3, 200); err != nil {
+		return err
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		return fmt.Errorf("is not a valid email address")
+	}
+	return nil
+}
+
+// ValidateLoginPassword checks a password given to log in. Only new passwords have to meet the
+// password policy, so that tightening it does not lock out existing users.
+func ValidateLoginPassword(value string) error {
+	if value == "" {
+		return fmt.Errorf("is required")
+	}
+	if len(value) > password.MaxLength {
+		return fmt.Errorf("must be at most %d bytes", password.MaxLength)
+	}
+	return nil
+}
+
+// ValidateAmount checks that a transfer amount, in the smallest unit of its currency, is positive.
+func ValidateAmount(value int64) error {
+	if value <= 0 {
+		return fmt.Errorf("must be greater than 0")
+	}
+	return nil
+}
+
+// ValidateCurrency checks that value is a currency accounts can be held in.
+func ValidateCurrency(value string) error {
+	if value == "" {
+		return fmt.Errorf("is required")
+	}
+	if !util.IsSupportedCurrency(value) {
+		return fmt.Errorf("must be one of %s", strings.Join(util.SupportedCurrencies, ", "))
+	}
+	return nil
+}
+
+// ValidateAccountType checks that value is a type of account that can be opened.
+func ValidateAccountType(value string) error {
+	if !util.IsSupportedAccountType(value) {
+		return fmt.Errorf("must be one of %s", strings.Join(util.AccountTypes, ", "))
+	}
+	return nil
+}
+
+// ValidateNickname checks the name an owner gives an account. It is optional, but can't be blank
+// or hold control characters when set.
+func ValidateNickname(value string) error {
+	if value == "" {
+		return nil
+	}
+	if strings.TrimSpace(value) != value {
+		return fmt.Errorf("must not start or end with spaces")
+	}
+	if err := ValidateString(value, 1, 50); err != nil {
+		return err
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("must not contain control characters")
+		}
+	}
+	return nil
+}
+
+// ValidatePayeeLabel checks the name a user saves a payee under. It follows the nickname rules
+// but is required.
+func ValidatePayeeLabel(value string) error {
+	if value == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	return ValidateNickname(value)
+}
+
+// ValidateID checks that a database ID is positive.
+func ValidateID(value int64) error {
+	if value <= 0 {
+		return fmt.Errorf("must be greater than 0")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package val
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidators(t *testing.T) {
+	testCases := []struct {
+		name     string
+		validate func(string) error
+		valid    []string
+		invalid  []string
+	}{
+		{
+			name:     "Username",
+			validate: ValidateUsername,
+			valid:    []string{"bob", "alice_01", "Carol"},
+			invalid:  []string{"", "ab", "bob smith", "bob@example", strings.Repeat("a", 101)},
+		},
+		{
+			name:     "FullName",
+			validate: ValidateFullName,
+			valid:    []string{"Bob", "Mary-Jane O'Neil", "José Müller", "J. R. R. Tolkien"},
+			invalid:  []string{"", "bob1", "<script>", strings.Repeat("a", 101)},
+		},
+		{
+			name:     "Email",
+			validate: ValidateEmail,
+			valid:    []string{"bob@example.com"},
+			invalid:  []string{"", "bob", "Bob <bob@example.com>", "bob@"},
+		},
+		{
+			name:     "LoginPassword",
+			validate: ValidateLoginPassword,
+			valid:    []string{"a", "secret"},
+			invalid:  []string{"", strings.Repeat("a", 73)},
+		},
+		{
+			name:     "Currency",
+			validate: ValidateCurrency,
+			valid:    []string{"USD", "EUR", "CAD"},
+			invalid:  []string{"", "usd", "GBP"},
+		},
+		{
+			name:     "AccountType",
+			validate: ValidateAccountType,
+			valid:    []string{"checking", "savings"},
+			invalid:  []string{"", "Savings", "brokerage"},
+		},
+		{
+			name:     "Nickname",
+			validate: ValidateNickname,
+			valid:    []string{"", "Rainy day", "Épargne 2"},
+			invalid:  []string{" ", "trailing ", "tab\tin", strings.Repeat("a", 51)},
+		},
+		{
+			name:     "PayeeLabel",
+			validate: ValidatePayeeLabel,
+			valid:    []string{"Mom", "Landlord – flat 2"},
+			invalid:  []string{"", " Mom", "new\nline"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, value := range tc.valid {
+				require.NoError(t, tc.validate(value), value)
+			}
+			for _, value := range tc.invalid {
+				require.Error(t, tc.validate(value), value)
+			}
+		})
+	}
+}
+
+func TestValidateAmount(t *testing.T) {
+	require.NoError(t, ValidateAmount(1))
+	require.Error(t, ValidateAmount(0))
+	require.Error(t, ValidateAmount(-10))
+}